@@ -0,0 +1,307 @@
+// Package bencode implements the bencoding used by BitTorrent metainfo files
+// and tracker responses (BEP 3).
+//
+// Decoded values are int64, string, []interface{} and map[string]interface{}.
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// maxDepth bounds list/dict nesting so hostile input cannot exhaust the stack.
+const maxDepth = 64
+
+// SyntaxError reports malformed input and the offset it was found at.
+type SyntaxError struct {
+	Offset int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("bencode: %s at offset %d", e.Msg, e.Offset)
+}
+
+// Decode parses a single bencoded value that must span all of data.
+func Decode(data []byte) (interface{}, error) {
+	d := &decoder{data: data}
+	v, err := d.value()
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(data) {
+		return nil, d.errorf("trailing data")
+	}
+	return v, nil
+}
+
+// DecodePrefix parses one bencoded value from the start of data and returns
+// it with the number of bytes consumed. ut_metadata messages (BEP 9) carry raw
+// piece data after their dictionary.
+func DecodePrefix(data []byte) (interface{}, int, error) {
+	d := &decoder{data: data}
+	v, err := d.value()
+	if err != nil {
+		return nil, 0, err
+	}
+	return v, d.pos, nil
+}
+
+// DictValue returns the raw, undecoded bytes stored under key in the
+// top-level dictionary of data. Infohashes are computed over these bytes
+// rather than a re-encoding, so non-canonical files still hash correctly.
+func DictValue(data []byte, key string) ([]byte, error) {
+	d := &decoder{data: data}
+	if d.pos >= len(data) || data[d.pos] != 'd' {
+		return nil, d.errorf("not a dictionary")
+	}
+	d.pos++
+	d.depth = 1
+	for {
+		if d.pos >= len(data) {
+			return nil, d.errorf("unterminated dictionary")
+		}
+		if data[d.pos] == 'e' {
+			return nil, fmt.Errorf("bencode: key %q not found", key)
+		}
+		k, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		start := d.pos
+		if _, err := d.value(); err != nil {
+			return nil, err
+		}
+		if k == key {
+			return data[start:d.pos], nil
+		}
+	}
+}
+
+type decoder struct {
+	data  []byte
+	pos   int
+	depth int
+}
+
+func (d *decoder) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Offset: d.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (d *decoder) value() (interface{}, error) {
+	if d.pos >= len(d.data) {
+		return nil, d.errorf("unexpected end of input")
+	}
+	switch c := d.data[d.pos]; {
+	case c == 'i':
+		return d.integer()
+	case c == 'l':
+		return d.list()
+	case c == 'd':
+		return d.dict()
+	case c >= '0' && c <= '9':
+		return d.string()
+	default:
+		return nil, d.errorf("unexpected byte %q", c)
+	}
+}
+
+func (d *decoder) integer() (int64, error) {
+	d.pos++ // 'i'
+	end := bytes.IndexByte(d.data[d.pos:], 'e')
+	if end < 0 {
+		return 0, d.errorf("unterminated integer")
+	}
+	digits := string(d.data[d.pos : d.pos+end])
+	// Leading zeros and negative zero are not canonical
+	if digits == "" || digits == "-0" ||
+		(len(digits) > 1 && digits[0] == '0') ||
+		(len(digits) > 2 && digits[0] == '-' && digits[1] == '0') {
+		return 0, d.errorf("invalid integer %q", digits)
+	}
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, d.errorf("invalid integer %q", digits)
+	}
+	d.pos += end + 1
+	return n, nil
+}
+
+func (d *decoder) string() (string, error) {
+	if d.pos >= len(d.data) || d.data[d.pos] < '0' || d.data[d.pos] > '9' {
+		return "", d.errorf("expected string")
+	}
+	colon := bytes.IndexByte(d.data[d.pos:], ':')
+	if colon < 0 {
+		return "", d.errorf("unterminated string length")
+	}
+	digits := string(d.data[d.pos : d.pos+colon])
+	if len(digits) > 1 && digits[0] == '0' {
+		return "", d.errorf("invalid string length %q", digits)
+	}
+	n, err := strconv.Atoi(digits)
+	if err != nil {
+		return "", d.errorf("invalid string length %q", digits)
+	}
+	start := d.pos + colon + 1
+	if n > len(d.data)-start {
+		return "", d.errorf("string length %d exceeds input", n)
+	}
+	d.pos = start + n
+	return string(d.data[start:d.pos]), nil
+}
+
+func (d *decoder) list() ([]interface{}, error) {
+	if d.depth++; d.depth > maxDepth {
+		return nil, d.errorf("nesting too deep")
+	}
+	defer func() { d.depth-- }()
+
+	d.pos++ // 'l'
+	list := []interface{}{}
+	for {
+		if d.pos >= len(d.data) {
+			return nil, d.errorf("unterminated list")
+		}
+		if d.data[d.pos] == 'e' {
+			d.pos++
+			return list, nil
+		}
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+}
+
+func (d *decoder) dict() (map[string]interface{}, error) {
+	if d.depth++; d.depth > maxDepth {
+		return nil, d.errorf("nesting too deep")
+	}
+	defer func() { d.depth-- }()
+
+	d.pos++ // 'd'
+	dict := map[string]interface{}{}
+	for {
+		if d.pos >= len(d.data) {
+			return nil, d.errorf("unterminated dictionary")
+		}
+		if d.data[d.pos] == 'e' {
+			d.pos++
+			return dict, nil
+		}
+		k, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := dict[k]; dup {
+			return nil, d.errorf("duplicate key %q", k)
+		}
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		dict[k] = v
+	}
+}
+
+// Encode bencodes v. Supported types are the integer kinds, string, []byte,
+// []string, []interface{}, map[string]interface{} and map[string]string;
+// dictionary keys are written in sorted order.
+func Encode(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	if err := encode(&b, v); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func encode(b *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case int:
+		writeInt(b, int64(v))
+	case int32:
+		writeInt(b, int64(v))
+	case int64:
+		writeInt(b, v)
+	case uint16:
+		writeInt(b, int64(v))
+	case uint32:
+		writeInt(b, int64(v))
+	case bool:
+		if v {
+			writeInt(b, 1)
+		} else {
+			writeInt(b, 0)
+		}
+	case string:
+		writeString(b, v)
+	case []byte:
+		writeString(b, string(v))
+	case []string:
+		b.WriteByte('l')
+		for _, s := range v {
+			writeString(b, s)
+		}
+		b.WriteByte('e')
+	case []interface{}:
+		b.WriteByte('l')
+		for _, item := range v {
+			if err := encode(b, item); err != nil {
+				return err
+			}
+		}
+		b.WriteByte('e')
+	case map[string]interface{}:
+		b.WriteByte('d')
+		for _, k := range sortedKeys(v) {
+			writeString(b, k)
+			if err := encode(b, v[k]); err != nil {
+				return err
+			}
+		}
+		b.WriteByte('e')
+	case map[string]string:
+		b.WriteByte('d')
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			writeString(b, k)
+			writeString(b, v[k])
+		}
+		b.WriteByte('e')
+	case nil:
+		return errors.New("bencode: cannot encode nil")
+	default:
+		return fmt.Errorf("bencode: unsupported type %T", v)
+	}
+	return nil
+}
+
+func writeInt(b *bytes.Buffer, n int64) {
+	b.WriteByte('i')
+	b.WriteString(strconv.FormatInt(n, 10))
+	b.WriteByte('e')
+}
+
+func writeString(b *bytes.Buffer, s string) {
+	b.WriteString(strconv.Itoa(len(s)))
+	b.WriteByte(':')
+	b.WriteString(s)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,138 @@
+package bencode
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		in   string
+		want interface{}
+	}{
+		{"i42e", int64(42)},
+		{"i-7e", int64(-7)},
+		{"i0e", int64(0)},
+		{"4:spam", "spam"},
+		{"0:", ""},
+		{"le", []interface{}{}},
+		{"l4:spami3ee", []interface{}{"spam", int64(3)}},
+		{"d3:cow3:moo4:spaml1:a1:bee", map[string]interface{}{"cow": "moo", "spam": []interface{}{"a", "b"}}},
+		// Unsorted keys aren't canonical but real files have them
+		{"d1:bi2e1:ai1ee", map[string]interface{}{"a": int64(1), "b": int64(2)}},
+	}
+	for _, tt := range tests {
+		got, err := Decode([]byte(tt.in))
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Decode(%q) = %#v, %v; want %#v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name, in string
+	}{
+		{"empty", ""},
+		{"truncated integer", "i42"},
+		{"truncated string", "5:spam"},
+		{"truncated list", "l4:spam"},
+		{"truncated dict", "d3:cow3:moo"},
+		{"dict key without value", "d3:cowe"},
+		{"empty integer", "ie"},
+		{"leading zero", "i03e"},
+		{"negative zero", "i-0e"},
+		{"negative leading zero", "i-03e"},
+		{"integer overflow", "i99999999999999999999e"},
+		{"string length leading zero", "04:spam"},
+		{"negative string length", "-1:a"},
+		{"non-string key", "di1ei2ee"},
+		{"duplicate key", "d1:ai1e1:ai2ee"},
+		{"trailing data", "i1ei2e"},
+		{"unknown type", "x"},
+		{"too deep", strings.Repeat("l", maxDepth+1) + strings.Repeat("e", maxDepth+1)},
+	}
+	for _, tt := range tests {
+		_, err := Decode([]byte(tt.in))
+		var se *SyntaxError
+		if !errors.As(err, &se) {
+			t.Errorf("%s: Decode(%.20q) = %v, want a SyntaxError", tt.name, tt.in, err)
+		}
+	}
+}
+
+func TestDecodeMaxDepth(t *testing.T) {
+	in := strings.Repeat("l", maxDepth) + strings.Repeat("e", maxDepth)
+	if _, err := Decode([]byte(in)); err != nil {
+		t.Errorf("nesting of exactly %d: %v", maxDepth, err)
+	}
+}
+
+func TestDecodePrefix(t *testing.T) {
+	v, n, err := DecodePrefix([]byte("d8:msg_typei1e5:piecei0eeRAWDATA"))
+	if err != nil || n != 25 {
+		t.Fatalf("DecodePrefix = %v, %d, %v; want 25 bytes", v, n, err)
+	}
+}
+
+func TestDictValue(t *testing.T) {
+	data := []byte("d8:announce3:url4:infod6:lengthi5e4:name1:xe7:comment2:hie")
+	tests := []struct {
+		key, want string
+	}{
+		{"announce", "3:url"},
+		{"info", "d6:lengthi5e4:name1:xe"},
+		{"comment", "2:hi"},
+	}
+	for _, tt := range tests {
+		got, err := DictValue(data, tt.key)
+		if err != nil || string(got) != tt.want {
+			t.Errorf("DictValue(%q) = %q, %v; want %q", tt.key, got, err, tt.want)
+		}
+	}
+
+	// Keys out of order are still found, byte for byte
+	got, err := DictValue([]byte("d4:infod1:bi1e1:ai2ee1:ai0ee"), "info")
+	if err != nil || string(got) != "d1:bi1e1:ai2ee" {
+		t.Errorf("DictValue of unsorted dict = %q, %v", got, err)
+	}
+
+	for _, in := range []string{
+		"",
+		"l4:infoe",
+		"d4:info",
+		"d4:infoi1",
+		"d4:infod1:ai01eee",
+		"d" + "4:info" + strings.Repeat("l", maxDepth+1) + strings.Repeat("e", maxDepth+2),
+	} {
+		if _, err := DictValue([]byte(in), "info"); err == nil {
+			t.Errorf("DictValue(%.30q) succeeded", in)
+		}
+	}
+	if _, err := DictValue([]byte("d1:ai1ee"), "info"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("missing key: err = %v", err)
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	v := map[string]interface{}{
+		"z": []interface{}{int64(-1), "x"},
+		"a": map[string]interface{}{"n": int64(0)},
+	}
+	data, err := Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "d1:ad1:ni0ee1:zli-1e1:xee" {
+		t.Errorf("Encode = %q, want sorted keys", data)
+	}
+	got, err := Decode(data)
+	if err != nil || !reflect.DeepEqual(got, v) {
+		t.Errorf("round trip = %#v, %v", got, err)
+	}
+	if _, err := Encode(nil); err == nil {
+		t.Error("Encode(nil) succeeded")
+	}
+}
@@ -0,0 +1,721 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"c-cli/core"
+	"c-cli/metainfo"
+	"c-cli/quality"
+)
+
+// /api/v1 is the versioned API: one result shape for every source, POST
+// for actions, errors as {"error": {"code", "message"}} and cursor paging.
+// The unversioned /api routes remain as a deprecated compatibility layer.
+
+// Error codes, with the status each is sent with.
+const (
+	codeInvalidRequest   = "invalid_request"    // 400
+	codeUnsupported      = "unsupported"        // 400
+	codeUnauthorized     = "unauthorized"       // 401
+	codeForbidden        = "forbidden"          // 403
+	codeNotFound         = "not_found"          // 404
+	codeMethodNotAllowed = "method_not_allowed" // 405
+	codeInternal         = "internal"           // 500
+	codeUpstream         = "upstream_error"     // 502
+	codeUnavailable      = "unavailable"        // 503
+)
+
+type apiErrorBody struct {
+	Error apiErrorDetail `json:"error"`
+}
+
+type apiErrorDetail struct {
+	Code    string `json:"code" doc:"invalid_request, unsupported, unauthorized, forbidden, not_found, method_not_allowed, internal, upstream_error or unavailable"`
+	Message string `json:"message" doc:"What went wrong, for people"`
+}
+
+// apiErr is an error a handler reports with a given status and code.
+type apiErr struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *apiErr) Error() string { return e.message }
+
+func invalidRequest(format string, args ...any) error {
+	return &apiErr{http.StatusBadRequest, codeInvalidRequest, fmt.Sprintf(format, args...)}
+}
+
+func apiError(w http.ResponseWriter, status int, code, message string) {
+	apiJSON(w, status, apiErrorBody{apiErrorDetail{Code: code, Message: message}})
+}
+
+// writeAPIError reports err: an apiErr as given, a refused .torrent URL as
+// forbidden, and anything else from the providers as an upstream error.
+func writeAPIError(w http.ResponseWriter, err error) {
+	var e *apiErr
+	switch {
+	case errors.As(err, &e):
+		apiError(w, e.status, e.code, e.message)
+	case errors.Is(err, core.ErrNotAllowed):
+		apiError(w, http.StatusForbidden, codeForbidden, err.Error())
+	default:
+		apiError(w, http.StatusBadGateway, codeUpstream, err.Error())
+	}
+}
+
+func apiJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+// decodeJSON reads a request body into v, refusing unknown fields so typos
+// don't go unnoticed.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return invalidRequest("invalid request body: %v", err)
+	}
+	return nil
+}
+
+// Cursors are opaque to clients: base64 JSON of where the next page starts.
+func encodeCursor(v any) string {
+	b, _ := json.Marshal(v)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		err = json.Unmarshal(b, v)
+	}
+	if err != nil {
+		return invalidRequest("invalid cursor")
+	}
+	return nil
+}
+
+// pageLimit reads the limit parameter: 1 to 100, 20 by default.
+func pageLimit(r *http.Request) (int, error) {
+	s := r.URL.Query().Get("limit")
+	if s == "" {
+		return 20, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > 100 {
+		return 0, invalidRequest("limit must be between 1 and 100")
+	}
+	return n, nil
+}
+
+// apiResult is a search result from any source.
+type apiResult struct {
+	ID       string          `json:"id" doc:"<source>:<provider ID>, for /api/v1/results/{id}"`
+	Source   string          `json:"source"`
+	Title    string          `json:"title"`
+	Year     int             `json:"year,omitempty"`
+	IMDBID   string          `json:"imdb_id,omitempty"`
+	Rating   float64         `json:"rating,omitempty" doc:"The provider's rating; omdb has IMDb's"`
+	Runtime  int             `json:"runtime,omitempty" doc:"Minutes"`
+	Genres   []string        `json:"genres,omitempty"`
+	Summary  string          `json:"summary,omitempty"`
+	Poster   string          `json:"poster,omitempty" doc:"Image URL"`
+	Torrents []apiTorrent    `json:"torrents"`
+	Best     *bestTorrent    `json:"best,omitempty" doc:"The torrent the quality profile picks, and why"`
+	Details  bool            `json:"details" doc:"Whether /api/v1/results/{id} has more, such as OMDB data and the full description"`
+	OMDB     *core.OMDBMovie `json:"omdb,omitempty"`
+}
+
+type apiTorrent struct {
+	Hash     string     `json:"hash" doc:"Infohash, lower case"`
+	Name     string     `json:"name,omitempty" doc:"Release name"`
+	Quality  string     `json:"quality"`
+	Type     string     `json:"type,omitempty" doc:"Release type, e.g. web or bluray"`
+	Codec    string     `json:"codec,omitempty"`
+	Size     int64      `json:"size" doc:"Bytes"`
+	Seeds    int        `json:"seeds"`
+	Peers    int        `json:"peers"`
+	Uploaded *time.Time `json:"uploaded,omitempty"`
+	URL      string     `json:"url,omitempty" doc:".torrent URL; without one, grabs fetch the .torrent by hash"`
+	Magnet   string     `json:"magnet"`
+}
+
+// resultID identifies a result for /api/v1/results/{id}: by the provider's
+// ID where it has one, otherwise by infohash.
+func resultID(m core.Movie) string {
+	if m.ID != 0 {
+		return fmt.Sprintf("%s:%d", m.Source, m.ID)
+	}
+	return fmt.Sprintf("%s:%s", m.Source, strings.ToLower(m.Infohash))
+}
+
+func toAPIResult(m core.Movie, profileName string, profile quality.Profile) apiResult {
+	res := apiResult{
+		ID:       resultID(m),
+		Source:   string(m.Source),
+		Title:    m.Title,
+		Year:     m.Year,
+		IMDBID:   m.IMDBCode,
+		Rating:   m.Rating,
+		Runtime:  m.Runtime,
+		Genres:   m.Genres,
+		Summary:  m.Description,
+		Poster:   m.MediumCover,
+		Torrents: make([]apiTorrent, len(m.Torrents)),
+		OMDB:     m.OMDB,
+	}
+	if p, ok := lib.Provider(m.Source); ok {
+		res.Details = p.Capabilities().Details
+	}
+	if res.Summary == "" {
+		res.Summary = m.Summary
+	}
+	if res.Poster == "" && m.OMDB != nil && m.OMDB.Poster != "N/A" {
+		res.Poster = m.OMDB.Poster
+	}
+
+	for i, t := range m.Torrents {
+		name := t.Name
+		if name == "" {
+			name = m.Title + " " + t.Quality
+		}
+		at := apiTorrent{
+			Hash:    strings.ToLower(t.Hash),
+			Name:    t.Name,
+			Quality: t.Quality,
+			Type:    t.Type,
+			Codec:   t.VideoCodec,
+			Size:    t.SizeBytes,
+			Seeds:   t.Seeds,
+			Peers:   t.Peers,
+			URL:     t.URL,
+			Magnet:  lib.Magnet(t.Hash, name),
+		}
+		if t.DateUploadedUnix > 0 {
+			uploaded := time.Unix(t.DateUploadedUnix, 0).UTC()
+			at.Uploaded = &uploaded
+		}
+		res.Torrents[i] = at
+	}
+	if len(m.Torrents) > 0 {
+		res.Best = selectBestTorrent(m.Torrents, profileName, profile)
+	}
+	return res
+}
+
+type apiResultPage struct {
+	Results    []apiResult `json:"results"`
+	Total      int         `json:"total" doc:"Results across all pages"`
+	NextCursor string      `json:"next_cursor,omitempty" doc:"Pass as cursor for the next page; absent on the last page"`
+}
+
+// searchCursor is where the next page of a search starts.
+type searchCursor struct {
+	Query  string `json:"q"`
+	Source string `json:"s"`
+	Page   int    `json:"p"`
+	Limit  int    `json:"l"`
+}
+
+// handleAPISearch runs a search, enriched from OMDB and sorted by IMDb
+// votes within the page.
+func handleAPISearch(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	c := searchCursor{Query: q.Get("q"), Source: q.Get("source"), Page: 1}
+	if cursor := q.Get("cursor"); cursor != "" {
+		if err := decodeCursor(cursor, &c); err != nil || c.Page < 1 || c.Limit < 1 || c.Limit > 100 {
+			writeAPIError(w, invalidRequest("invalid cursor"))
+			return
+		}
+	} else {
+		var err error
+		if c.Limit, err = pageLimit(r); err != nil {
+			writeAPIError(w, err)
+			return
+		}
+	}
+	if c.Source == "" {
+		c.Source = string(core.SourceYTS)
+	}
+	if c.Query == "" {
+		writeAPIError(w, invalidRequest("missing q"))
+		return
+	}
+	source := core.SearchSource(c.Source)
+	if _, ok := lib.Provider(source); !ok {
+		writeAPIError(w, invalidRequest("unknown source %q; see /api/v1/sources", c.Source))
+		return
+	}
+	profileName, profile, err := qualityProfile(q.Get("profile"))
+	if err != nil {
+		writeAPIError(w, invalidRequest("%v", err))
+		return
+	}
+
+	result, err := lib.Search(r.Context(), c.Query, c.Page, c.Limit, source)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	lib.EnrichMovies(r.Context(), result.Movies, nil)
+	core.SortByVotes(result.Movies)
+
+	page := apiResultPage{Results: make([]apiResult, len(result.Movies)), Total: result.Total}
+	for i, m := range result.Movies {
+		page.Results[i] = toAPIResult(m, profileName, profile)
+	}
+	if c.Page < result.TotalPages {
+		next := c
+		next.Page++
+		page.NextCursor = encodeCursor(next)
+	}
+	apiJSON(w, http.StatusOK, page)
+}
+
+// handleAPIResult looks a result up again with its provider's details.
+func handleAPIResult(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	i := strings.LastIndex(id, ":")
+	if i < 0 {
+		apiError(w, http.StatusNotFound, codeNotFound, fmt.Sprintf("no result %q", id))
+		return
+	}
+	source, key := core.SearchSource(id[:i]), id[i+1:]
+	p, ok := lib.Provider(source)
+	if !ok {
+		apiError(w, http.StatusNotFound, codeNotFound, fmt.Sprintf("no result %q", id))
+		return
+	}
+	if !p.Capabilities().Details {
+		apiError(w, http.StatusBadRequest, codeUnsupported, fmt.Sprintf("%s results are complete as listed by /api/v1/search", source))
+		return
+	}
+	movie := core.Movie{Source: source, Infohash: key}
+	if n, err := strconv.Atoi(key); err == nil {
+		movie = core.Movie{Source: source, ID: n}
+	}
+	profileName, profile, err := qualityProfile(r.URL.Query().Get("profile"))
+	if err != nil {
+		writeAPIError(w, invalidRequest("%v", err))
+		return
+	}
+
+	details, err := lib.Details(r.Context(), movie)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	if details.Title == "" {
+		apiError(w, http.StatusNotFound, codeNotFound, fmt.Sprintf("no result %q", id))
+		return
+	}
+	apiJSON(w, http.StatusOK, toAPIResult(*details, profileName, profile))
+}
+
+type apiSource struct {
+	Source  string `json:"source" doc:"The source parameter for /api/v1/search"`
+	Label   string `json:"label"`
+	Paging  bool   `json:"paging" doc:"Pages server-side; otherwise a batch is fetched and paged locally"`
+	Details bool   `json:"details" doc:"Has /api/v1/results/{id} details"`
+	IMDBIDs bool   `json:"imdb_ids" doc:"Results carry IMDb IDs without OMDB"`
+}
+
+type apiSourceList struct {
+	Sources []apiSource `json:"sources"`
+}
+
+func handleAPISources(w http.ResponseWriter, r *http.Request) {
+	var list apiSourceList
+	for _, p := range lib.Providers() {
+		caps := p.Capabilities()
+		list.Sources = append(list.Sources, apiSource{
+			Source:  string(p.Source()),
+			Label:   p.Label(),
+			Paging:  caps.Paging,
+			Details: caps.Details,
+			IMDBIDs: caps.IMDBIDs,
+		})
+	}
+	apiJSON(w, http.StatusOK, list)
+}
+
+type apiGrabRequest struct {
+	Target   string   `json:"target" doc:"server saves the .torrent in your download directory, or a .magnet when only hash is given and no .torrent can be found; client sends it to the download client"`
+	Hash     string   `json:"hash,omitempty" doc:"Infohash; required for client"`
+	URL      string   `json:"url,omitempty" doc:".torrent URL from a result's torrents; must be on one of TORRENT_HOSTS"`
+	Title    string   `json:"title,omitempty" doc:"Names the saved file or the client's entry; defaults to hash"`
+	Quality  string   `json:"quality,omitempty"`
+	Category string   `json:"category,omitempty" doc:"client only"`
+	SavePath string   `json:"save_path,omitempty" doc:"client only: overrides the client's download directory"`
+	Tags     []string `json:"tags,omitempty" doc:"client only"`
+}
+
+// handleAPICreateGrab saves a torrent on the server or sends it to the
+// download client, and returns the grab as recorded in the history.
+func handleAPICreateGrab(w http.ResponseWriter, r *http.Request) {
+	var req apiGrabRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	if req.Hash == "" && req.URL == "" {
+		writeAPIError(w, invalidRequest("hash or url is required"))
+		return
+	}
+	if req.Hash != "" {
+		if _, err := metainfo.ParseHash(req.Hash); err != nil {
+			writeAPIError(w, invalidRequest("%v", err))
+			return
+		}
+	}
+	if req.Title == "" {
+		req.Title = req.Hash
+	}
+	if req.Title == "" {
+		writeAPIError(w, invalidRequest("title is required with url alone"))
+		return
+	}
+
+	var g grab
+	var err error
+	switch req.Target {
+	case "server":
+		g, err = grabToServer(r, req)
+	case "client":
+		g, err = grabToClient(r, req)
+	default:
+		err = invalidRequest(`target must be "server" or "client"`)
+	}
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	apiJSON(w, http.StatusCreated, g)
+}
+
+// grabFilename is the name a grab is saved under, before the extension.
+func grabFilename(title, quality string) string {
+	name := core.SanitizeFilename(title)
+	if quality != "" {
+		name += "." + core.SanitizeFilename(quality)
+	}
+	return name
+}
+
+func grabToServer(r *http.Request, req apiGrabRequest) (grab, error) {
+	dir, err := userDownloadDir(r)
+	if err != nil {
+		return grab{}, &apiErr{http.StatusInternalServerError, codeInternal, err.Error()}
+	}
+
+	_, data, err := lib.FetchTorrent(r.Context(), core.Torrent{URL: req.URL, Hash: req.Hash})
+	ext := ".torrent"
+	if err != nil {
+		if req.URL != "" {
+			return grab{}, err
+		}
+		// By hash alone, a magnet is better than nothing
+		data, ext = []byte(lib.Magnet(req.Hash, req.Title)), ".magnet"
+	}
+
+	path := filepath.Join(dir, grabFilename(req.Title, req.Quality)+ext)
+	if err := core.WriteFileAtomic(path, data, 0644); err != nil {
+		return grab{}, &apiErr{http.StatusInternalServerError, codeInternal, err.Error()}
+	}
+	return grabs.record(r, grab{Action: "download", Title: req.Title, Quality: req.Quality, Hash: req.Hash, URL: req.URL, File: path}), nil
+}
+
+func grabToClient(r *http.Request, req apiGrabRequest) (grab, error) {
+	if req.Hash == "" {
+		return grab{}, invalidRequest("hash is required for client")
+	}
+	if downloadClientErr != nil {
+		return grab{}, &apiErr{http.StatusServiceUnavailable, codeUnavailable, downloadClientErr.Error()}
+	}
+	if downloadClient == nil {
+		return grab{}, &apiErr{http.StatusServiceUnavailable, codeUnavailable, "no download client configured (set DOWNLOAD_CLIENT)"}
+	}
+
+	opts := core.AddOptions{Category: req.Category, SavePath: req.SavePath, Tags: req.Tags}
+	torrent := core.Torrent{Hash: req.Hash, URL: req.URL}
+	id, err := lib.Send(r.Context(), downloadClient, torrent, req.Title, opts)
+	var fallback *core.MagnetFallback
+	if errors.As(err, &fallback) {
+		log.Printf("Send %q: %v", req.Title, err)
+	} else if err != nil {
+		return grab{}, err
+	}
+	return grabs.record(r, grab{Action: "send", Title: req.Title, Quality: req.Quality, Hash: req.Hash, URL: req.URL, Client: downloadClient.Name(), ClientID: id}), nil
+}
+
+type apiGrabPage struct {
+	Grabs      []grab `json:"grabs" doc:"Newest first"`
+	NextCursor string `json:"next_cursor,omitempty" doc:"Pass as cursor for older grabs; absent when there are none"`
+}
+
+// grabCursor is where the next page of history starts.
+type grabCursor struct {
+	Before time.Time `json:"b"`
+}
+
+// handleAPIGrabs pages through the user's recent grabs.
+func handleAPIGrabs(w http.ResponseWriter, r *http.Request) {
+	limit, err := pageLimit(r)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	var c grabCursor
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		if err := decodeCursor(cursor, &c); err != nil {
+			writeAPIError(w, err)
+			return
+		}
+	}
+
+	name := ""
+	if u := requestUser(r); u != nil {
+		name = u.Name
+	}
+	all := grabs.recent(name)
+	start := 0
+	if !c.Before.IsZero() {
+		for start < len(all) && !all[start].Time.Before(c.Before) {
+			start++
+		}
+	}
+	end := min(start+limit, len(all))
+
+	page := apiGrabPage{Grabs: all[start:end]}
+	if end < len(all) {
+		page.NextCursor = encodeCursor(grabCursor{Before: all[end-1].Time})
+	}
+	apiJSON(w, http.StatusOK, page)
+}
+
+// handleAPITorrentFile sends a .torrent to the browser.
+func handleAPITorrentFile(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	hash, torrentURL, title, quality := q.Get("hash"), q.Get("url"), q.Get("title"), q.Get("quality")
+	if hash == "" && torrentURL == "" {
+		writeAPIError(w, invalidRequest("hash or url is required"))
+		return
+	}
+	if title == "" {
+		title = hash
+	}
+
+	_, data, err := lib.FetchTorrent(r.Context(), core.Torrent{URL: torrentURL, Hash: hash})
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	grabs.record(r, grab{Action: "download-file", Title: title, Quality: quality, Hash: hash, URL: torrentURL})
+	w.Header().Set("Content-Type", "application/x-bittorrent")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.torrent"`, grabFilename(title, quality)))
+	w.Write(data)
+}
+
+func handleAPIStatus(w http.ResponseWriter, r *http.Request) {
+	apiJSON(w, http.StatusOK, currentStatus(r))
+}
+
+type apiSession struct {
+	User string `json:"user"`
+}
+
+// handleAPISignIn starts a session, setting its cookie.
+func handleAPISignIn(w http.ResponseWriter, r *http.Request) {
+	if auth == nil {
+		apiError(w, http.StatusNotFound, codeNotFound, "authentication is not enabled")
+		return
+	}
+	var creds credentials
+	if err := decodeJSON(w, r, &creds); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	u := signIn(w, r, creds)
+	if u == nil {
+		apiError(w, http.StatusUnauthorized, codeUnauthorized, "invalid username or password")
+		return
+	}
+	apiJSON(w, http.StatusCreated, apiSession{User: u.Name})
+}
+
+func handleAPISignOut(w http.ResponseWriter, r *http.Request) {
+	if auth == nil {
+		apiError(w, http.StatusNotFound, codeNotFound, "authentication is not enabled")
+		return
+	}
+	signOut(w, r)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// apiRoute is an /api/v1 endpoint, as served and as described in the
+// OpenAPI document.
+type apiRoute struct {
+	method, path string // path in ServeMux and OpenAPI form, e.g. /api/v1/results/{id}
+	id, summary  string
+	params       []apiParam
+	body         any    // Request body type, as a zero value
+	status       int    // Success status
+	response     any    // JSON response type, as a zero value
+	contentType  string // Instead of response, for files
+	public       bool   // Served without signing in
+	handler      http.HandlerFunc
+}
+
+type apiParam struct {
+	name, in, typ string
+	required      bool
+	doc           string
+}
+
+var (
+	limitParam   = apiParam{"limit", "query", "integer", false, "Page size, 1 to 100; default 20"}
+	profileParam = apiParam{"profile", "query", "string", false, "Quality profile that picks best; default the server's"}
+)
+
+func apiRoutes() []apiRoute {
+	return []apiRoute{
+		{
+			method: "GET", path: "/api/v1/search", id: "search",
+			summary: "Search a source, with OMDB data and the best torrent for a quality profile",
+			params: []apiParam{
+				{"q", "query", "string", false, "Search terms; required without cursor"},
+				{"source", "query", "string", false, "A source from /api/v1/sources; default yts"},
+				limitParam,
+				{"cursor", "query", "string", false, "next_cursor of the previous page, which carries q, source and limit"},
+				profileParam,
+			},
+			status: http.StatusOK, response: apiResultPage{}, handler: handleAPISearch,
+		},
+		{
+			method: "GET", path: "/api/v1/results/{id}", id: "getResult",
+			summary: "Look a result up again with its source's details",
+			params: []apiParam{
+				{"id", "path", "string", true, "A result's id"},
+				profileParam,
+			},
+			status: http.StatusOK, response: apiResult{}, handler: handleAPIResult,
+		},
+		{
+			method: "GET", path: "/api/v1/sources", id: "listSources",
+			summary: "List the search sources and what they support",
+			status:  http.StatusOK, response: apiSourceList{}, handler: handleAPISources,
+		},
+		{
+			method: "POST", path: "/api/v1/grabs", id: "createGrab",
+			summary: "Save a torrent on the server or send it to the download client",
+			body:    apiGrabRequest{},
+			status:  http.StatusCreated, response: grab{}, handler: handleAPICreateGrab,
+		},
+		{
+			method: "GET", path: "/api/v1/grabs", id: "listGrabs",
+			summary: "List your grabs, newest first",
+			params: []apiParam{
+				limitParam,
+				{"cursor", "query", "string", false, "next_cursor of the previous page"},
+			},
+			status: http.StatusOK, response: apiGrabPage{}, handler: handleAPIGrabs,
+		},
+		{
+			method: "GET", path: "/api/v1/torrent-file", id: "getTorrentFile",
+			summary: "Download a .torrent, by URL or by hash from the swarm or a cache service",
+			params: []apiParam{
+				{"hash", "query", "string", false, "Infohash; required without url, checked against the file with it"},
+				{"url", "query", "string", false, ".torrent URL from a result's torrents; must be on one of TORRENT_HOSTS"},
+				{"title", "query", "string", false, "Names the file"},
+				{"quality", "query", "string", false, "Added to the file name"},
+			},
+			status: http.StatusOK, contentType: "application/x-bittorrent", handler: handleAPITorrentFile,
+		},
+		{
+			method: "GET", path: "/api/v1/status", id: "getStatus",
+			summary: "Server features, OMDB quota and the signed-in user",
+			status:  http.StatusOK, response: serverStatus{}, handler: handleAPIStatus,
+		},
+		{
+			method: "POST", path: "/api/v1/session", id: "signIn",
+			summary: "Sign in, setting the session cookie",
+			body:    credentials{}, public: true,
+			status: http.StatusCreated, response: apiSession{}, handler: handleAPISignIn,
+		},
+		{
+			method: "DELETE", path: "/api/v1/session", id: "signOut",
+			summary: "Sign out", public: true,
+			status: http.StatusNoContent, handler: handleAPISignOut,
+		},
+		{
+			method: "GET", path: "/api/v1/openapi.json", id: "getOpenAPI",
+			summary: "This document", public: true,
+			status: http.StatusOK, response: map[string]any{}, handler: handleOpenAPI,
+		},
+	}
+}
+
+// registerAPI serves apiRoutes on mux. Paths are matched by ServeMux and
+// methods here, so a wrong method gets the error envelope too. Paths whose
+// routes are all public are added to authExempt.
+func registerAPI(mux *http.ServeMux) {
+	byPath := map[string][]apiRoute{}
+	var paths []string
+	for _, rt := range apiRoutes() {
+		if byPath[rt.path] == nil {
+			paths = append(paths, rt.path)
+		}
+		byPath[rt.path] = append(byPath[rt.path], rt)
+	}
+
+	for _, path := range paths {
+		routes := byPath[path]
+		if !slices.ContainsFunc(routes, func(rt apiRoute) bool { return !rt.public }) {
+			authExempt[path] = true
+		}
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			var allow []string
+			for _, rt := range routes {
+				if r.Method == rt.method {
+					rt.handler(w, r)
+					return
+				}
+				allow = append(allow, rt.method)
+			}
+			w.Header().Set("Allow", strings.Join(allow, ", "))
+			apiError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, fmt.Sprintf("use %s", strings.Join(allow, " or ")))
+		})
+	}
+	mux.HandleFunc("/api/v1/", func(w http.ResponseWriter, r *http.Request) {
+		apiError(w, http.StatusNotFound, codeNotFound, "no such endpoint")
+	})
+}
+
+// legacyDeprecation is when the unversioned /api routes were deprecated in
+// favour of /api/v1, as a Deprecation header value (RFC 9745).
+const legacyDeprecation = "@1792108800" // 2026-10-16
+
+// deprecated marks a legacy /api handler's responses as deprecated, linking
+// to the /api/v1 endpoint that replaces it.
+func deprecated(successor string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", legacyDeprecation)
+		w.Header().Set("Link", fmt.Sprintf(`<%s%s>; rel="successor-version"`, basePath, successor))
+		h(w, r)
+	}
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Authentication is off unless AUTH_FILE names a users file:
+//
+//	[users.alice]
+//	password = "$2a$10$..."              # c-cli-web hash-password
+//	tokens = ["9f86d081884c7d65..."]     # c-cli-web new-token
+//	download_dir = "/srv/torrents/alice" # default: DOWNLOAD_DIR/alice
+//
+// Browsers sign in at /api/login for a session cookie; scripts send one of
+// their tokens as "Authorization: Bearer <token>".
+var auth *authenticator
+
+// sessionCookie names the cookie that carries a session ID.
+const sessionCookie = "c-cli-session"
+
+// sessionTTL is how long a login lasts. Sessions live in memory, so a
+// restart signs everyone out.
+const sessionTTL = 7 * 24 * time.Hour
+
+// user is a signed-in account.
+type user struct {
+	Name        string
+	DownloadDir string
+}
+
+type account struct {
+	user
+	password []byte // bcrypt hash; nil for token-only accounts
+}
+
+type session struct {
+	user    *user
+	expires time.Time
+}
+
+type authenticator struct {
+	accounts map[string]*account
+	tokens   map[string]*user // By hex SHA-256 of the token
+	// Compared against for unknown users, so a failed login takes as long
+	// whether or not the user exists
+	dummyHash []byte
+
+	mu       sync.Mutex
+	sessions map[string]session
+}
+
+// userNamePattern keeps user names usable as directory names.
+var userNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9_.-]*$`)
+
+// loadAuthFromEnv reads AUTH_FILE. It returns nil, with no error, when
+// authentication is off.
+func loadAuthFromEnv() (*authenticator, error) {
+	path := os.Getenv("AUTH_FILE")
+	if path == "" {
+		return nil, nil
+	}
+	var file struct {
+		Users map[string]struct {
+			Password    string   `toml:"password"`
+			Tokens      []string `toml:"tokens"`
+			DownloadDir string   `toml:"download_dir"`
+		} `toml:"users"`
+	}
+	if _, err := toml.DecodeFile(path, &file); err != nil {
+		return nil, fmt.Errorf("AUTH_FILE: %w", err)
+	}
+	if len(file.Users) == 0 {
+		return nil, fmt.Errorf("AUTH_FILE: %s has no [users.<name>] tables", path)
+	}
+
+	a := &authenticator{
+		accounts: make(map[string]*account),
+		tokens:   make(map[string]*user),
+		sessions: make(map[string]session),
+	}
+	for name, u := range file.Users {
+		if !userNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("AUTH_FILE: invalid user name %q", name)
+		}
+		acc := &account{user: user{Name: name, DownloadDir: u.DownloadDir}}
+		if acc.DownloadDir == "" {
+			acc.DownloadDir = filepath.Join(downloadDir, name)
+		}
+		if u.Password != "" {
+			if _, err := bcrypt.Cost([]byte(u.Password)); err != nil {
+				return nil, fmt.Errorf("AUTH_FILE: user %s: password is not a bcrypt hash (use c-cli-web hash-password)", name)
+			}
+			acc.password = []byte(u.Password)
+		}
+		for _, t := range u.Tokens {
+			t = strings.ToLower(t)
+			if b, err := hex.DecodeString(t); err != nil || len(b) != sha256.Size {
+				return nil, fmt.Errorf("AUTH_FILE: user %s: token is not a SHA-256 hash (use c-cli-web new-token)", name)
+			}
+			a.tokens[t] = &acc.user
+		}
+		a.accounts[name] = acc
+	}
+
+	var err error
+	a.dummyHash, err = bcrypt.GenerateFromPassword([]byte("dummy"), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// login checks a user's password.
+func (a *authenticator) login(name, password string) *user {
+	acc, ok := a.accounts[name]
+	hash := a.dummyHash
+	if ok && acc.password != nil {
+		hash = acc.password
+	}
+	err := bcrypt.CompareHashAndPassword(hash, []byte(password))
+	if !ok || acc.password == nil || err != nil {
+		return nil
+	}
+	return &acc.user
+}
+
+// newSession starts a session for u and returns its ID.
+func (a *authenticator) newSession(u *user) string {
+	id := randomToken()
+	now := time.Now()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for k, s := range a.sessions {
+		if now.After(s.expires) {
+			delete(a.sessions, k)
+		}
+	}
+	a.sessions[id] = session{user: u, expires: now.Add(sessionTTL)}
+	return id
+}
+
+func (a *authenticator) endSession(id string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.sessions, id)
+}
+
+// authenticate returns the user a request's bearer token or session
+// cookie belongs to, or nil.
+func (a *authenticator) authenticate(r *http.Request) *user {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return a.tokens[hashToken(strings.TrimSpace(token))]
+	}
+	c, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	s, ok := a.sessions[c.Value]
+	if !ok || time.Now().After(s.expires) {
+		return nil
+	}
+	return s.user
+}
+
+// authExempt lists the paths served without signing in: the page itself,
+// which shows the login form, and signing in. registerAPI adds the public
+// /api/v1 routes.
+var authExempt = map[string]bool{
+	"/":           true,
+	"/api/login":  true,
+	"/api/logout": true,
+}
+
+// isAuthExempt reports whether path is served without signing in. The
+// Torznab API is when TORZNAB_API_KEY protects it instead, since Sonarr and
+// Radarr can only send an apikey parameter.
+func isAuthExempt(path string) bool {
+	return authExempt[path] || (path == "/torznab/api" && os.Getenv("TORZNAB_API_KEY") != "")
+}
+
+type userKey struct{}
+
+// requireAuth refuses requests that aren't signed in, when authentication
+// is on, and passes the user on in the request context.
+func requireAuth(next http.Handler) http.Handler {
+	if auth == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isAuthExempt(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		u := auth.authenticate(r)
+		if u == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="c-cli-web"`)
+			if strings.HasPrefix(r.URL.Path, "/api/v1/") {
+				apiError(w, http.StatusUnauthorized, codeUnauthorized, "authentication required")
+			} else {
+				jsonError(w, "authentication required", http.StatusUnauthorized)
+			}
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, u)))
+	})
+}
+
+// requestUser returns the signed-in user; nil when authentication is off.
+func requestUser(r *http.Request) *user {
+	u, _ := r.Context().Value(userKey{}).(*user)
+	return u
+}
+
+// userDownloadDir is where files the request saves go: the user's own
+// directory, or DOWNLOAD_DIR when authentication is off.
+func userDownloadDir(r *http.Request) (string, error) {
+	u := requestUser(r)
+	if u == nil {
+		return downloadDir, nil
+	}
+	if err := os.MkdirAll(u.DownloadDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create download directory: %w", err)
+	}
+	return u.DownloadDir, nil
+}
+
+// credentials is a login request.
+type credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// handleLogin checks a JSON {"username", "password"} and sets the session
+// cookie.
+func handleLogin(w http.ResponseWriter, r *http.Request) {
+	if auth == nil {
+		jsonError(w, "authentication is not enabled", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodPost {
+		jsonError(w, "use POST", http.StatusMethodNotAllowed)
+		return
+	}
+	var creds credentials
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&creds); err != nil {
+		jsonError(w, "invalid login request", http.StatusBadRequest)
+		return
+	}
+
+	u := signIn(w, r, creds)
+	if u == nil {
+		jsonError(w, "invalid username or password", http.StatusUnauthorized)
+		return
+	}
+	jsonResponse(w, map[string]string{"user": u.Name})
+}
+
+// signIn checks creds and sets the session cookie. It returns nil when
+// they're wrong.
+func signIn(w http.ResponseWriter, r *http.Request, creds credentials) *user {
+	u := auth.login(creds.Username, creds.Password)
+	if u == nil {
+		log.Printf("Failed login for %q from %s", creds.Username, r.RemoteAddr)
+		return nil
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    auth.newSession(u),
+		Path:     basePath + "/",
+		MaxAge:   int(sessionTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		// Strict, so no other site can make a signed-in browser grab
+		// torrents through the GET download endpoints
+		SameSite: http.SameSiteStrictMode,
+	})
+	return u
+}
+
+func handleLogout(w http.ResponseWriter, r *http.Request) {
+	if auth == nil {
+		jsonError(w, "authentication is not enabled", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodPost {
+		jsonError(w, "use POST", http.StatusMethodNotAllowed)
+		return
+	}
+	signOut(w, r)
+	jsonResponse(w, map[string]bool{"ok": true})
+}
+
+// signOut ends the request's session and clears the cookie.
+func signOut(w http.ResponseWriter, r *http.Request) {
+	if c, err := r.Cookie(sessionCookie); err == nil {
+		auth.endSession(c.Value)
+	}
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Path: basePath + "/", MaxAge: -1, HttpOnly: true, Secure: isHTTPS(r)})
+}
+
+func randomToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// hashToken is how API tokens are kept in AUTH_FILE. They are random, so
+// unlike passwords they don't need a slow hash.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// runCommand runs the helper commands for writing AUTH_FILE.
+func runCommand(args []string) error {
+	switch args[0] {
+	case "hash-password":
+		fmt.Fprint(os.Stderr, "Password: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("no password given")
+		}
+		password := strings.TrimRight(line, "\r\n")
+		if password == "" {
+			return fmt.Errorf("no password given")
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("password = %q\n", hash)
+	case "new-token":
+		token := randomToken()
+		fmt.Printf("Token (give this to the script): %s\n", token)
+		fmt.Printf("Add to the user's tokens in AUTH_FILE: %q\n", hashToken(token))
+	default:
+		return errors.New("usage: c-cli-web [hash-password | new-token]")
+	}
+	return nil
+}
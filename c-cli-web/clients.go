@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"c-cli/core"
+)
+
+// downloadClient is the client selected by DOWNLOAD_CLIENT, or nil.
+// downloadClientErr holds the reason it could not be built.
+var (
+	downloadClient    core.DownloadClient
+	downloadClientErr error
+)
+
+// newDownloadClientFromEnv builds the client selected by DOWNLOAD_CLIENT.
+func newDownloadClientFromEnv() (core.DownloadClient, error) {
+	return lib.NewDownloadClient(core.ClientConfig{
+		Kind:     os.Getenv("DOWNLOAD_CLIENT"),
+		WatchDir: os.Getenv("WATCH_DIR"),
+		QBittorrent: core.QBittorrentConfig{
+			URL:      os.Getenv("QBITTORRENT_URL"),
+			Username: os.Getenv("QBITTORRENT_USERNAME"),
+			Password: os.Getenv("QBITTORRENT_PASSWORD"),
+			Category: os.Getenv("QBITTORRENT_CATEGORY"),
+			SavePath: os.Getenv("QBITTORRENT_SAVE_PATH"),
+			Tags:     splitList(os.Getenv("QBITTORRENT_TAGS")),
+		},
+		Transmission: core.TransmissionConfig{
+			URL:         os.Getenv("TRANSMISSION_URL"),
+			Username:    os.Getenv("TRANSMISSION_USERNAME"),
+			Password:    os.Getenv("TRANSMISSION_PASSWORD"),
+			DownloadDir: os.Getenv("TRANSMISSION_DOWNLOAD_DIR"),
+			Labels:      splitList(os.Getenv("TRANSMISSION_LABELS")),
+		},
+		Aria2: core.Aria2Config{
+			URL:    os.Getenv("ARIA2_URL"),
+			Secret: os.Getenv("ARIA2_SECRET"),
+			Dir:    os.Getenv("ARIA2_DIR"),
+		},
+		Deluge: core.DelugeConfig{
+			URL:         os.Getenv("DELUGE_URL"),
+			Password:    os.Getenv("DELUGE_PASSWORD"),
+			DownloadDir: os.Getenv("DELUGE_DOWNLOAD_DIR"),
+			Label:       os.Getenv("DELUGE_LABEL"),
+		},
+		RTorrent: core.RTorrentConfig{
+			URL:         os.Getenv("RTORRENT_URL"),
+			Username:    os.Getenv("RTORRENT_USERNAME"),
+			Password:    os.Getenv("RTORRENT_PASSWORD"),
+			DownloadDir: os.Getenv("RTORRENT_DOWNLOAD_DIR"),
+			Label:       os.Getenv("RTORRENT_LABEL"),
+		},
+	})
+}
+
+// splitList splits a comma-separated environment value, dropping blanks.
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
@@ -1,3 +1,11 @@
 module c-cli-web
 
-go 1.22.2
+go 1.24.0
+
+require (
+	c-cli v0.0.0
+	github.com/BurntSushi/toml v1.6.0
+	golang.org/x/crypto v0.36.0
+)
+
+replace c-cli => ../
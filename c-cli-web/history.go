@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// grab is a torrent someone saved, downloaded or sent to a client.
+type grab struct {
+	Time     time.Time `json:"time"`
+	User     string    `json:"user,omitempty" doc:"Signed-in user, when authentication is on"`
+	Action   string    `json:"action" doc:"download (saved on the server), send (to the download client) or download-file (to the browser); the legacy API also logs save-magnet and download-torrent"`
+	Title    string    `json:"title"`
+	Quality  string    `json:"quality,omitempty"`
+	Hash     string    `json:"hash,omitempty"`
+	URL      string    `json:"url,omitempty"`
+	File     string    `json:"file,omitempty" doc:"Where it was saved on the server"`
+	Client   string    `json:"client,omitempty" doc:"Download client it was sent to"`
+	ClientID string    `json:"client_id,omitempty" doc:"The download client's ID for it"`
+	Remote   string    `json:"remote" doc:"Client address"`
+}
+
+// historySize is how many recent grabs /api/history keeps per user.
+const historySize = 100
+
+// grabLog keeps each user's recent grabs and appends every grab to the
+// audit log, AUDIT_LOG, as a line of JSON. The log defaults to audit.log
+// next to AUTH_FILE when authentication is on; "off" disables it.
+type grabLog struct {
+	mu      sync.Mutex
+	file    *os.File
+	history map[string][]grab // By user, oldest first
+}
+
+var grabs = &grabLog{history: make(map[string][]grab)}
+
+func openGrabLogFromEnv() {
+	path := os.Getenv("AUDIT_LOG")
+	if path == "" && auth != nil {
+		path = filepath.Join(filepath.Dir(os.Getenv("AUTH_FILE")), "audit.log")
+	}
+	if path == "" || path == "off" {
+		return
+	}
+	if err := grabs.open(path); err != nil {
+		log.Printf("Audit log disabled: %v", err)
+	}
+}
+
+// open appends to the audit log at path, reading back the grabs already
+// in it so history survives restarts.
+func (l *grabLog) open(path string) error {
+	if f, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var g grab
+			if json.Unmarshal(scanner.Bytes(), &g) == nil {
+				l.remember(g)
+			}
+		}
+		f.Close()
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	l.file = f
+	return nil
+}
+
+func (l *grabLog) remember(g grab) {
+	h := append(l.history[g.User], g)
+	if len(h) > historySize {
+		h = slices.Clone(h[len(h)-historySize:])
+	}
+	l.history[g.User] = h
+}
+
+// record logs a grab made by r's user, and returns it as logged.
+func (l *grabLog) record(r *http.Request, g grab) grab {
+	g.Time = time.Now().UTC()
+	if u := requestUser(r); u != nil {
+		g.User = u.Name
+	}
+	g.Remote = r.RemoteAddr
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.remember(g)
+	if l.file == nil {
+		return g
+	}
+	line, _ := json.Marshal(g)
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		log.Printf("Audit log: %v", err)
+	}
+	return g
+}
+
+// recent returns a user's grabs, newest first.
+func (l *grabLog) recent(user string) []grab {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	h := slices.Clone(l.history[user])
+	slices.Reverse(h)
+	if h == nil {
+		h = []grab{}
+	}
+	return h
+}
+
+// handleHistory lists the signed-in user's recent grabs; everyone's, when
+// authentication is off.
+func handleHistory(w http.ResponseWriter, r *http.Request) {
+	name := ""
+	if u := requestUser(r); u != nil {
+		name = u.Name
+	}
+	jsonResponse(w, map[string]any{"grabs": grabs.recent(name)})
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"c-cli/throttle"
+)
+
+// Request limits, shared by every search the server is running at once.
+// OMDB_CONCURRENCY/OMDB_RATE and PROVIDER_CONCURRENCY/PROVIDER_RATE (per
+// search provider) override the defaults.
+var (
+	omdbHTTP       *http.Client
+	providerLimits throttle.Limits
+
+	enrichPool *throttle.Pool
+
+	providerClientsMu sync.Mutex
+	providerClients   = map[string]*http.Client{}
+)
+
+func loadLimitsFromEnv() {
+	omdbLimits := limitsFromEnv("OMDB", throttle.Limits{Concurrency: 4, Rate: 5, Burst: 5, Retries: 2, Timeout: 10 * time.Second})
+	providerLimits = limitsFromEnv("PROVIDER", throttle.Limits{Concurrency: 2, Rate: 2, Burst: 4, Retries: 2, Timeout: 15 * time.Second})
+
+	omdbHTTP = throttle.Client(throttle.New(omdbLimits))
+	enrichPool = throttle.NewPool(omdbLimits.Concurrency)
+}
+
+// providerHTTP returns the rate-limited client for a search provider: YTS,
+// Torrents-CSV or one Torznab indexer. Each gets its own limiter, shared by
+// every search so its limits hold across them.
+func providerHTTP(service string) *http.Client {
+	providerClientsMu.Lock()
+	defer providerClientsMu.Unlock()
+	c, ok := providerClients[service]
+	if !ok {
+		c = throttle.Client(throttle.New(providerLimits))
+		providerClients[service] = c
+	}
+	return c
+}
+
+// limitsFromEnv applies <prefix>_CONCURRENCY and <prefix>_RATE to l.
+func limitsFromEnv(prefix string, l throttle.Limits) throttle.Limits {
+	if n, err := strconv.Atoi(os.Getenv(prefix + "_CONCURRENCY")); err == nil && n > 0 {
+		l.Concurrency = n
+	}
+	if r, err := strconv.ParseFloat(os.Getenv(prefix+"_RATE"), 64); err == nil && r > 0 {
+		l.Rate = r
+		l.Burst = max(l.Burst, int(r))
+	}
+	return l
+}
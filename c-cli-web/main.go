@@ -1,36 +1,42 @@
 package main
 
 import (
+	"context"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
-	"regexp"
-	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"c-cli/core"
+	"c-cli/omdb"
+	"c-cli/release"
+	"c-cli/tracker"
 )
 
 //go:embed static/*
 var staticFiles embed.FS
 
-const (
-	ytsBaseURL     = "https://yts.bz/api/v2"
-	torrentsCSVURL = "https://torrents-csv.com/service/search"
-)
-
-var httpClient = &http.Client{Timeout: 15 * time.Second}
 var downloadDir string
 var omdbAPIKey string
+var metadataFetch bool
 
 func main() {
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		return
+	}
+
 	// Default download dir to home directory
 	downloadDir, _ = os.UserHomeDir()
 	if dir := os.Getenv("DOWNLOAD_DIR"); dir != "" {
@@ -43,16 +49,55 @@ func main() {
 	}
 
 	http.HandleFunc("/", handleIndex)
-	http.HandleFunc("/api/search", handleSearch)
-	http.HandleFunc("/api/movie/", handleMovieDetails)
-	http.HandleFunc("/api/omdb", handleOMDBLookup)
-	http.HandleFunc("/api/magnet", handleMagnet)
-	http.HandleFunc("/api/download", handleDownloadToServer)
-	http.HandleFunc("/api/download-file", handleDownloadToClient)
-	http.HandleFunc("/api/save-magnet", handleSaveMagnet)
-	http.HandleFunc("/api/download-torrent", handleDownloadTorrentToClient)
+	registerAPI(http.DefaultServeMux)
+	// The unversioned API, kept for the page and existing scripts
+	http.HandleFunc("/api/search", deprecated("/api/v1/search", handleSearch))
+	http.HandleFunc("/api/movie/", deprecated("/api/v1/results/{id}", handleMovieDetails))
+	http.HandleFunc("/api/omdb", deprecated("/api/v1/search", handleOMDBLookup))
+	http.HandleFunc("/api/status", deprecated("/api/v1/status", handleStatus))
+	http.HandleFunc("/api/magnet", deprecated("/api/v1/search", handleMagnet))
+	http.HandleFunc("/api/download", deprecated("/api/v1/grabs", handleDownloadToServer))
+	http.HandleFunc("/api/download-file", deprecated("/api/v1/torrent-file", handleDownloadToClient))
+	http.HandleFunc("/api/save-magnet", deprecated("/api/v1/grabs", handleSaveMagnet))
+	http.HandleFunc("/api/download-torrent", deprecated("/api/v1/torrent-file", handleDownloadTorrentToClient))
+	http.HandleFunc("/api/send", deprecated("/api/v1/grabs", handleSendToClient))
+	http.HandleFunc("/api/history", deprecated("/api/v1/grabs", handleHistory))
+	http.HandleFunc("/api/login", deprecated("/api/v1/session", handleLogin))
+	http.HandleFunc("/api/logout", deprecated("/api/v1/session", handleLogout))
+	http.HandleFunc("/torznab/api", handleTorznab)
 
 	omdbAPIKey = os.Getenv("OMDB_API_KEY")
+	loadLimitsFromEnv()
+	loadProfilesFromEnv()
+	omdbClient = newOMDBClientFromEnv()
+	metadataFetch, _ = strconv.ParseBool(os.Getenv("METADATA_FETCH"))
+	trackerList = loadTrackersFromEnv()
+	go probeTrackers()
+	lib = core.New(core.Options{
+		HTTP:           providerHTTP,
+		OMDB:           omdbClient,
+		Enrich:         enrichPool,
+		Trackers:       trackerList,
+		MagnetTrackers: magnetTrackers,
+		MetadataFetch:  metadataFetch,
+		TorrentHosts:   torrentHostsFromEnv(),
+		PublicOnly:     true,
+	})
+	var err error
+	if auth, err = loadAuthFromEnv(); err != nil {
+		log.Fatal(err)
+	}
+	if auth != nil && os.Getenv("TORZNAB_API_KEY") == "" {
+		log.Printf("Torznab API requires signing in; set TORZNAB_API_KEY for Sonarr and Radarr")
+	}
+	openGrabLogFromEnv()
+	if err := loadProxyFromEnv(); err != nil {
+		log.Fatal(err)
+	}
+	downloadClient, downloadClientErr = newDownloadClientFromEnv()
+	if downloadClientErr != nil {
+		log.Printf("Download client disabled: %v", downloadClientErr)
+	}
 
 	host := "127.0.0.1"
 	if h := os.Getenv("HOST"); h != "" {
@@ -64,8 +109,21 @@ func main() {
 	if omdbAPIKey != "" {
 		omdbStatus = "enabled"
 	}
-	log.Printf("Starting server on %s (downloads to: %s, OMDB: %s)", addr, downloadDir, omdbStatus)
-	log.Fatal(http.ListenAndServe(addr, nil))
+	authStatus := "off"
+	if auth != nil {
+		authStatus = fmt.Sprintf("%d users", len(auth.accounts))
+	}
+	log.Printf("Starting server on %s%s/ (downloads to: %s, OMDB: %s, auth: %s)", addr, basePath, downloadDir, omdbStatus, authStatus)
+	log.Fatal(serve(addr, forwarded(mountAt(basePath, requireAuth(http.DefaultServeMux)))))
+}
+
+// torrentHostsFromEnv is the download endpoints' host allowlist: the
+// comma-separated TORRENT_HOSTS, or the built-in providers' hosts.
+func torrentHostsFromEnv() []string {
+	if hosts := splitList(os.Getenv("TORRENT_HOSTS")); len(hosts) > 0 {
+		return hosts
+	}
+	return core.ProviderHosts
 }
 
 func handleIndex(w http.ResponseWriter, r *http.Request) {
@@ -78,426 +136,148 @@ func handleIndex(w http.ResponseWriter, r *http.Request) {
 	w.Write(data)
 }
 
-// API Types
-type Movie struct {
-	ID                int        `json:"id"`
-	Title             string     `json:"title"`
-	Year              int        `json:"year"`
-	Rating            float64    `json:"rating"`
-	Runtime           int        `json:"runtime"`
-	Genres            []string   `json:"genres"`
-	Summary           string     `json:"summary"`
-	Description       string     `json:"description_full"`
-	IMDBCode          string     `json:"imdb_code"`
-	SmallCover        string     `json:"small_cover_image"`
-	MediumCover       string     `json:"medium_cover_image"`
-	LargeCover        string     `json:"large_cover_image"`
-	Torrents          []Torrent  `json:"torrents"`
-	OMDB              *OMDBMovie `json:"omdb,omitempty"`
-}
-
-type Torrent struct {
-	URL     string `json:"url"`
-	Hash    string `json:"hash"`
-	Quality string `json:"quality"`
-	Type    string `json:"type"`
-	Size    string `json:"size"`
-	Seeds   int    `json:"seeds"`
-	Peers   int    `json:"peers"`
-}
-
-type searchResponse struct {
-	Status string `json:"status"`
-	Data   struct {
-		MovieCount int     `json:"movie_count"`
-		Movies     []Movie `json:"movies"`
-	} `json:"data"`
-}
-
-type detailResponse struct {
-	Status string `json:"status"`
-	Data   struct {
-		Movie Movie `json:"movie"`
-	} `json:"data"`
-}
-
-// TorrentsCSV types
-type TorrentsCSVResponse struct {
-	Torrents []TorrentsCSVItem `json:"torrents"`
-	Next     *int              `json:"next,omitempty"` // Cursor for next page
-}
-
-type TorrentsCSVItem struct {
-	Infohash    string `json:"infohash"`
-	Name        string `json:"name"`
-	SizeBytes   int64  `json:"size_bytes"`
-	Seeders     int    `json:"seeders"`
-	Leechers    int    `json:"leechers"`
-	CreatedUnix int64  `json:"created_unix"`
-}
-
-// Generic search result for unified API
-type SearchResult struct {
-	ID        string     `json:"id"`
-	Title     string     `json:"title"`
-	Year      int        `json:"year,omitempty"`
-	Source    string     `json:"source"` // "yts" or "torrents-csv"
-	Infohash  string     `json:"infohash,omitempty"`
-	Size      string     `json:"size,omitempty"`
-	Seeders   int        `json:"seeders"`
-	Leechers  int        `json:"leechers"`
-	IMDBCode  string     `json:"imdb_code,omitempty"`
-	OMDB      *OMDBMovie `json:"omdb,omitempty"`
-	// YTS specific
-	SmallCover  string    `json:"small_cover_image,omitempty"`
-	MediumCover string    `json:"medium_cover_image,omitempty"`
-	Torrents    []Torrent `json:"torrents,omitempty"`
-}
-
-// OMDB types
-type OMDBMovie struct {
-	Title        string `json:"Title"`
-	Year         string `json:"Year"`
-	Rated        string `json:"Rated"`
-	Released     string `json:"Released"`
-	Runtime      string `json:"Runtime"`
-	Genre        string `json:"Genre"`
-	Director     string `json:"Director"`
-	Writer       string `json:"Writer"`
-	Actors       string `json:"Actors"`
-	Plot         string `json:"Plot"`
-	Poster       string `json:"Poster"`
-	IMDBRating   string `json:"imdbRating"`
-	IMDBVotes    string `json:"imdbVotes"`
-	IMDBID       string `json:"imdbID"`
-	Type         string `json:"Type"`         // "movie", "series", or "episode"
-	TotalSeasons string `json:"totalSeasons"` // Only for series
-	Response     string `json:"Response"`
-	Error        string `json:"Error"`
-}
-
-func fetchOMDBInfo(imdbID string) (*OMDBMovie, error) {
-	if omdbAPIKey == "" || imdbID == "" {
-		return nil, nil
-	}
-
-	url := fmt.Sprintf("http://www.omdbapi.com/?i=%s&apikey=%s", imdbID, omdbAPIKey)
-	resp, err := httpClient.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var movie OMDBMovie
-	if err := json.NewDecoder(resp.Body).Decode(&movie); err != nil {
-		return nil, err
-	}
+// lib searches the providers and fetches torrents for every handler.
+var lib *core.Client
 
-	if movie.Response == "False" {
-		return nil, nil
-	}
-
-	return &movie, nil
+// movieDetails is a YTS movie with the quality profile's pick among its
+// torrents.
+type movieDetails struct {
+	core.Movie
+	Best *bestTorrent `json:"best,omitempty"`
 }
 
-func enrichAndSortMovies(movies []Movie) []Movie {
-	var wg sync.WaitGroup
-	var mu sync.Mutex
-
-	// Fetch OMDB data concurrently
-	for i := range movies {
-		wg.Add(1)
-		go func(idx int) {
-			defer wg.Done()
-			if movies[idx].IMDBCode != "" {
-				if omdb, err := fetchOMDBInfo(movies[idx].IMDBCode); err == nil && omdb != nil {
-					mu.Lock()
-					movies[idx].OMDB = omdb
-					mu.Unlock()
-				}
-			}
-		}(i)
-	}
-	wg.Wait()
-
-	// Sort by IMDB votes (descending)
-	sort.Slice(movies, func(i, j int) bool {
-		votesI := parseVotes(movies[i].OMDB)
-		votesJ := parseVotes(movies[j].OMDB)
-		return votesI > votesJ
-	})
-
-	return movies
-}
-
-func parseVotes(omdb *OMDBMovie) int {
-	if omdb == nil || omdb.IMDBVotes == "" || omdb.IMDBVotes == "N/A" {
-		return 0
-	}
-	// Remove commas from vote count (e.g., "1,234,567" -> "1234567")
-	voteStr := strings.ReplaceAll(omdb.IMDBVotes, ",", "")
-	votes, _ := strconv.Atoi(voteStr)
-	return votes
-}
-
-func extractYearAndIMDB(name string) (int, string) {
-	year := 0
-	imdbCode := ""
-
-	// Try to extract year from patterns like (2010) or 2010
-	yearRegex := regexp.MustCompile(`[\(\[]?(19\d{2}|20\d{2})[\)\]]?`)
-	if match := yearRegex.FindString(name); match != "" {
-		match = strings.Trim(match, "()[]")
-		year, _ = strconv.Atoi(match)
-	}
-
-	// Try to extract IMDB code if present (rare in torrent names)
-	imdbRegex := regexp.MustCompile(`tt\d{7,}`)
-	if match := imdbRegex.FindString(name); match != "" {
-		imdbCode = match
+// SearchResult is how /api/search lists Torrents-CSV results.
+type SearchResult struct {
+	ID        string          `json:"id"`
+	Title     string          `json:"title"`
+	Year      int             `json:"year,omitempty"`
+	Name      string          `json:"name,omitempty"` // Raw release name
+	Source    string          `json:"source"`         // "torrents-csv"
+	Infohash  string          `json:"infohash,omitempty"`
+	Size      string          `json:"size,omitempty"`
+	SizeBytes int64           `json:"size_bytes,omitempty"`
+	Seeders   int             `json:"seeders"`
+	Leechers  int             `json:"leechers"`
+	IMDBCode  string          `json:"imdb_code,omitempty"`
+	OMDB      *core.OMDBMovie `json:"omdb,omitempty"`
+	// Torrents-CSV specific
+	CreatedUnix int64 `json:"created_unix,omitempty"`
+}
+
+// toSearchResult lists a single-torrent result from core in the
+// SearchResult shape.
+func toSearchResult(m core.Movie) SearchResult {
+	r := SearchResult{
+		ID:       m.Infohash,
+		Title:    m.Title,
+		Year:     m.Year,
+		Source:   string(m.Source),
+		Infohash: m.Infohash,
+		Size:     m.Size,
+		Seeders:  m.Seeders,
+		Leechers: m.Leechers,
+		IMDBCode: m.IMDBCode,
+		OMDB:     m.OMDB,
+	}
+	if len(m.Torrents) > 0 {
+		t := m.Torrents[0]
+		r.Name, r.SizeBytes, r.CreatedUnix = t.Name, t.SizeBytes, t.DateUploadedUnix
+	}
+	return r
+}
+
+// omdbClient looks titles up on OMDB through the on-disk cache shared with
+// the TUI.
+var omdbClient *omdb.Client
+
+// newOMDBClientFromEnv sets up OMDB lookups. OMDB_CACHE overrides the cache
+// file path, or disables the cache when set to "off"; request counts are
+// then kept in memory.
+func newOMDBClientFromEnv() *omdb.Client {
+	c := &omdb.Client{APIKey: omdbAPIKey, HTTP: omdbHTTP}
+	c.Cache = openOMDBCacheFromEnv()
+	var quotaPath string
+	if c.Cache != nil {
+		quotaPath = omdb.QuotaPath(c.Cache.Path())
+	}
+	quota, err := omdb.OpenQuota(quotaPath)
+	if err != nil {
+		log.Printf("OMDB quota: %v", err)
+		quota, _ = omdb.OpenQuota("")
 	}
-
-	return year, imdbCode
-}
-
-// cleanTitleForOMDB removes year and other suffixes that interfere with OMDB search
-func cleanTitleForOMDB(title string) string {
-	// Remove year in parentheses like "(2022)" or "(2022)" at end
-	re := regexp.MustCompile(`\s*\(\d{4}\)\s*$`)
-	title = re.ReplaceAllString(title, "")
-	
-	// Remove year without parentheses at end
-	re = regexp.MustCompile(`\s+\d{4}\s*$`)
-	title = re.ReplaceAllString(title, "")
-	
-	// Remove common quality/codec tags that might remain
-	re = regexp.MustCompile(`(?i)\s*(10bit|AV1|AV1tester|HDR|HEVC|x264|x265).*$`)
-	title = re.ReplaceAllString(title, "")
-	
-	return strings.TrimSpace(title)
+	quota.Limit, _ = strconv.Atoi(os.Getenv("OMDB_DAILY_LIMIT"))
+	c.Quota = quota
+	return c
 }
 
-func cleanTorrentName(name string) string {
-	// Remove common torrent tags and clean up the name
-	cleaners := []string{
-		`\[.*?\]`,
-		`\(.*?(rip|YIFY|MX|HDR|BluRay|WEB|HDTV|DVDRip|BRRip|x264|x265|HEVC|AAC|DTS|10bit|5\.1|7\.1).*?\)`,
-		`(1080p|720p|480p|2160p|4K|HDR|HDR10|BluRay|BRRip|WEB-DL|WEBRip|HDTV|DVDRip)`,
-		`(x264|x265|HEVC|H\.?264|H\.?265|AVC)`,
-		`(AAC|DTS|AC3|FLAC|TrueHD|Atmos|5\.1|7\.1)`,
-		`(YIFY|YTS|MX|RARBG|SPARKS|FGT|EVO|GECKOS|AMZN|NF|iNTERNAL)`,
-		`\s*-\s*$`,
-		`\.mkv$|\.mp4$|\.avi$`,
-	}
-
-	result := name
-	for _, pattern := range cleaners {
-		re := regexp.MustCompile(`(?i)` + pattern)
-		result = re.ReplaceAllString(result, "")
-	}
-
-	// Replace dots and underscores with spaces
-	result = strings.ReplaceAll(result, ".", " ")
-	result = strings.ReplaceAll(result, "_", " ")
-
-	// Clean up multiple spaces
-	spaceRegex := regexp.MustCompile(`\s+`)
-	result = spaceRegex.ReplaceAllString(result, " ")
-
-	return strings.TrimSpace(result)
-}
-
-func formatBytes(bytes int64) string {
-	const unit = 1024
-	if bytes < unit {
-		return fmt.Sprintf("%d B", bytes)
-	}
-	div, exp := int64(unit), 0
-	for n := bytes / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
+func openOMDBCacheFromEnv() *omdb.Cache {
+	path := os.Getenv("OMDB_CACHE")
+	if path == "off" {
+		return nil
 	}
-	return fmt.Sprintf("%.2f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
-}
-
-// extractShowName extracts the show/movie name from a torrent title
-// For TV shows like "Breaking Bad S01E01", returns "Breaking Bad"
-func extractShowName(title string) string {
-	// TV show patterns to remove - order matters, more specific first
-	patterns := []string{
-		`(?i)\s*-?\s*s\d{1,2}e\d{1,2}.*$`,     // S01E01 and everything after
-		`(?i)\s*-?\s*s\d{1,2}\s*-?\s*s\d{1,2}.*$`, // S01-S05 range
-		`(?i)\s*-?\s*s\d{1,2}.*$`,             // S01 and everything after
-		`(?i)\s*-?\s*season\s*\d+.*$`,         // Season 1 and everything after
-		`(?i)\s*-?\s*\d{1,2}x\d{1,2}.*$`,      // 1x01 and everything after
-		`(?i)\s*-?\s*complete\s*(series|season|collection).*$`,
-		`(?i)\s*-?\s*all\s*seasons.*$`,
-		`(?i)\s*-?\s*full\s*series.*$`,
-		`(?i)\s*\(\d{4}\)\s*$`,                 // Year in parentheses at end
-		`(?i)\s*\d{4}\s*$`,                     // Year at end
+	if path == "" {
+		var err error
+		if path, err = omdb.DefaultPath(); err != nil {
+			log.Printf("OMDB cache disabled: %v", err)
+			return nil
+		}
 	}
-	
-	result := title
-	for _, p := range patterns {
-		re := regexp.MustCompile(p)
-		result = re.ReplaceAllString(result, "")
+	cache, err := omdb.Open(path)
+	if err != nil {
+		log.Printf("OMDB cache disabled: %v", err)
+		return nil
 	}
-	
-	// Also remove common suffixes that might remain
-	result = regexp.MustCompile(`(?i)\s*-\s*$`).ReplaceAllString(result, "")
-	
-	return strings.TrimSpace(result)
-}
-
-func enrichTorrentsCSVResults(results []SearchResult) []SearchResult {
-	var wg sync.WaitGroup
-	var mu sync.Mutex
-
-	// Try to find OMDB data by searching for title+year
-	for i := range results {
-		wg.Add(1)
-		go func(idx int) {
-			defer wg.Done()
-			r := &results[idx]
-
-			// If we have an IMDB code, use it directly
-			if r.IMDBCode != "" {
-				if omdb, err := fetchOMDBInfo(r.IMDBCode); err == nil && omdb != nil {
-					mu.Lock()
-					r.OMDB = omdb
-					mu.Unlock()
-				}
-				return
-			}
-
-			// Determine if this looks like TV content
-			isTVContent := looksLikeTVShow(r.Title)
-			
-			// Clean the title for OMDB search
-			searchTitle := cleanTitleForOMDB(r.Title)
-			if isTVContent {
-				searchTitle = extractShowName(searchTitle)
-			}
-
-			// Try to search by title and year
-			var omdb *OMDBMovie
-			if isTVContent {
-				// For TV content, search specifically as series first
-				omdb = searchOMDBWithType(searchTitle, r.Year, "series")
-				if omdb == nil {
-					// Fall back to general search
-					omdb = searchOMDBWithType(searchTitle, r.Year, "")
-				}
-			} else {
-				// For non-TV content, try general search first, then movie
-				omdb = searchOMDBWithType(searchTitle, r.Year, "")
-				if omdb == nil {
-					omdb = searchOMDBWithType(searchTitle, r.Year, "movie")
-				}
-			}
-			
-			if omdb != nil {
-				mu.Lock()
-				r.OMDB = omdb
-				r.IMDBCode = omdb.IMDBID
-				mu.Unlock()
-			}
-		}(i)
-	}
-	wg.Wait()
-
-	// Sort by IMDB votes
-	sort.Slice(results, func(i, j int) bool {
-		return parseVotes(results[i].OMDB) > parseVotes(results[j].OMDB)
-	})
-
-	return results
+	cache.TTL, _ = time.ParseDuration(os.Getenv("OMDB_CACHE_TTL"))
+	cache.NegativeTTL, _ = time.ParseDuration(os.Getenv("OMDB_CACHE_NEGATIVE_TTL"))
+	return cache
 }
 
-func searchOMDB(title string, year int) (*OMDBMovie, error) {
-	if omdbAPIKey == "" {
-		return nil, nil
-	}
-
-	// First try without type restriction (OMDB will return best match)
-	result := searchOMDBWithType(title, year, "")
-	if result != nil {
-		return result, nil
-	}
-
-	// If no result and title looks like a TV show, try searching as series
-	if looksLikeTVShow(title) {
-		return searchOMDBWithType(title, year, "series"), nil
-	}
-
-	return nil, nil
+// serverStatus reports server features and the OMDB request quota, so the
+// UI can tell when ratings come from the cache only.
+type serverStatus struct {
+	OMDB           omdbStatus `json:"omdb"`
+	DownloadClient string     `json:"download_client,omitempty"`
+	MetadataFetch  bool       `json:"metadata_fetch"`
+	Profile        string     `json:"profile" doc:"Quality profile used when a request doesn't name one"`
+	Profiles       []string   `json:"profiles"`
+	Auth           bool       `json:"auth" doc:"Whether signing in is required"`
+	User           string     `json:"user,omitempty" doc:"Signed-in user"`
 }
 
-func searchOMDBWithType(title string, year int, mediaType string) *OMDBMovie {
-	if omdbAPIKey == "" {
-		return nil
-	}
-	
-	// Try with year first
-	if year > 0 {
-		if result := doOMDBSearch(title, year, mediaType); result != nil {
-			return result
-		}
-	}
-	
-	// Try without year as fallback
-	return doOMDBSearch(title, 0, mediaType)
+type omdbStatus struct {
+	Enabled bool              `json:"enabled"`
+	Quota   *omdb.QuotaStatus `json:"quota,omitempty"`
+	Cache   *omdb.Stats       `json:"cache,omitempty"`
 }
 
-func doOMDBSearch(title string, year int, mediaType string) *OMDBMovie {
-	params := url.Values{}
-	params.Set("t", title)
-	params.Set("apikey", omdbAPIKey)
-	if year > 0 {
-		params.Set("y", strconv.Itoa(year))
+func currentStatus(r *http.Request) serverStatus {
+	status := serverStatus{
+		OMDB:          omdbStatus{Enabled: omdbAPIKey != ""},
+		MetadataFetch: metadataFetch,
+		Profiles:      profileNames(),
+		Auth:          auth != nil,
 	}
-	if mediaType != "" {
-		params.Set("type", mediaType)
+	if u := requestUser(r); u != nil {
+		status.User = u.Name
 	}
-
-	resp, err := httpClient.Get("http://www.omdbapi.com/?" + params.Encode())
-	if err != nil {
-		return nil
+	status.Profile, _, _ = qualityProfile("")
+	if omdbAPIKey != "" {
+		q := omdbClient.Quota.Status(omdbAPIKey)
+		status.OMDB.Quota = &q
 	}
-	defer resp.Body.Close()
-
-	var movie OMDBMovie
-	if err := json.NewDecoder(resp.Body).Decode(&movie); err != nil {
-		return nil
+	if omdbClient.Cache != nil {
+		s := omdbClient.Cache.Stats()
+		s.Path = "" // Server layout isn't the client's business
+		status.OMDB.Cache = &s
 	}
-
-	if movie.Response == "False" {
-		return nil
+	if downloadClient != nil {
+		status.DownloadClient = downloadClient.Name()
 	}
-
-	return &movie
+	return status
 }
 
-// looksLikeTVShow checks if a torrent name suggests it's a TV show
-func looksLikeTVShow(name string) bool {
-	lower := strings.ToLower(name)
-	// Common TV show patterns
-	patterns := []string{
-		`s\d{1,2}e\d{1,2}`,     // S01E01
-		`s\d{1,2}`,              // S01 (full season)
-		`season\s*\d`,           // Season 1
-		`episode\s*\d`,          // Episode 1
-		`\d{1,2}x\d{1,2}`,       // 1x01
-		`complete\s*series`,
-		`complete\s*season`,
-		`all\s*seasons`,
-	}
-	for _, p := range patterns {
-		if matched, _ := regexp.MatchString(p, lower); matched {
-			return true
-		}
-	}
-	return false
+func handleStatus(w http.ResponseWriter, r *http.Request) {
+	jsonResponse(w, currentStatus(r))
 }
 
 // PaginatedResponse wraps search results with pagination info
@@ -512,7 +292,7 @@ type PaginatedResponse struct {
 func handleSearch(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("q")
 	if query == "" {
-		jsonError(w, "missing query parameter 'q'", http.StatusBadRequest)
+		searchError(w, r, "missing query parameter 'q'", http.StatusBadRequest)
 		return
 	}
 
@@ -538,127 +318,136 @@ func handleSearch(w http.ResponseWriter, r *http.Request) {
 
 	switch source {
 	case "yts":
-		handleYTSSearch(w, query, page, perPage)
+		handleYTSSearch(w, r, query, page, perPage)
 	case "torrents-csv", "tcsv":
-		handleTorrentsCSVSearch(w, query, page, perPage)
+		handleTorrentsCSVSearch(w, r, query, page, perPage)
 	default:
-		jsonError(w, "invalid source, use 'yts' or 'torrents-csv'", http.StatusBadRequest)
+		searchError(w, r, "invalid source, use 'yts' or 'torrents-csv'", http.StatusBadRequest)
 	}
 }
 
-func handleYTSSearch(w http.ResponseWriter, query string, page, perPage int) {
-	params := url.Values{}
-	params.Set("query_term", query)
-	params.Set("limit", strconv.Itoa(perPage))
-	params.Set("page", strconv.Itoa(page))
-
-	resp, err := httpClient.Get(fmt.Sprintf("%s/list_movies.json?%s", ytsBaseURL, params.Encode()))
-	if err != nil {
-		jsonError(w, err.Error(), http.StatusBadGateway)
-		return
-	}
-	defer resp.Body.Close()
+// wantsEventStream reports whether the client asked for search results as
+// Server-Sent Events, as EventSource does.
+func wantsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
 
-	var result searchResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		jsonError(w, err.Error(), http.StatusInternalServerError)
+// searchError reports a failed search; event stream clients get it as an
+// "error" event, since EventSource can't read an error response.
+func searchError(w http.ResponseWriter, r *http.Request, message string, status int) {
+	if !wantsEventStream(r) {
+		jsonError(w, message, status)
 		return
 	}
-
-	movies := result.Data.Movies
-	if movies == nil {
-		movies = []Movie{}
+	sse := newEventStream(w)
+	sse.send("error", map[string]string{"error": message})
+}
+
+// writeSearch answers a search with one page of results, enriched from OMDB
+// and sorted by popularity. Plain requests get the page once enrichment is
+// done. Event stream clients get it straight away as a "results" event,
+// then a "result" event ({index, result}) for each row as its lookup
+// finishes, and finally a "done" event with the sorted page. view gives
+// each row the shape its source is listed in.
+func writeSearch[T any](w http.ResponseWriter, r *http.Request, resp PaginatedResponse, movies []core.Movie, view func(core.Movie) T) {
+	ctx := r.Context()
+	enriching := omdbAPIKey != "" && len(movies) > 0
+	views := func() []T {
+		results := make([]T, len(movies))
+		for i, m := range movies {
+			results[i] = view(m)
+		}
+		return results
 	}
+	resp.Results = views()
 
-	// If OMDB is configured, fetch vote counts and sort by popularity
-	if omdbAPIKey != "" && len(movies) > 0 {
-		movies = enrichAndSortMovies(movies)
+	if !wantsEventStream(r) {
+		if enriching {
+			lib.EnrichMovies(ctx, movies, nil)
+			core.SortByVotes(movies)
+			resp.Results = views()
+		}
+		jsonResponse(w, resp)
+		return
 	}
 
-	total := result.Data.MovieCount
-	totalPages := (total + perPage - 1) / perPage
-	if totalPages < 1 {
-		totalPages = 1
+	sse := newEventStream(w)
+	sse.send("results", resp)
+	if enriching {
+		lib.EnrichMovies(ctx, movies, func(i int, m core.Movie) {
+			sse.send("result", map[string]any{"index": i, "result": view(m)})
+		})
+		core.SortByVotes(movies)
+		resp.Results = views()
 	}
+	sse.send("done", resp)
+}
 
-	jsonResponse(w, PaginatedResponse{
-		Results:    movies,
-		Page:       page,
-		PerPage:    perPage,
-		Total:      total,
-		TotalPages: totalPages,
-	})
+// eventStream writes Server-Sent Events. send is safe for concurrent use.
+type eventStream struct {
+	mu sync.Mutex
+	w  http.ResponseWriter
+	rc *http.ResponseController
 }
 
-func handleTorrentsCSVSearch(w http.ResponseWriter, query string, page, perPage int) {
-	// Fetch a larger batch from Torrents-CSV (they use cursor pagination)
-	// We'll fetch up to 200 results and paginate on our side
-	fetchSize := 200
-	params := url.Values{}
-	params.Set("q", query)
-	params.Set("size", strconv.Itoa(fetchSize))
+func newEventStream(w http.ResponseWriter) *eventStream {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Don't let nginx hold events back
+	return &eventStream{w: w, rc: http.NewResponseController(w)}
+}
 
-	resp, err := httpClient.Get(fmt.Sprintf("%s?%s", torrentsCSVURL, params.Encode()))
+func (s *eventStream) send(event string, data any) {
+	b, err := json.Marshal(data)
 	if err != nil {
-		jsonError(w, err.Error(), http.StatusBadGateway)
-		return
-	}
-	defer resp.Body.Close()
-
-	var result TorrentsCSVResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		jsonError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, b)
+	s.rc.Flush()
+}
 
-	// Convert to SearchResult format
-	allResults := make([]SearchResult, 0, len(result.Torrents))
-	for _, t := range result.Torrents {
-		year, imdbCode := extractYearAndIMDB(t.Name)
-		allResults = append(allResults, SearchResult{
-			ID:       t.Infohash,
-			Title:    cleanTorrentName(t.Name),
-			Year:     year,
-			Source:   "torrents-csv",
-			Infohash: t.Infohash,
-			Size:     formatBytes(t.SizeBytes),
-			Seeders:  t.Seeders,
-			Leechers: t.Leechers,
-			IMDBCode: imdbCode,
-		})
-	}
-
-	// Enrich with OMDB if available (only enrich current page to avoid too many API calls)
-	total := len(allResults)
-	totalPages := (total + perPage - 1) / perPage
-	if totalPages < 1 {
-		totalPages = 1
+// searchErrorStatus maps a search error to the HTTP status the API reports:
+// 502 Bad Gateway when the provider couldn't be reached.
+func searchErrorStatus(err error) int {
+	var ue *core.UpstreamError
+	if errors.As(err, &ue) {
+		return http.StatusBadGateway
 	}
+	return http.StatusInternalServerError
+}
 
-	// Paginate
-	start := (page - 1) * perPage
-	end := start + perPage
-	if start > total {
-		start = total
-	}
-	if end > total {
-		end = total
+func handleYTSSearch(w http.ResponseWriter, r *http.Request, query string, page, perPage int) {
+	result, err := lib.Search(r.Context(), query, page, perPage, core.SourceYTS)
+	if err != nil {
+		searchError(w, r, err.Error(), searchErrorStatus(err))
+		return
 	}
 
-	pageResults := allResults[start:end]
+	// If OMDB is configured, fetch vote counts and sort by popularity
+	writeSearch(w, r, PaginatedResponse{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      result.Total,
+		TotalPages: result.TotalPages,
+	}, result.Movies, func(m core.Movie) core.Movie { return m })
+}
 
-	// Enrich only the current page with OMDB
-	if omdbAPIKey != "" && len(pageResults) > 0 {
-		pageResults = enrichTorrentsCSVResults(pageResults)
+func handleTorrentsCSVSearch(w http.ResponseWriter, r *http.Request, query string, page, perPage int) {
+	// Torrents-CSV is paged locally, so only the current page is enriched
+	result, err := lib.Search(r.Context(), query, page, perPage, core.SourceTorrentsCSV)
+	if err != nil {
+		searchError(w, r, err.Error(), searchErrorStatus(err))
+		return
 	}
 
-	jsonResponse(w, PaginatedResponse{
-		Results:    pageResults,
+	writeSearch(w, r, PaginatedResponse{
 		Page:       page,
 		PerPage:    perPage,
-		Total:      total,
-		TotalPages: totalPages,
-	})
+		Total:      result.Total,
+		TotalPages: result.TotalPages,
+	}, result.Movies, toSearchResult)
 }
 
 func handleMovieDetails(w http.ResponseWriter, r *http.Request) {
@@ -669,51 +458,66 @@ func handleMovieDetails(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	params := url.Values{}
-	params.Set("movie_id", strconv.Itoa(movieID))
-	params.Set("with_images", "true")
-	params.Set("with_cast", "true")
-
-	resp, err := httpClient.Get(fmt.Sprintf("%s/movie_details.json?%s", ytsBaseURL, params.Encode()))
+	profileName, profile, err := qualityProfile(r.URL.Query().Get("profile"))
 	if err != nil {
-		jsonError(w, err.Error(), http.StatusBadGateway)
+		jsonError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	defer resp.Body.Close()
 
-	var result detailResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		jsonError(w, err.Error(), http.StatusInternalServerError)
+	movie, err := lib.Details(r.Context(), core.Movie{ID: movieID, Source: core.SourceYTS})
+	if err != nil {
+		jsonError(w, err.Error(), searchErrorStatus(err))
 		return
 	}
 
-	movie := result.Data.Movie
-
-	// Fetch OMDB data if API key is configured
-	if omdbAPIKey != "" && movie.IMDBCode != "" {
-		if omdb, err := fetchOMDBInfo(movie.IMDBCode); err == nil && omdb != nil {
-			movie.OMDB = omdb
-		}
+	details := movieDetails{Movie: *movie}
+	if len(movie.Torrents) > 0 {
+		details.Best = selectBestTorrent(movie.Torrents, profileName, profile)
 	}
 
-	jsonResponse(w, movie)
+	jsonResponse(w, details)
 }
 
-var trackers = []string{
-	"udp://open.demonii.com:1337/announce",
-	"udp://tracker.openbittorrent.com:80/announce",
-	"udp://tracker.coppersurfer.tk:6969/announce",
-	"udp://glotorrents.pw:6969/announce",
-	"udp://tracker.opentrackr.org:1337/announce",
+// trackerList holds the configured trackers, re-ranked by periodic probes.
+var trackerList *tracker.List
+
+// magnetTrackers is how many of the healthiest trackers go into magnets.
+var magnetTrackers = 8
+
+const (
+	trackerProbeTimeout  = 15 * time.Second
+	trackerProbeInterval = 30 * time.Minute
+)
+
+// loadTrackersFromEnv builds the tracker list: TRACKERS (comma-separated)
+// replaces the built-in defaults and TRACKERS_FILE adds one URL per line.
+func loadTrackersFromEnv() *tracker.List {
+	base := tracker.DefaultTrackers
+	if s := os.Getenv("TRACKERS"); s != "" {
+		base = strings.Split(s, ",")
+	}
+	var extra []string
+	if path := os.Getenv("TRACKERS_FILE"); path != "" {
+		var err error
+		if extra, err = tracker.ReadListFile(path); err != nil {
+			log.Printf("Ignoring TRACKERS_FILE: %v", err)
+		}
+	}
+	if n, err := strconv.Atoi(os.Getenv("MAGNET_TRACKERS")); err == nil {
+		magnetTrackers = n
+	}
+	return tracker.NewList(base, extra)
 }
 
-func buildMagnet(hash, name string) string {
-	var trackerParams strings.Builder
-	for _, t := range trackers {
-		trackerParams.WriteString("&tr=")
-		trackerParams.WriteString(url.QueryEscape(t))
+// probeTrackers keeps tracker health scores fresh for the life of the
+// server.
+func probeTrackers() {
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), trackerProbeTimeout)
+		trackerList.Probe(ctx)
+		cancel()
+		time.Sleep(trackerProbeInterval)
 	}
-	return fmt.Sprintf("magnet:?xt=urn:btih:%s&dn=%s%s", hash, url.QueryEscape(name), trackerParams.String())
 }
 
 // handleOMDBLookup fetches OMDB data by IMDB ID or by title search
@@ -722,21 +526,21 @@ func handleOMDBLookup(w http.ResponseWriter, r *http.Request) {
 	title := r.URL.Query().Get("t")
 	yearStr := r.URL.Query().Get("y")
 	
-	var omdb *OMDBMovie
+	var omdb *core.OMDBMovie
 	var err error
 	
 	if imdbID != "" {
-		omdb, err = fetchOMDBInfo(imdbID)
+		omdb, err = lib.OMDBByID(r.Context(), imdbID)
 	} else if title != "" {
 		year := 0
 		if yearStr != "" {
 			year, _ = strconv.Atoi(yearStr)
 		}
-		// Extract show name if it looks like a TV show
-		if looksLikeTVShow(title) {
-			title = extractShowName(title)
+		// Search for the show rather than the episode
+		if rel := release.Parse(title); rel.IsTV() {
+			title = rel.Title
 		}
-		omdb, err = searchOMDB(title, year)
+		omdb, err = lib.SearchOMDB(r.Context(), title, year)
 	} else {
 		jsonError(w, "missing 'i' (IMDB ID) or 't' (title) parameter", http.StatusBadRequest)
 		return
@@ -762,88 +566,124 @@ func handleMagnet(w http.ResponseWriter, r *http.Request) {
 		jsonError(w, "missing hash parameter", http.StatusBadRequest)
 		return
 	}
-	magnet := buildMagnet(hash, name)
+	magnet := lib.Magnet(hash, name)
 	jsonResponse(w, map[string]string{"magnet": magnet})
 }
 
+// fetchErrorStatus maps a .torrent fetch error to the HTTP status reported:
+// 403 Forbidden for URLs the server won't fetch.
+func fetchErrorStatus(err error) int {
+	if errors.Is(err, core.ErrNotAllowed) {
+		return http.StatusForbidden
+	}
+	return http.StatusBadGateway
+}
+
+// handleDownloadToServer saves a .torrent in the user's download directory,
+// DOWNLOAD_DIR without authentication. It is fetched by
+// provider URL, which must be on one of TORRENT_HOSTS and resolve to a
+// public address, or by infohash alone from the swarm or a cache service.
 func handleDownloadToServer(w http.ResponseWriter, r *http.Request) {
 	torrentURL := r.URL.Query().Get("url")
 	title := r.URL.Query().Get("title")
 	quality := r.URL.Query().Get("quality")
+	hash := r.URL.Query().Get("hash")
 
-	if torrentURL == "" {
-		jsonError(w, "missing url parameter", http.StatusBadRequest)
+	if torrentURL == "" && hash == "" {
+		jsonError(w, "missing url or hash parameter", http.StatusBadRequest)
 		return
 	}
 
-	resp, err := httpClient.Get(torrentURL)
+	_, data, err := lib.FetchTorrent(r.Context(), core.Torrent{URL: torrentURL, Hash: hash})
 	if err != nil {
-		jsonError(w, fmt.Sprintf("download failed: %v", err), http.StatusBadGateway)
+		jsonError(w, err.Error(), fetchErrorStatus(err))
 		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		jsonError(w, fmt.Sprintf("download failed with status: %d", resp.StatusCode), http.StatusBadGateway)
-		return
-	}
-
-	safeTitle := sanitizeFilename(title)
-	filename := fmt.Sprintf("%s.%s.torrent", safeTitle, quality)
-	filepath := filepath.Join(downloadDir, filename)
-
-	out, err := os.Create(filepath)
+	dir, err := userDownloadDir(r)
 	if err != nil {
-		jsonError(w, fmt.Sprintf("failed to create file: %v", err), http.StatusInternalServerError)
+		jsonError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	defer out.Close()
-
-	_, err = io.Copy(out, resp.Body)
-	if err != nil {
-		jsonError(w, fmt.Sprintf("failed to write file: %v", err), http.StatusInternalServerError)
+	filename := core.TorrentFilename(title, quality)
+	filepath := filepath.Join(dir, filename)
+	if err := core.WriteFileAtomic(filepath, data, 0644); err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	grabs.record(r, grab{Action: "download", Title: title, Quality: quality, Hash: hash, URL: torrentURL, File: filepath})
 	jsonResponse(w, map[string]string{"filepath": filepath, "filename": filename})
 }
 
+// handleDownloadToClient sends a .torrent, fetched as for
+// handleDownloadToServer, to the browser.
 func handleDownloadToClient(w http.ResponseWriter, r *http.Request) {
 	torrentURL := r.URL.Query().Get("url")
 	title := r.URL.Query().Get("title")
 	quality := r.URL.Query().Get("quality")
+	hash := r.URL.Query().Get("hash")
 
-	if torrentURL == "" {
-		http.Error(w, "missing url parameter", http.StatusBadRequest)
+	if torrentURL == "" && hash == "" {
+		http.Error(w, "missing url or hash parameter", http.StatusBadRequest)
 		return
 	}
 
-	resp, err := httpClient.Get(torrentURL)
+	_, data, err := lib.FetchTorrent(r.Context(), core.Torrent{URL: torrentURL, Hash: hash})
 	if err != nil {
-		http.Error(w, fmt.Sprintf("download failed: %v", err), http.StatusBadGateway)
+		http.Error(w, err.Error(), fetchErrorStatus(err))
 		return
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		http.Error(w, fmt.Sprintf("download failed with status: %d", resp.StatusCode), http.StatusBadGateway)
-		return
-	}
-
-	safeTitle := sanitizeFilename(title)
-	filename := fmt.Sprintf("%s.%s.torrent", safeTitle, quality)
 
+	grabs.record(r, grab{Action: "download-file", Title: title, Quality: quality, Hash: hash, URL: torrentURL})
+	filename := core.TorrentFilename(title, quality)
 	w.Header().Set("Content-Type", "application/x-bittorrent")
 	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
-	io.Copy(w, resp.Body)
+	w.Write(data)
 }
 
-func sanitizeFilename(name string) string {
-	replacer := strings.NewReplacer(
-		"/", "-", "\\", "-", ":", "-", "*", "-",
-		"?", "-", "\"", "-", "<", "-", ">", "-", "|", "-",
-	)
-	return replacer.Replace(name)
+// handleSendToClient queues a torrent on the configured download client.
+// With url, the .torrent is fetched and uploaded; otherwise the magnet is sent.
+func handleSendToClient(w http.ResponseWriter, r *http.Request) {
+	hash := r.URL.Query().Get("hash")
+	title := r.URL.Query().Get("title")
+	torrentURL := r.URL.Query().Get("url")
+
+	if hash == "" {
+		jsonError(w, "missing hash parameter", http.StatusBadRequest)
+		return
+	}
+	if downloadClientErr != nil {
+		jsonError(w, downloadClientErr.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if downloadClient == nil {
+		jsonError(w, "no download client configured (set DOWNLOAD_CLIENT)", http.StatusServiceUnavailable)
+		return
+	}
+	if title == "" {
+		title = hash
+	}
+
+	opts := core.AddOptions{
+		Category: r.URL.Query().Get("category"),
+		SavePath: r.URL.Query().Get("save_path"),
+		Tags:     splitList(r.URL.Query().Get("tags")),
+	}
+	// The .torrent is uploaded when it's the one we asked for; otherwise the
+	// magnet is sent
+	torrent := core.Torrent{Hash: hash, URL: torrentURL}
+	id, err := lib.Send(r.Context(), downloadClient, torrent, title, opts)
+	var fallback *core.MagnetFallback
+	if errors.As(err, &fallback) {
+		log.Printf("Send %q: %v", title, err)
+	} else if err != nil {
+		jsonError(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	grabs.record(r, grab{Action: "send", Title: title, Hash: hash, URL: torrentURL, Client: downloadClient.Name(), ClientID: id})
+	jsonResponse(w, map[string]string{"client": downloadClient.Name(), "id": id, "title": title})
 }
 
 func jsonResponse(w http.ResponseWriter, data interface{}) {
@@ -857,50 +697,6 @@ func jsonError(w http.ResponseWriter, message string, status int) {
 	json.NewEncoder(w).Encode(map[string]string{"error": message})
 }
 
-// Torrent cache services that provide .torrent files from infohash
-var torrentCacheURLs = []string{
-	"https://itorrents.org/torrent/%s.torrent",
-	"http://btcache.me/torrent/%s",
-}
-
-// fetchTorrentFromCache tries to download a .torrent file from cache services
-func fetchTorrentFromCache(infohash string) ([]byte, error) {
-	upperHash := strings.ToUpper(infohash)
-	
-	for _, urlTemplate := range torrentCacheURLs {
-		torrentURL := fmt.Sprintf(urlTemplate, upperHash)
-		
-		req, err := http.NewRequest("GET", torrentURL, nil)
-		if err != nil {
-			continue
-		}
-		req.Header.Set("User-Agent", "Mozilla/5.0")
-		
-		client := &http.Client{Timeout: 10 * time.Second}
-		resp, err := client.Do(req)
-		if err != nil {
-			continue
-		}
-		defer resp.Body.Close()
-		
-		if resp.StatusCode != 200 {
-			continue
-		}
-		
-		data, err := io.ReadAll(resp.Body)
-		if err != nil {
-			continue
-		}
-		
-		// Verify it's actually a torrent file (starts with "d" for bencoded dict)
-		if len(data) > 0 && data[0] == 'd' {
-			return data, nil
-		}
-	}
-	
-	return nil, fmt.Errorf("could not fetch .torrent from any cache service")
-}
-
 func handleSaveMagnet(w http.ResponseWriter, r *http.Request) {
 	infohash := r.URL.Query().Get("infohash")
 	title := r.URL.Query().Get("title")
@@ -914,38 +710,43 @@ func handleSaveMagnet(w http.ResponseWriter, r *http.Request) {
 		title = infohash
 	}
 
-	safeTitle := sanitizeFilename(title)
+	safeTitle := core.SanitizeFilename(title)
+	dir, err := userDownloadDir(r)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 	
 	// Try to fetch actual .torrent file from cache services
-	torrentData, err := fetchTorrentFromCache(infohash)
+	torrentData, err := lib.FetchTorrentByHash(r.Context(), infohash)
 	if err == nil && len(torrentData) > 0 {
 		// Successfully got .torrent file
 		filename := fmt.Sprintf("%s.torrent", safeTitle)
-		filepath := filepath.Join(downloadDir, filename)
+		filepath := filepath.Join(dir, filename)
 		
-		if err := os.WriteFile(filepath, torrentData, 0644); err != nil {
-			jsonError(w, fmt.Sprintf("failed to save: %v", err), http.StatusInternalServerError)
+		if err := core.WriteFileAtomic(filepath, torrentData, 0644); err != nil {
+			jsonError(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 		
+		grabs.record(r, grab{Action: "save-magnet", Title: title, Hash: infohash, File: filepath})
 		jsonResponse(w, map[string]string{"filepath": filepath, "filename": filename, "type": "torrent"})
 		return
 	}
 	
 	// Fallback to saving magnet link
-	magnet := fmt.Sprintf("magnet:?xt=urn:btih:%s&dn=%s", infohash, url.QueryEscape(title))
-	for _, t := range trackers {
-		magnet += "&tr=" + url.QueryEscape(t)
-	}
+	magnet := lib.Magnet(infohash, title)
 
 	filename := fmt.Sprintf("%s.magnet", safeTitle)
-	filepath := filepath.Join(downloadDir, filename)
+	filepath := filepath.Join(dir, filename)
 
-	if err := os.WriteFile(filepath, []byte(magnet), 0644); err != nil {
-		jsonError(w, fmt.Sprintf("failed to save: %v", err), http.StatusInternalServerError)
+	if err := core.WriteFileAtomic(filepath, []byte(magnet), 0644); err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	grabs.record(r, grab{Action: "save-magnet", Title: title, Hash: infohash, File: filepath})
+
 	jsonResponse(w, map[string]string{"filepath": filepath, "filename": filename, "type": "magnet"})
 }
 
@@ -963,10 +764,11 @@ func handleDownloadTorrentToClient(w http.ResponseWriter, r *http.Request) {
 		title = infohash
 	}
 
-	safeTitle := sanitizeFilename(title)
+	safeTitle := core.SanitizeFilename(title)
+	grabs.record(r, grab{Action: "download-torrent", Title: title, Hash: infohash})
 	
 	// Try to fetch actual .torrent file from cache services
-	torrentData, err := fetchTorrentFromCache(infohash)
+	torrentData, err := lib.FetchTorrentByHash(r.Context(), infohash)
 	if err == nil && len(torrentData) > 0 {
 		// Successfully got .torrent file - send to browser
 		filename := fmt.Sprintf("%s.torrent", safeTitle)
@@ -978,10 +780,7 @@ func handleDownloadTorrentToClient(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	// Fallback to magnet file
-	magnet := fmt.Sprintf("magnet:?xt=urn:btih:%s&dn=%s", infohash, url.QueryEscape(title))
-	for _, t := range trackers {
-		magnet += "&tr=" + url.QueryEscape(t)
-	}
+	magnet := lib.Magnet(infohash, title)
 	
 	filename := fmt.Sprintf("%s.magnet", safeTitle)
 	w.Header().Set("Content-Type", "application/x-magnet")
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// The OpenAPI document for /api/v1 is generated from apiRoutes and the Go
+// types their handlers decode and encode, so it can't drift from the code.
+// Struct fields are documented with a doc tag.
+
+// openAPIDocument builds the OpenAPI 3.1 description of apiRoutes.
+func openAPIDocument() map[string]any {
+	g := &schemaGen{components: map[string]any{}}
+	errorResponse := map[string]any{
+		"description": "Error",
+		"content": map[string]any{
+			"application/json": map[string]any{"schema": g.schema(reflect.TypeFor[apiErrorBody]())},
+		},
+	}
+
+	paths := map[string]any{}
+	for _, rt := range apiRoutes() {
+		op := map[string]any{
+			"operationId": rt.id,
+			"summary":     rt.summary,
+			"responses": map[string]any{
+				strconv.Itoa(rt.status): g.response(rt),
+				"default":               errorResponse,
+			},
+		}
+		if auth != nil && !rt.public {
+			op["security"] = []any{map[string]any{"session": []string{}}, map[string]any{"token": []string{}}}
+		}
+		var params []any
+		for _, p := range rt.params {
+			params = append(params, map[string]any{
+				"name":        p.name,
+				"in":          p.in,
+				"description": p.doc,
+				"required":    p.required || p.in == "path",
+				"schema":      map[string]any{"type": p.typ},
+			})
+		}
+		if params != nil {
+			op["parameters"] = params
+		}
+		if rt.body != nil {
+			op["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": g.schema(reflect.TypeOf(rt.body))},
+				},
+			}
+		}
+
+		item, _ := paths[rt.path].(map[string]any)
+		if item == nil {
+			item = map[string]any{}
+			paths[rt.path] = item
+		}
+		item[strings.ToLower(rt.method)] = op
+	}
+
+	components := map[string]any{"schemas": g.components}
+	if auth != nil {
+		components["securitySchemes"] = map[string]any{
+			"session": map[string]any{"type": "apiKey", "in": "cookie", "name": sessionCookie},
+			"token":   map[string]any{"type": "http", "scheme": "bearer"},
+		}
+	}
+	return map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":   "c-cli-web API",
+			"version": "1",
+			"description": "Search the providers, look titles up on OMDB and grab torrents. " +
+				"Errors are returned as {\"error\": {\"code\", \"message\"}}. " +
+				"Lists are paged with an opaque cursor: pass next_cursor back as cursor until it is absent.",
+		},
+		"servers":    []any{map[string]any{"url": basePath + "/"}},
+		"paths":      paths,
+		"components": components,
+	}
+}
+
+func (g *schemaGen) response(rt apiRoute) map[string]any {
+	resp := map[string]any{"description": http.StatusText(rt.status)}
+	switch {
+	case rt.contentType != "":
+		resp["content"] = map[string]any{rt.contentType: map[string]any{
+			"schema": map[string]any{"type": "string", "format": "binary"},
+		}}
+	case rt.response != nil:
+		resp["content"] = map[string]any{"application/json": map[string]any{
+			"schema": g.schema(reflect.TypeOf(rt.response)),
+		}}
+	}
+	return resp
+}
+
+// schemaGen turns Go types into JSON Schemas, collecting named structs in
+// components.
+type schemaGen struct {
+	components map[string]any
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+
+func (g *schemaGen) schema(t reflect.Type) map[string]any {
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schema(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		name := schemaName(t)
+		if _, ok := g.components[name]; !ok {
+			g.components[name] = nil // Placeholder, for recursive types
+			g.components[name] = g.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]any{}
+}
+
+// schemaName is a type's name as shown in the document: unexported API
+// types like apiResult lose their prefix and become Result.
+func schemaName(t reflect.Type) string {
+	name := strings.TrimPrefix(t.Name(), "api")
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+func (g *schemaGen) object(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	var required []string
+	g.fields(t, props, &required)
+	obj := map[string]any{"type": "object", "properties": props}
+	if required != nil {
+		obj["required"] = required
+	}
+	return obj
+}
+
+// fields adds t's JSON fields to props, following encoding/json: embedded
+// structs without a name tag are flattened, and fields are required
+// unless omitempty.
+func (g *schemaGen) fields(t reflect.Type, props map[string]any, required *[]string) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			g.fields(f.Type, props, required)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		s := g.schema(f.Type)
+		if doc := f.Tag.Get("doc"); doc != "" {
+			described := map[string]any{"description": doc}
+			for k, v := range s {
+				described[k] = v
+			}
+			s = described
+		}
+		props[name] = s
+		if !strings.Contains(opts, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// handleOpenAPI serves the generated document.
+func handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	jsonResponse(w, openAPIDocument())
+}
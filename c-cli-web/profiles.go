@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"c-cli/core"
+	"c-cli/quality"
+)
+
+// Quality profiles come from the [profiles] tables of the TUI's
+// config.toml, or of QUALITY_PROFILES_FILE. QUALITY_PROFILE picks the one
+// used when a request doesn't name one; by default the file's profile.
+var (
+	qualityProfiles map[string]quality.Profile
+	activeProfile   string
+)
+
+func loadProfilesFromEnv() {
+	path := os.Getenv("QUALITY_PROFILES_FILE")
+	if path == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, ".config", "c-cli", "config.toml")
+		}
+	}
+	var file struct {
+		Profile  string                     `toml:"profile"`
+		Profiles map[string]quality.Profile `toml:"profiles"`
+	}
+	if path != "" {
+		if _, err := toml.DecodeFile(path, &file); err != nil && !os.IsNotExist(err) {
+			log.Printf("Ignoring quality profiles in %s: %v", path, err)
+		}
+	}
+	qualityProfiles = file.Profiles
+	activeProfile = file.Profile
+	if p := os.Getenv("QUALITY_PROFILE"); p != "" {
+		activeProfile = p
+	}
+}
+
+// qualityProfile returns the named quality profile, or the active one when
+// name is empty. Without profiles it falls back to quality.Default.
+func qualityProfile(name string) (string, quality.Profile, error) {
+	if name == "" {
+		name = activeProfile
+	}
+	if name == "" {
+		if p, ok := qualityProfiles["default"]; ok {
+			return "default", p, nil
+		}
+		return "default", quality.Default, nil
+	}
+	p, ok := qualityProfiles[name]
+	if !ok {
+		return name, quality.Profile{}, fmt.Errorf("no quality profile %q", name)
+	}
+	return name, p, nil
+}
+
+// profileNames lists the configured profiles for /api/status.
+func profileNames() []string {
+	names := make([]string, 0, len(qualityProfiles))
+	for name := range qualityProfiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// bestTorrent is the profile's pick among a movie's torrents, with the
+// reasons shown next to it in the UI. Index is -1 when none fit, and Why
+// then says what ruled each one out.
+type bestTorrent struct {
+	Index   int      `json:"index"`
+	Hash    string   `json:"hash,omitempty"`
+	Quality string   `json:"quality,omitempty"`
+	Profile string   `json:"profile"`
+	Why     []string `json:"why"`
+}
+
+func selectBestTorrent(torrents []core.Torrent, name string, p quality.Profile) *bestTorrent {
+	t, choice := core.SelectBestTorrent(torrents, p)
+	best := &bestTorrent{Index: choice.Index, Profile: name, Why: choice.Why}
+	if t != nil {
+		best.Hash, best.Quality = strings.ToLower(t.Hash), t.Quality
+	}
+	return best
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// Behind a reverse proxy, TRUSTED_PROXIES (comma-separated addresses or
+// CIDR ranges) lists the proxies whose X-Forwarded-For, X-Forwarded-Proto
+// and X-Forwarded-Host headers are believed, and BASE_PATH is the path the
+// proxy serves c-cli-web under, such as /c-cli.
+var (
+	trustedProxies []netip.Prefix
+	basePath       string
+)
+
+func loadProxyFromEnv() error {
+	for _, s := range splitList(os.Getenv("TRUSTED_PROXIES")) {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(s)
+			if addrErr != nil {
+				return fmt.Errorf("TRUSTED_PROXIES: %q is not an address or CIDR range", s)
+			}
+			p = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen())
+		}
+		trustedProxies = append(trustedProxies, p.Masked())
+	}
+	basePath = cleanBasePath(os.Getenv("BASE_PATH"))
+	return nil
+}
+
+// cleanBasePath turns "c-cli/", "/c-cli" and so on into "/c-cli", and "/"
+// into "".
+func cleanBasePath(p string) string {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return ""
+	}
+	return "/" + p
+}
+
+// remoteIP returns the address in a request's RemoteAddr, which has a port
+// unless forwarded rewrote it.
+func remoteIP(remoteAddr string) (netip.Addr, bool) {
+	if ap, err := netip.ParseAddrPort(remoteAddr); err == nil {
+		return ap.Addr().Unmap(), true
+	}
+	addr, err := netip.ParseAddr(remoteAddr)
+	return addr.Unmap(), err == nil
+}
+
+func isTrustedProxy(addr netip.Addr) bool {
+	for _, p := range trustedProxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwarded applies a trusted proxy's X-Forwarded-* headers to the request:
+// RemoteAddr becomes the client's address, URL.Scheme the scheme the client
+// used and Host the host it asked for.
+func forwarded(next http.Handler) http.Handler {
+	if len(trustedProxies) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ip, ok := remoteIP(r.RemoteAddr); !ok || !isTrustedProxy(ip) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		r = r.Clone(r.Context())
+		if client := forwardedFor(r.Header.Values("X-Forwarded-For")); client != "" {
+			r.RemoteAddr = client
+		}
+		if proto := firstValue(r.Header.Get("X-Forwarded-Proto")); proto == "http" || proto == "https" {
+			r.URL.Scheme = proto
+		}
+		if host := firstValue(r.Header.Get("X-Forwarded-Host")); host != "" {
+			r.Host = host
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// forwardedFor picks the client out of X-Forwarded-For: the last address
+// that isn't one of our proxies. Anything left of it was sent by the client
+// and can't be trusted.
+func forwardedFor(headers []string) string {
+	var hops []string
+	for _, h := range headers {
+		for _, hop := range strings.Split(h, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip, ok := remoteIP(hops[i])
+		if !ok {
+			return ""
+		}
+		if !isTrustedProxy(ip) || i == 0 {
+			return ip.String()
+		}
+	}
+	return ""
+}
+
+func firstValue(header string) string {
+	v, _, _ := strings.Cut(header, ",")
+	return strings.ToLower(strings.TrimSpace(v))
+}
+
+// isHTTPS reports whether the client reached us over HTTPS, directly or
+// through a trusted proxy.
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || r.URL.Scheme == "https"
+}
+
+// mountAt serves h under BASE_PATH, with the prefix stripped, so handlers
+// see the same paths either way.
+func mountAt(base string, h http.Handler) http.Handler {
+	if base == "" {
+		return h
+	}
+	strip := http.StripPrefix(base, h)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == base:
+			// The UI's relative API paths need the trailing slash
+			http.Redirect(w, r, base+"/", http.StatusMovedPermanently)
+		case strings.HasPrefix(r.URL.Path, base+"/"):
+			strip.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// serve runs the server on addr. TLS_CERT and TLS_KEY name a certificate
+// and key to serve HTTPS with; TLS_SELF_SIGNED=true generates one instead,
+// for LAN use where browsers can be told to trust it.
+func serve(addr string, h http.Handler) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           h,
+		ReadHeaderTimeout: 10 * time.Second,
+		TLSConfig:         &tls.Config{MinVersion: tls.VersionTLS12},
+	}
+
+	certFile, keyFile := os.Getenv("TLS_CERT"), os.Getenv("TLS_KEY")
+	selfSigned, _ := strconv.ParseBool(os.Getenv("TLS_SELF_SIGNED"))
+	switch {
+	case certFile != "" || keyFile != "":
+		if certFile == "" || keyFile == "" {
+			return errors.New("TLS_CERT and TLS_KEY must be set together")
+		}
+	case selfSigned:
+		host, _, _ := net.SplitHostPort(addr)
+		var err error
+		if certFile, keyFile, err = selfSignedCert(host); err != nil {
+			return fmt.Errorf("self-signed certificate: %w", err)
+		}
+	default:
+		return srv.ListenAndServe()
+	}
+	return srv.ListenAndServeTLS(certFile, keyFile)
+}
+
+// selfSignedCert returns the certificate and key files of a self-signed
+// certificate for host, kept in the user config directory so browsers only
+// have to accept it once. It is replaced when it's about to expire or no
+// longer covers the server's addresses.
+func selfSignedCert(host string) (certFile, keyFile string, err error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", "", err
+	}
+	dir = filepath.Join(dir, "c-cli")
+	certFile = filepath.Join(dir, "c-cli-web.crt")
+	keyFile = filepath.Join(dir, "c-cli-web.key")
+
+	names, ips := certNames(host)
+	if cert, err := readCert(certFile); err == nil && certCovers(cert, names, ips) {
+		logFingerprint(cert)
+		return certFile, keyFile, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return "", "", err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "c-cli-web"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(1, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              names,
+		IPAddresses:           ips,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return "", "", err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return "", "", err
+	}
+	cert, _ := x509.ParseCertificate(der)
+	log.Printf("Generated self-signed certificate %s", certFile)
+	logFingerprint(cert)
+	return certFile, keyFile, nil
+}
+
+// certNames lists the names and addresses the server can be reached at:
+// localhost and the machine's hostname, plus host when it's a specific
+// address, otherwise every interface address.
+func certNames(host string) ([]string, []net.IP) {
+	names := []string{"localhost"}
+	if h, err := os.Hostname(); err == nil && h != "localhost" {
+		names = append(names, strings.ToLower(h))
+	}
+	if host != "" && net.ParseIP(host) == nil && host != "localhost" {
+		names = append(names, strings.ToLower(host))
+	}
+
+	ips := []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+	if ip := net.ParseIP(host); ip != nil && !ip.IsUnspecified() {
+		if !ip.IsLoopback() {
+			ips = append(ips, ip)
+		}
+		return names, ips
+	}
+	addrs, _ := net.InterfaceAddrs()
+	for _, a := range addrs {
+		if n, ok := a.(*net.IPNet); ok && !n.IP.IsLoopback() && !n.IP.IsLinkLocalUnicast() {
+			ips = append(ips, n.IP)
+		}
+	}
+	return names, ips
+}
+
+func readCert(path string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM certificate")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// certCovers reports whether cert is valid for another month for every
+// name and address.
+func certCovers(cert *x509.Certificate, names []string, ips []net.IP) bool {
+	if time.Now().AddDate(0, 1, 0).After(cert.NotAfter) {
+		return false
+	}
+	for _, n := range names {
+		if cert.VerifyHostname(n) != nil {
+			return false
+		}
+	}
+	for _, ip := range ips {
+		if cert.VerifyHostname(ip.String()) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// logFingerprint logs the certificate's SHA-256 fingerprint, to check
+// against the one the browser shows before trusting it.
+func logFingerprint(cert *x509.Certificate) {
+	sum := sha256.Sum256(cert.Raw)
+	hex := make([]string, len(sum))
+	for i, b := range sum {
+		hex[i] = fmt.Sprintf("%02X", b)
+	}
+	log.Printf("TLS certificate SHA-256 fingerprint: %s", strings.Join(hex, ":"))
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"c-cli/core"
+	"c-cli/release"
+)
+
+// torznabBatch is how many Torrents-CSV results a feed request searches.
+const torznabBatch = 200
+
+// Torznab category IDs reported in caps and on items
+const (
+	torznabCatMovies   = 2000
+	torznabCatMoviesSD = 2030
+	torznabCatMoviesHD = 2040
+	torznabCatMovies4K = 2045
+	torznabCatTV       = 5000
+)
+
+// handleTorznab implements the Torznab API (t=caps, search, movie, tvsearch)
+// so Sonarr, Radarr and Prowlarr can add c-cli-web as an indexer.
+// If TORZNAB_API_KEY is set, requests must pass it as apikey; otherwise,
+// with authentication on, they need a session or token like the rest of
+// the API.
+func handleTorznab(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	if key := os.Getenv("TORZNAB_API_KEY"); key != "" && subtle.ConstantTimeCompare([]byte(q.Get("apikey")), []byte(key)) != 1 {
+		torznabError(w, 100, "Incorrect user credentials")
+		return
+	}
+
+	offset, _ := strconv.Atoi(q.Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	limit := 100
+	if l, err := strconv.Atoi(q.Get("limit")); err == nil && l > 0 && l < limit {
+		limit = l
+	}
+
+	var items []torznabItem
+	var err error
+	switch q.Get("t") {
+	case "caps":
+		writeXML(w, torznabCaps())
+		return
+	case "search":
+		items, err = torznabSearch(r.Context(), q.Get("q"))
+	case "movie", "movie-search":
+		items, err = torznabMovieSearch(r.Context(), q.Get("q"), normalizeIMDBID(q.Get("imdbid")))
+	case "tvsearch", "tv-search":
+		season, _ := strconv.Atoi(q.Get("season"))
+		ep, _ := strconv.Atoi(q.Get("ep"))
+		items, err = torznabTVSearch(r.Context(), q.Get("q"), normalizeIMDBID(q.Get("imdbid")), season, ep)
+	case "":
+		torznabError(w, 200, "Missing parameter (t)")
+		return
+	default:
+		torznabError(w, 202, "No such function")
+		return
+	}
+	if err != nil {
+		torznabError(w, 900, err.Error())
+		return
+	}
+
+	items = filterTorznabCategories(items, q.Get("cat"))
+
+	total := len(items)
+	if offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+
+	feed := torznabFeed{
+		Version:      "2.0",
+		XMLNSAtom:    "http://www.w3.org/2005/Atom",
+		XMLNSTorznab: "http://torznab.com/schemas/2015/feed",
+	}
+	feed.Channel.Title = "c-cli"
+	feed.Channel.Description = "c-cli Torznab feed (YTS + Torrents-CSV)"
+	feed.Channel.Response = torznabResponse{Offset: offset, Total: total}
+	feed.Channel.Items = items[offset:end]
+	writeXML(w, feed)
+}
+
+// torznabSearch is a free-text search across both sources.
+func torznabSearch(ctx context.Context, query string) ([]torznabItem, error) {
+	if query == "" {
+		// Indexer managers send an empty search to test the connection
+		query = "1080p"
+	}
+	items, err := torznabTorrentsCSV(ctx, query, "")
+	if err != nil {
+		return nil, err
+	}
+	if result, err := lib.Search(ctx, query, 1, 50, core.SourceYTS); err == nil {
+		items = append(ytsTorznabItems(result.Movies), items...)
+	}
+	return items, nil
+}
+
+// torznabMovieSearch searches YTS (which accepts IMDb IDs directly) and
+// Torrents-CSV, keeping only Torrents-CSV results that match the IMDb ID.
+func torznabMovieSearch(ctx context.Context, query, imdbID string) ([]torznabItem, error) {
+	if query == "" && imdbID != "" {
+		query = titleForIMDBID(ctx, imdbID)
+	}
+
+	ytsQuery := query
+	if imdbID != "" {
+		ytsQuery = imdbID
+	}
+	var items []torznabItem
+	if ytsQuery != "" {
+		result, err := lib.Search(ctx, ytsQuery, 1, 50, core.SourceYTS)
+		if err != nil {
+			return nil, err
+		}
+		items = ytsTorznabItems(result.Movies)
+	}
+
+	if query != "" {
+		more, err := torznabTorrentsCSV(ctx, query, imdbID)
+		if err != nil {
+			return nil, err
+		}
+		for _, it := range more {
+			if it.Category != torznabCatTV {
+				items = append(items, it)
+			}
+		}
+	}
+	return items, nil
+}
+
+// torznabTVSearch searches Torrents-CSV for "Show SxxEyy". With an IMDb ID
+// the show title comes from OMDB and results are matched on IMDb ID.
+func torznabTVSearch(ctx context.Context, query, imdbID string, season, ep int) ([]torznabItem, error) {
+	if query == "" && imdbID != "" {
+		query = titleForIMDBID(ctx, imdbID)
+	}
+	if query == "" {
+		query = "S01"
+	}
+
+	search := query
+	switch {
+	case season > 0 && ep > 0:
+		search = fmt.Sprintf("%s S%02dE%02d", query, season, ep)
+	case season > 0:
+		search = fmt.Sprintf("%s S%02d", query, season)
+	}
+
+	items, err := torznabTorrentsCSV(ctx, search, imdbID)
+	if err != nil {
+		return nil, err
+	}
+
+	tv := items[:0]
+	for _, it := range items {
+		if it.Category == torznabCatTV {
+			tv = append(tv, it)
+		}
+	}
+	return tv, nil
+}
+
+// torznabTorrentsCSV runs a Torrents-CSV search, enriches it with OMDB and,
+// when imdbID is set, drops results whose OMDB match is a different title.
+func torznabTorrentsCSV(ctx context.Context, query, imdbID string) ([]torznabItem, error) {
+	// One page holds everything a Torrents-CSV search returns
+	result, err := lib.Search(ctx, query, 1, torznabBatch, core.SourceTorrentsCSV)
+	if err != nil {
+		return nil, err
+	}
+	lib.EnrichMovies(ctx, result.Movies, nil)
+	core.SortByVotes(result.Movies)
+
+	items := make([]torznabItem, 0, len(result.Movies))
+	for _, m := range result.Movies {
+		if imdbID != "" && m.IMDBCode != "" && !strings.EqualFold(m.IMDBCode, imdbID) {
+			continue
+		}
+		items = append(items, torrentsCSVTorznabItem(m))
+	}
+	return items, nil
+}
+
+// titleForIMDBID resolves an IMDb ID to a search title via OMDB.
+func titleForIMDBID(ctx context.Context, imdbID string) string {
+	omdb, err := lib.OMDBByID(ctx, imdbID)
+	if err != nil || omdb == nil {
+		return ""
+	}
+	return omdb.Title
+}
+
+// normalizeIMDBID accepts "1375666" or "tt1375666" and returns "tt1375666".
+func normalizeIMDBID(id string) string {
+	id = strings.TrimSpace(strings.ToLower(id))
+	if id == "" {
+		return ""
+	}
+	id = strings.TrimPrefix(id, "tt")
+	if _, err := strconv.Atoi(id); err != nil {
+		return ""
+	}
+	return fmt.Sprintf("tt%07s", id)
+}
+
+func ytsTorznabItems(movies []core.Movie) []torznabItem {
+	var items []torznabItem
+	for _, m := range movies {
+		for _, t := range m.Torrents {
+			// Build a scene-style name so *arr quality parsing works
+			name := fmt.Sprintf("%s (%d) [%s] [%s] [YTS]", m.Title, m.Year, t.Quality, t.Type)
+			cat := torznabCatMoviesHD
+			switch t.Quality {
+			case "2160p":
+				cat = torznabCatMovies4K
+			case "480p":
+				cat = torznabCatMoviesSD
+			}
+			pub := time.Now()
+			if t.DateUploadedUnix > 0 {
+				pub = time.Unix(t.DateUploadedUnix, 0)
+			}
+			magnet := lib.Magnet(t.Hash, fmt.Sprintf("%s %s", m.Title, t.Quality))
+			items = append(items, newTorznabItem(name, t.Hash, t.URL, magnet, t.SizeBytes,
+				t.Seeds, t.Peers, m.IMDBCode, cat, pub))
+		}
+	}
+	return items
+}
+
+func torrentsCSVTorznabItem(m core.Movie) torznabItem {
+	t := m.Torrents[0]
+	name := t.Name
+	if name == "" {
+		name = m.Title
+	}
+	cat := torznabCatMovies
+	if release.Parse(name).IsTV() || m.OMDB.IsSeries() {
+		cat = torznabCatTV
+	}
+	pub := time.Now()
+	if t.DateUploadedUnix > 0 {
+		pub = time.Unix(t.DateUploadedUnix, 0)
+	}
+	magnet := lib.Magnet(m.Infohash, name)
+	return newTorznabItem(name, m.Infohash, magnet, magnet, t.SizeBytes,
+		m.Seeders, m.Leechers, m.IMDBCode, cat, pub)
+}
+
+func newTorznabItem(title, hash, link, magnet string, size int64, seeders, leechers int,
+	imdbCode string, category int, pub time.Time) torznabItem {
+	it := torznabItem{
+		Title:    title,
+		GUID:     strings.ToLower(hash),
+		Link:     link,
+		Size:     size,
+		PubDate:  pub.UTC().Format(time.RFC1123Z),
+		Category: category,
+	}
+	it.Enclosure.URL = link
+	it.Enclosure.Length = size
+	it.Enclosure.Type = "application/x-bittorrent"
+	if strings.HasPrefix(link, "magnet:") {
+		it.Enclosure.Type = "application/x-bittorrent;x-scheme-handler/magnet"
+	}
+
+	attr := func(name, value string) {
+		it.Attrs = append(it.Attrs, torznabAttr{Name: name, Value: value})
+	}
+	attr("category", strconv.Itoa(category))
+	attr("size", strconv.FormatInt(size, 10))
+	attr("seeders", strconv.Itoa(seeders))
+	attr("peers", strconv.Itoa(seeders+leechers))
+	attr("infohash", strings.ToLower(hash))
+	attr("magneturl", magnet)
+	attr("downloadvolumefactor", "1")
+	attr("uploadvolumefactor", "1")
+	if imdbCode != "" {
+		attr("imdbid", strings.TrimPrefix(imdbCode, "tt"))
+	}
+	return it
+}
+
+// filterTorznabCategories keeps items in any requested category, treating a
+// parent category (2000, 5000) as matching its subcategories.
+func filterTorznabCategories(items []torznabItem, cat string) []torznabItem {
+	if cat == "" {
+		return items
+	}
+	wanted := map[int]bool{}
+	for _, c := range strings.Split(cat, ",") {
+		if n, err := strconv.Atoi(strings.TrimSpace(c)); err == nil {
+			wanted[n] = true
+		}
+	}
+	if len(wanted) == 0 {
+		return items
+	}
+	filtered := items[:0]
+	for _, it := range items {
+		if wanted[it.Category] || wanted[it.Category/1000*1000] {
+			filtered = append(filtered, it)
+		}
+	}
+	return filtered
+}
+
+// Torznab XML types
+type torznabFeed struct {
+	XMLName      xml.Name `xml:"rss"`
+	Version      string   `xml:"version,attr"`
+	XMLNSAtom    string   `xml:"xmlns:atom,attr"`
+	XMLNSTorznab string   `xml:"xmlns:torznab,attr"`
+	Channel      struct {
+		Title       string          `xml:"title"`
+		Description string          `xml:"description"`
+		Response    torznabResponse `xml:"torznab:response"`
+		Items       []torznabItem   `xml:"item"`
+	} `xml:"channel"`
+}
+
+type torznabResponse struct {
+	Offset int `xml:"offset,attr"`
+	Total  int `xml:"total,attr"`
+}
+
+type torznabItem struct {
+	Title     string `xml:"title"`
+	GUID      string `xml:"guid"`
+	Link      string `xml:"link"`
+	Size      int64  `xml:"size"`
+	PubDate   string `xml:"pubDate"`
+	Category  int    `xml:"category"`
+	Enclosure struct {
+		URL    string `xml:"url,attr"`
+		Length int64  `xml:"length,attr"`
+		Type   string `xml:"type,attr"`
+	} `xml:"enclosure"`
+	Attrs []torznabAttr `xml:"torznab:attr"`
+}
+
+type torznabAttr struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type torznabCapsDoc struct {
+	XMLName xml.Name `xml:"caps"`
+	Server  struct {
+		Title string `xml:"title,attr"`
+	} `xml:"server"`
+	Limits struct {
+		Max     int `xml:"max,attr"`
+		Default int `xml:"default,attr"`
+	} `xml:"limits"`
+	Searching struct {
+		Search      torznabSearchCap `xml:"search"`
+		TVSearch    torznabSearchCap `xml:"tv-search"`
+		MovieSearch torznabSearchCap `xml:"movie-search"`
+	} `xml:"searching"`
+	Categories struct {
+		Category []torznabCategory `xml:"category"`
+	} `xml:"categories"`
+}
+
+type torznabSearchCap struct {
+	Available       string `xml:"available,attr"`
+	SupportedParams string `xml:"supportedParams,attr"`
+}
+
+type torznabCategory struct {
+	ID     int               `xml:"id,attr"`
+	Name   string            `xml:"name,attr"`
+	Subcat []torznabCategory `xml:"subcat"`
+}
+
+func torznabCaps() torznabCapsDoc {
+	var caps torznabCapsDoc
+	caps.Server.Title = "c-cli"
+	caps.Limits.Max = 100
+	caps.Limits.Default = 100
+	caps.Searching.Search = torznabSearchCap{"yes", "q"}
+	caps.Searching.TVSearch = torznabSearchCap{"yes", "q,season,ep,imdbid"}
+	caps.Searching.MovieSearch = torznabSearchCap{"yes", "q,imdbid"}
+	caps.Categories.Category = []torznabCategory{
+		{ID: torznabCatMovies, Name: "Movies", Subcat: []torznabCategory{
+			{ID: torznabCatMoviesSD, Name: "Movies/SD"},
+			{ID: torznabCatMoviesHD, Name: "Movies/HD"},
+			{ID: torznabCatMovies4K, Name: "Movies/UHD"},
+		}},
+		{ID: torznabCatTV, Name: "TV"},
+	}
+	return caps
+}
+
+func torznabError(w http.ResponseWriter, code int, description string) {
+	writeXML(w, struct {
+		XMLName     xml.Name `xml:"error"`
+		Code        int      `xml:"code,attr"`
+		Description string   `xml:"description,attr"`
+	}{Code: code, Description: description})
+}
+
+func writeXML(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"c-cli/core"
+)
+
+// downloadClient is the client configured by download_client, or nil.
+// downloadClientErr holds the reason it could not be built.
+var (
+	downloadClient    core.DownloadClient
+	downloadClientErr error
+)
+
+// newDownloadClient builds the client selected by cfg.DownloadClient.
+func newDownloadClient(cfg Config) (core.DownloadClient, error) {
+	return lib.NewDownloadClient(core.ClientConfig{
+		Kind:         cfg.DownloadClient,
+		WatchDir:     cfg.WatchDir,
+		QBittorrent:  cfg.QBittorrent,
+		Transmission: cfg.Transmission,
+		Aria2:        cfg.Aria2,
+		Deluge:       cfg.Deluge,
+		RTorrent:     cfg.RTorrent,
+	})
+}
+
+// SendToClient queues the torrent on the configured client, returning the
+// client's ID for the download.
+func SendToClient(ctx context.Context, torrent core.Torrent, name string) (string, error) {
+	if downloadClientErr != nil {
+		return "", downloadClientErr
+	}
+	if downloadClient == nil {
+		return "", fmt.Errorf("no download client configured (set download_client in config.toml)")
+	}
+	return lib.Send(ctx, downloadClient, torrent, name, core.AddOptions{})
+}
@@ -0,0 +1,624 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"c-cli/core"
+	"c-cli/metainfo"
+)
+
+// Exit codes of the non-interactive commands, for scripts and cron.
+const (
+	exitOK        = 0
+	exitError     = 1 // Anything not covered below, e.g. a download client error
+	exitUsage     = 2
+	exitNoResults = 3
+	exitProvider  = 4 // The search provider failed or couldn't be reached
+	exitQuota     = 5 // Results were printed, but OMDB's quota is used up and they may lack ratings
+)
+
+const usage = `Usage: c-cli [command] [flags]
+
+Without a command, c-cli starts the interactive browser.
+
+Commands:
+  search <query>        Search a provider
+  details <id>          Show a YTS movie by YTS ID or IMDb ID (tt...)
+  magnet <hash>         Print the magnet link for an infohash
+  grab <id|hash|query>  Send the best torrent to the download client, or save it with --file
+  cache [stats]         Show the OMDB cache location and contents
+  cache prune           Drop expired OMDB cache entries
+  cache clear           Delete the OMDB cache
+  cache path            Print the OMDB cache file path
+
+Run "c-cli <command> -h" for a command's flags.
+
+Exit codes: 0 ok, 1 error, 2 usage, 3 no results, 4 provider error,
+5 OMDB quota exhausted (results printed without fresh OMDB data).
+`
+
+// runCommand runs a non-interactive subcommand and returns the exit code.
+func runCommand(args []string) int {
+	// Ctrl+C abandons the command's requests; a second one kills it outright
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+
+	switch args[0] {
+	case "search":
+		return runSearch(ctx, args[1:])
+	case "details":
+		return runDetails(ctx, args[1:])
+	case "magnet":
+		return runMagnet(args[1:])
+	case "grab":
+		return runGrab(ctx, args[1:])
+	case "cache":
+		return runCacheCommand(args[1:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return exitOK
+	default:
+		fmt.Fprintf(os.Stderr, "c-cli: unknown command %q\n\n%s", args[0], usage)
+		return exitUsage
+	}
+}
+
+// outputFormat is the --json/--jsonl choice shared by the commands.
+type outputFormat struct {
+	json, jsonl bool
+}
+
+func (o *outputFormat) register(fs *flag.FlagSet) {
+	fs.BoolVar(&o.json, "json", false, "print a single JSON document")
+	fs.BoolVar(&o.jsonl, "jsonl", false, "print one JSON object per line")
+}
+
+func (o *outputFormat) machine() bool { return o.json || o.jsonl }
+
+// print writes doc as indented JSON with --json. With --jsonl each of lines
+// is written on its own line instead.
+func (o *outputFormat) print(doc any, lines ...any) error {
+	enc := json.NewEncoder(os.Stdout)
+	if o.jsonl {
+		for _, l := range lines {
+			if err := enc.Encode(l); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+func newFlagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: c-cli %s %s\n\nFlags:\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses args, allowing flags after positional arguments as in
+// "c-cli search matrix --json", and returns the positional arguments.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// flagError maps a parse error to an exit code; -h is not a failure.
+func flagError(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	return exitUsage
+}
+
+func fail(code int, format string, a ...any) int {
+	fmt.Fprintf(os.Stderr, "c-cli: "+format+"\n", a...)
+	return code
+}
+
+// quotaExitCode turns success into exitQuota when OMDB enrichment was cut
+// short by an exhausted quota.
+func quotaExitCode() int {
+	if config.OMDBAPIKey == "" {
+		return exitOK
+	}
+	if q := omdbClient.Quota.Status(config.OMDBAPIKey); q.Exhausted {
+		fmt.Fprintf(os.Stderr, "c-cli: OMDB quota used up until %s, ratings are from the cache only\n",
+			q.Resets.Local().Format("15:04"))
+		return exitQuota
+	}
+	return exitOK
+}
+
+func runSearch(ctx context.Context, args []string) int {
+	fs := newFlagSet("search", "[flags] <query>")
+	source := fs.String("source", string(defaultSource()), `provider: "yts", "torrents-csv" or "torznab:<name>"`)
+	page := fs.Int("page", 1, "result page")
+	limit := fs.Int("limit", config.SearchLimit, "results per page")
+	quality := fs.String("quality", "", "only results with this quality, e.g. 1080p")
+	var out outputFormat
+	out.register(fs)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return flagError(err)
+	}
+	query := strings.Join(positional, " ")
+	if query == "" || *page < 1 || *limit < 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	p, ok := lib.Provider(core.SearchSource(*source))
+	if !ok {
+		return fail(exitUsage, "unknown source %q", *source)
+	}
+
+	result, err := p.Search(ctx, query, *page, *limit)
+	if err != nil {
+		return fail(exitProvider, "%s: %v", p.Label(), err)
+	}
+	enrichAndSort(ctx, result.Movies)
+	movies := result.Movies
+	if *quality != "" {
+		movies = filterQuality(movies, *quality)
+	}
+
+	if out.machine() {
+		doc := struct {
+			Query      string            `json:"query"`
+			Source     core.SearchSource `json:"source"`
+			Page       int               `json:"page"`
+			TotalPages int               `json:"total_pages"`
+			Total      int               `json:"total"`
+			Results    []core.Movie      `json:"results"`
+		}{query, p.Source(), result.Page, result.TotalPages, result.Total, movies}
+		if doc.Results == nil {
+			doc.Results = []core.Movie{}
+		}
+		lines := make([]any, len(movies))
+		for i := range movies {
+			lines[i] = movies[i]
+		}
+		if err := out.print(doc, lines...); err != nil {
+			return fail(exitError, "%v", err)
+		}
+	} else if len(movies) > 0 {
+		printMovieTable(movies)
+		fmt.Printf("\nPage %d/%d (%d results)\n", result.Page, result.TotalPages, result.Total)
+	}
+
+	if len(movies) == 0 {
+		return fail(exitNoResults, "no results for %q", query)
+	}
+	return quotaExitCode()
+}
+
+func runDetails(ctx context.Context, args []string) int {
+	fs := newFlagSet("details", "[flags] <yts-id|imdb-id>")
+	quality := fs.String("quality", "", "only list torrents with this quality")
+	var out outputFormat
+	out.register(fs)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return flagError(err)
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	movie, code := lookupYTS(ctx, positional[0])
+	if movie == nil {
+		return code
+	}
+	if *quality != "" {
+		movie.Torrents = torrentsWithQuality(movie.Torrents, *quality)
+	}
+
+	if out.machine() {
+		if err := out.print(movie, movie); err != nil {
+			return fail(exitError, "%v", err)
+		}
+	} else {
+		printMovieDetails(movie)
+	}
+
+	if len(movie.Torrents) == 0 {
+		return fail(exitNoResults, "no torrents for %s", movie.Title)
+	}
+	return quotaExitCode()
+}
+
+func runMagnet(args []string) int {
+	fs := newFlagSet("magnet", "[flags] <infohash>")
+	name := fs.String("name", "", "display name (dn) for the magnet")
+	var out outputFormat
+	out.register(fs)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return flagError(err)
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	hash := positional[0]
+	if _, err := metainfo.ParseHash(hash); err != nil {
+		return fail(exitUsage, "%v", err)
+	}
+	if *name == "" {
+		*name = hash
+	}
+
+	magnet := lib.Magnet(hash, *name)
+	if out.machine() {
+		doc := map[string]string{"hash": hash, "name": *name, "magnet": magnet}
+		if err := out.print(doc, doc); err != nil {
+			return fail(exitError, "%v", err)
+		}
+		return exitOK
+	}
+	fmt.Println(magnet)
+	return exitOK
+}
+
+func runGrab(ctx context.Context, args []string) int {
+	fs := newFlagSet("grab", "[flags] <yts-id|imdb-id|infohash|query>")
+	source := fs.String("source", string(defaultSource()), "provider to search when given a query")
+	quality := fs.String("quality", "", "torrent quality to grab, e.g. 1080p (default: the quality profile's pick)")
+	profile := fs.String("profile", "", "quality profile to pick with (default: profile from config.toml)")
+	name := fs.String("name", "", "name for the download (default: title and quality)")
+	toFile := fs.Bool("file", false, "save a .torrent (or .magnet) to download_dir instead of sending to the client")
+	var out outputFormat
+	out.register(fs)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return flagError(err)
+	}
+	target := strings.Join(positional, " ")
+	if target == "" {
+		fs.Usage()
+		return exitUsage
+	}
+	// Scored while the torrent is looked up, in time for the magnet
+	go probeTrackers()
+
+	var torrent core.Torrent
+	title, why := target, ""
+	if _, err := metainfo.ParseHash(target); err == nil {
+		torrent = core.Torrent{Hash: target, Quality: *quality}
+	} else {
+		movie, code := resolveGrab(ctx, target, core.SearchSource(*source))
+		if movie == nil {
+			return code
+		}
+		best, reason, err := pickTorrent(movie.Torrents, *quality, *profile)
+		if err != nil {
+			return fail(exitUsage, "%v", err)
+		}
+		if best == nil && reason != "" {
+			return fail(exitNoResults, "no torrent for %s fits the %s", movie.Title, reason)
+		}
+		if best == nil {
+			return fail(exitNoResults, "no %s torrent for %s", *quality, movie.Title)
+		}
+		torrent, title, why = *best, movie.Title, reason
+	}
+	if *name == "" {
+		*name = strings.TrimSpace(title + " " + torrent.Quality)
+	}
+
+	result := map[string]string{"title": title, "quality": torrent.Quality, "hash": torrent.Hash}
+	if why != "" {
+		result["why"] = why
+	}
+	if *toFile || (downloadClient == nil && downloadClientErr == nil) {
+		path, err := saveGrab(ctx, torrent, title)
+		if err != nil {
+			return fail(exitError, "%v", err)
+		}
+		result["path"] = path
+	} else {
+		id, err := SendToClient(ctx, torrent, *name)
+		var fallback *core.MagnetFallback
+		if errors.As(err, &fallback) {
+			fmt.Fprintf(os.Stderr, "c-cli: %v\n", err)
+		} else if err != nil {
+			return fail(exitError, "%v", err)
+		}
+		result["client"] = downloadClient.Name()
+		result["id"] = id
+	}
+
+	if out.machine() {
+		if err := out.print(result, result); err != nil {
+			return fail(exitError, "%v", err)
+		}
+	} else if path, ok := result["path"]; ok {
+		printWhy(why)
+		fmt.Printf("Saved %s\n", path)
+	} else {
+		printWhy(why)
+		fmt.Printf("Sent %s to %s\n", *name, result["client"])
+	}
+	return exitOK
+}
+
+var (
+	ytsIDPattern  = regexp.MustCompile(`^\d+$`)
+	imdbIDPattern = regexp.MustCompile(`^(?i)tt\d{7,}$`)
+)
+
+// lookupYTS fetches a YTS movie by YTS ID or IMDb ID. On failure it returns
+// nil and the exit code, having reported the error.
+func lookupYTS(ctx context.Context, id string) (*core.Movie, int) {
+	switch {
+	case ytsIDPattern.MatchString(id):
+		n, _ := strconv.Atoi(id)
+		movie, err := lib.Details(ctx, core.Movie{ID: n, Source: core.SourceYTS})
+		if err != nil {
+			return nil, fail(exitProvider, "YTS: %v", err)
+		}
+		if movie.ID == 0 {
+			return nil, fail(exitNoResults, "no YTS movie with ID %s", id)
+		}
+		return movie, exitOK
+	case imdbIDPattern.MatchString(id):
+		// YTS search matches IMDb IDs exactly
+		result, err := lib.Search(ctx, id, 1, 1, core.SourceYTS)
+		if err != nil {
+			return nil, fail(exitProvider, "YTS: %v", err)
+		}
+		if len(result.Movies) == 0 {
+			return nil, fail(exitNoResults, "no YTS movie for %s", id)
+		}
+		movie, err := lib.Details(ctx, result.Movies[0])
+		if err != nil {
+			return nil, fail(exitProvider, "YTS: %v", err)
+		}
+		return movie, exitOK
+	default:
+		return nil, fail(exitUsage, "%q is neither a YTS ID nor an IMDb ID", id)
+	}
+}
+
+// resolveGrab finds the movie to grab from: a YTS or IMDb ID, or else the
+// top search result for a query.
+func resolveGrab(ctx context.Context, target string, source core.SearchSource) (*core.Movie, int) {
+	if ytsIDPattern.MatchString(target) || imdbIDPattern.MatchString(target) {
+		return lookupYTS(ctx, target)
+	}
+	p, ok := lib.Provider(source)
+	if !ok {
+		return nil, fail(exitUsage, "unknown source %q", source)
+	}
+	result, err := p.Search(ctx, target, 1, config.SearchLimit)
+	if err != nil {
+		return nil, fail(exitProvider, "%s: %v", p.Label(), err)
+	}
+	enrichAndSort(ctx, result.Movies)
+	if len(result.Movies) == 0 {
+		return nil, fail(exitNoResults, "no results for %q", target)
+	}
+	movie, err := p.Details(ctx, result.Movies[0])
+	if err != nil {
+		return nil, fail(exitProvider, "%s: %v", p.Label(), err)
+	}
+	return movie, exitOK
+}
+
+// pickTorrent returns the best-seeded torrent of the wanted quality or, when
+// quality is empty, the one the quality profile prefers along with why.
+func pickTorrent(torrents []core.Torrent, quality, profile string) (*core.Torrent, string, error) {
+	if quality == "" {
+		name, p, err := qualityProfile(profile)
+		if err != nil {
+			return nil, "", err
+		}
+		best, choice := core.SelectBestTorrent(torrents, p)
+		return best, fmt.Sprintf("%s profile: %s", name, choice), nil
+	}
+	var best *core.Torrent
+	for i, t := range torrents {
+		if strings.EqualFold(t.Quality, quality) && (best == nil || t.Seeds > best.Seeds) {
+			best = &torrents[i]
+		}
+	}
+	return best, "", nil
+}
+
+// printWhy tells the user why grab picked the torrent it did.
+func printWhy(why string) {
+	if why != "" {
+		fmt.Printf("Picked by the %s\n", why)
+	}
+}
+
+// saveGrab writes the .torrent to download_dir, or a .magnet file when no
+// .torrent can be fetched for an infohash-only result.
+func saveGrab(ctx context.Context, torrent core.Torrent, title string) (string, error) {
+	path, err := DownloadTorrentFile(ctx, torrent, title)
+	if err == nil || torrent.URL != "" {
+		return path, err
+	}
+	path = filepath.Join(config.DownloadDir, core.SanitizeFilename(title)+".magnet")
+	if err := core.WriteFileAtomic(path, []byte(lib.Magnet(torrent.Hash, title)), 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func torrentsWithQuality(torrents []core.Torrent, quality string) []core.Torrent {
+	return slices.DeleteFunc(slices.Clone(torrents), func(t core.Torrent) bool {
+		return !strings.EqualFold(t.Quality, quality)
+	})
+}
+
+// filterQuality keeps the movies offering quality, with only those torrents.
+func filterQuality(movies []core.Movie, quality string) []core.Movie {
+	var kept []core.Movie
+	for _, m := range movies {
+		if m.Torrents = torrentsWithQuality(m.Torrents, quality); len(m.Torrents) > 0 {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+func printMovieTable(movies []core.Movie) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTITLE\tYEAR\tRATING\tSEEDS\tQUALITY\tSIZE")
+	for _, m := range movies {
+		id := m.Infohash
+		if m.ID != 0 {
+			id = strconv.Itoa(m.ID)
+		}
+		seeds := m.Seeders
+		var qualities []string
+		for _, t := range m.Torrents {
+			seeds = max(seeds, t.Seeds)
+			if !slices.Contains(qualities, t.Quality) {
+				qualities = append(qualities, t.Quality)
+			}
+		}
+		size := m.Size
+		if size == "" && len(m.Torrents) == 1 {
+			size = m.Torrents[0].Size
+		}
+		year := "-"
+		if m.Year > 0 {
+			year = strconv.Itoa(m.Year)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			id, m.Title, year, movieRating(m), seeds, strings.Join(qualities, ","), size)
+	}
+	w.Flush()
+}
+
+func printMovieDetails(m *core.Movie) {
+	fmt.Printf("%s (%d)\n", m.Title, m.Year)
+	if m.IMDBCode != "" {
+		fmt.Printf("IMDb:    https://www.imdb.com/title/%s/\n", m.IMDBCode)
+	}
+	fmt.Printf("Rating:  %s\n", movieRating(*m))
+	if m.Runtime > 0 {
+		fmt.Printf("Runtime: %d min\n", m.Runtime)
+	}
+	if len(m.Genres) > 0 {
+		fmt.Printf("Genres:  %s\n", strings.Join(m.Genres, ", "))
+	}
+	if m.OMDB != nil {
+		if m.OMDB.Director != "" && m.OMDB.Director != "N/A" {
+			fmt.Printf("Director: %s\n", m.OMDB.Director)
+		}
+		if m.OMDB.Actors != "" && m.OMDB.Actors != "N/A" {
+			fmt.Printf("Cast:    %s\n", m.OMDB.Actors)
+		}
+	}
+	plot := m.Summary
+	if m.OMDB != nil && m.OMDB.Plot != "" && m.OMDB.Plot != "N/A" {
+		plot = m.OMDB.Plot
+	}
+	if plot != "" {
+		fmt.Printf("\n%s\n", plot)
+	}
+	if len(m.Torrents) == 0 {
+		return
+	}
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "QUALITY\tTYPE\tSIZE\tSEEDS\tPEERS\tHASH")
+	for _, t := range m.Torrents {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%s\n", t.Quality, t.Type, t.Size, t.Seeds, t.Peers, t.Hash)
+	}
+	w.Flush()
+}
+
+// movieRating prefers the IMDb rating from OMDB over the provider's own.
+func movieRating(m core.Movie) string {
+	if m.OMDB != nil && m.OMDB.IMDBRating != "" && m.OMDB.IMDBRating != "N/A" {
+		return m.OMDB.IMDBRating
+	}
+	if m.Rating > 0 {
+		return fmt.Sprintf("%.1f", m.Rating)
+	}
+	return "-"
+}
+
+func runCacheCommand(args []string) int {
+	cache := omdbClient.Cache
+	if cache == nil {
+		if config.OMDBCache.Disabled {
+			return fail(exitError, "the OMDB cache is disabled in config")
+		}
+		if _, err := openOMDBCache(config.OMDBCache); err != nil {
+			return fail(exitError, "%v", err)
+		}
+		return fail(exitError, "the OMDB cache is unavailable")
+	}
+
+	action := "stats"
+	if len(args) > 0 {
+		action = args[0]
+	}
+	switch action {
+	case "stats":
+		s := cache.Stats()
+		fmt.Printf("Path:      %s\n", s.Path)
+		fmt.Printf("Size:      %s\n", core.FormatBytes(s.Bytes))
+		fmt.Printf("Entries:   %d (%d found, %d not found)\n", s.Entries, s.Entries-s.Negative, s.Negative)
+		fmt.Printf("Expired:   %d\n", s.Expired)
+		if config.OMDBAPIKey != "" {
+			q := omdbClient.Quota.Status(config.OMDBAPIKey)
+			fmt.Printf("Quota:     %d of %d requests used today", q.Used, q.Limit)
+			if q.Exhausted {
+				fmt.Print(" (exhausted, cache only)")
+			}
+			fmt.Println()
+		}
+	case "prune":
+		n, err := cache.Prune()
+		if err != nil {
+			return fail(exitError, "%v", err)
+		}
+		fmt.Printf("Pruned %d expired entries\n", n)
+	case "clear":
+		if err := cache.Clear(); err != nil {
+			return fail(exitError, "%v", err)
+		}
+		fmt.Println("OMDB cache cleared")
+	case "path":
+		fmt.Println(cache.Path())
+	default:
+		fmt.Fprintf(os.Stderr, "c-cli: unknown cache action %q\n\n%s", action, usage)
+		return exitUsage
+	}
+	return exitOK
+}
@@ -1,29 +1,97 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/BurntSushi/toml"
+
+	"c-cli/core"
+	"c-cli/quality"
 )
 
 type Config struct {
 	SearchLimit  int    `toml:"search_limit"`
 	DownloadDir  string `toml:"download_dir"`
 	OMDBAPIKey   string `toml:"omdb_api_key"`
-	SearchSource string `toml:"search_source"` // "yts" or "torrents-csv"
+	SearchSource string `toml:"search_source"` // "yts", "torrents-csv" or "torznab:<name>"
+
+	OMDBCache      OMDBCacheConfig `toml:"omdb_cache"`
+	OMDBDailyLimit int             `toml:"omdb_daily_limit"` // Requests per day; 1000 for free keys
+	SortByVotes    bool            `toml:"sort_by_votes"`    // Re-sort results by IMDb votes once enriched
+
+	// Request limits by service: "omdb" or a search source such as "yts"
+	Limits map[string]LimitsConfig `toml:"limits"`
+
+	// Quality profiles by name; profile is the one `a` and grab use
+	Profile  string                     `toml:"profile"`
+	Profiles map[string]quality.Profile `toml:"profiles"`
+
+	// Fetch .torrent metadata for infohash-only results from peers (trackers
+	// and DHT) before falling back to cache services
+	MetadataFetch bool `toml:"metadata_fetch"`
+
+	// Trackers replace the built-in list; trackers_file adds one URL per
+	// line. Only the magnet_trackers healthiest are put in magnets.
+	Trackers       []string `toml:"trackers"`
+	TrackersFile   string   `toml:"trackers_file"`
+	MagnetTrackers int      `toml:"magnet_trackers"`
+
+	Torznab []core.TorznabConfig `toml:"torznab"`
+
+	// Download client integration: "qbittorrent", "transmission", "aria2",
+	// "deluge", "rtorrent" or "watch"
+	DownloadClient string                  `toml:"download_client"`
+	WatchDir       string                  `toml:"watch_dir"`
+	QBittorrent    core.QBittorrentConfig  `toml:"qbittorrent"`
+	Transmission   core.TransmissionConfig `toml:"transmission"`
+	Aria2          core.Aria2Config        `toml:"aria2"`
+	Deluge         core.DelugeConfig       `toml:"deluge"`
+	RTorrent       core.RTorrentConfig     `toml:"rtorrent"`
+}
+
+// OMDBCacheConfig controls the on-disk cache of OMDB responses, shared
+// with c-cli-web.
+type OMDBCacheConfig struct {
+	Disabled    bool          `toml:"disabled"`
+	Path        string        `toml:"path"`         // Defaults to the user cache dir
+	TTL         time.Duration `toml:"ttl"`          // e.g. "168h"
+	NegativeTTL time.Duration `toml:"negative_ttl"` // For "not found" answers
 }
 
 var config Config
 
+// qualityProfile returns the named quality profile, or the configured one
+// when name is empty. Without profiles it falls back to quality.Default.
+func qualityProfile(name string) (string, quality.Profile, error) {
+	if name == "" {
+		name = config.Profile
+	}
+	if name == "" {
+		if p, ok := config.Profiles["default"]; ok {
+			return "default", p, nil
+		}
+		return "default", quality.Default, nil
+	}
+	p, ok := config.Profiles[name]
+	if !ok {
+		return name, quality.Profile{}, fmt.Errorf("no quality profile %q in config.toml", name)
+	}
+	return name, p, nil
+}
+
 func LoadConfig() Config {
 	// Default to current working directory
 	pwd, _ := os.Getwd()
 
 	cfg := Config{
-		SearchLimit: 50,
-		DownloadDir: pwd,
-		OMDBAPIKey:  os.Getenv("OMDB_API_KEY"),
+		SearchLimit:    50,
+		DownloadDir:    pwd,
+		OMDBAPIKey:     os.Getenv("OMDB_API_KEY"),
+		MagnetTrackers: 8,
+		SortByVotes:    true,
 	}
 
 	home, err := os.UserHomeDir()
@@ -43,6 +111,18 @@ func LoadConfig() Config {
 		cfg.DownloadDir = filepath.Join(home, cfg.DownloadDir[1:])
 	}
 
+	if len(cfg.WatchDir) > 0 && cfg.WatchDir[0] == '~' {
+		cfg.WatchDir = filepath.Join(home, cfg.WatchDir[1:])
+	}
+
+	if len(cfg.OMDBCache.Path) > 0 && cfg.OMDBCache.Path[0] == '~' {
+		cfg.OMDBCache.Path = filepath.Join(home, cfg.OMDBCache.Path[1:])
+	}
+
+	if len(cfg.TrackersFile) > 0 && cfg.TrackersFile[0] == '~' {
+		cfg.TrackersFile = filepath.Join(home, cfg.TrackersFile[1:])
+	}
+
 	// If still empty, use pwd
 	if cfg.DownloadDir == "" {
 		cfg.DownloadDir = pwd
@@ -53,4 +133,13 @@ func LoadConfig() Config {
 
 func init() {
 	config = LoadConfig()
+	omdbClient = newOMDBClient(config)
+
+	// Probed by the TUI and grab; until then magnets use the configured order
+	trackerList = loadTrackers(config)
+
+	lib = newLibrary(config)
+
+	// A misconfigured client is reported when the user tries to send
+	downloadClient, downloadClientErr = newDownloadClient(config)
 }
@@ -0,0 +1,81 @@
+package core
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// aria2d is a stand-in aria2c that requires the "token:" secret as the first
+// parameter, as --rpc-secret does, and records the rest.
+type aria2d struct {
+	method string
+	params []interface{}
+}
+
+func (d *aria2d) start(t *testing.T, secret string) DownloadClient {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     string        `json:"id"`
+			Method string        `json:"method"`
+			Params []interface{} `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		if len(req.Params) == 0 || req.Params[0] != "token:s3cret" {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"id": req.ID, "jsonrpc": "2.0",
+				"error": map[string]interface{}{"code": 1, "message": "Unauthorized"},
+			})
+			return
+		}
+		d.method, d.params = req.Method, req.Params[1:]
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": req.ID, "jsonrpc": "2.0", "result": "2089b05ecca3d829"})
+	}))
+	t.Cleanup(srv.Close)
+
+	dl, err := newAria2Client(Aria2Config{URL: srv.URL + "/jsonrpc", Secret: secret, Dir: "/downloads"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dl
+}
+
+func TestAria2Add(t *testing.T) {
+	torrent := []byte("d4:infode")
+	options := map[string]interface{}{"dir": "/downloads"}
+	tests := []struct {
+		g      Grab
+		method string
+		params []interface{}
+	}{
+		{Grab{Magnet: "magnet:?xt=urn:btih:abc"}, "aria2.addUri",
+			[]interface{}{[]interface{}{"magnet:?xt=urn:btih:abc"}, options}},
+		{Grab{Magnet: "magnet:?xt=urn:btih:abc", Torrent: torrent}, "aria2.addTorrent",
+			[]interface{}{base64.StdEncoding.EncodeToString(torrent), []interface{}{}, options}},
+	}
+	for _, tt := range tests {
+		d := &aria2d{}
+		gid, err := d.start(t, "s3cret").Add(context.Background(), tt.g, AddOptions{})
+		if err != nil || gid != "2089b05ecca3d829" {
+			t.Errorf("%s: Add = %q, %v", tt.method, gid, err)
+		}
+		if d.method != tt.method || !reflect.DeepEqual(d.params, tt.params) {
+			t.Errorf("called %s%v, want %s%v", d.method, d.params, tt.method, tt.params)
+		}
+	}
+}
+
+func TestAria2WrongSecret(t *testing.T) {
+	d := &aria2d{}
+	_, err := d.start(t, "guess").Add(context.Background(), Grab{Magnet: "magnet:?xt=urn:btih:abc"}, AddOptions{})
+	if err == nil || err.Error() != "Unauthorized" {
+		t.Errorf("err = %v, want aria2's Unauthorized", err)
+	}
+}
@@ -0,0 +1,112 @@
+// Package core is what the c-cli TUI and c-cli-web are built on: searching
+// the providers, OMDB metadata, magnets and .torrent files, and grabs sent
+// to download clients. Each binary reads its own configuration and hands the
+// result to New.
+package core
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"c-cli/omdb"
+	"c-cli/throttle"
+	"c-cli/tracker"
+)
+
+// Options configure a Client. The zero Options search YTS and Torrents-CSV
+// without OMDB data, using the built-in trackers.
+type Options struct {
+	// HTTP returns the client for requests to a service: "yts",
+	// "torrents-csv" or "torznab:<name>". Callers use it to apply their
+	// request limits. Nil means a plain client for everything.
+	HTTP func(service string) *http.Client
+
+	OMDB   *omdb.Client   // Nil, or without an API key, disables OMDB data
+	Enrich *throttle.Pool // Runs OMDB lookups for all searches; one worker when nil
+
+	Trackers       *tracker.List // Ranked by the caller's probes; the built-in list when nil
+	MagnetTrackers int           // Healthiest trackers put in magnets; all when 0
+
+	// Fetch .torrent metadata for infohash-only results from peers (trackers
+	// and DHT) before falling back to cache services
+	MetadataFetch bool
+
+	Torznab []TorznabConfig // Indexers registered after YTS and Torrents-CSV
+
+	// TorrentHosts, when set, are the only hosts (and their subdomains)
+	// .torrent files are downloaded from by URL, e.g. ProviderHosts.
+	// PublicOnly refuses downloads from, and swarm peers at, loopback,
+	// private and link-local addresses. Servers that fetch URLs on behalf
+	// of others want both.
+	TorrentHosts []string
+	PublicOnly   bool
+}
+
+// Client searches, looks up metadata and fetches torrents with one set of
+// Options. It is safe for concurrent use once its providers are registered.
+type Client struct {
+	opts      Options
+	providers []Provider
+}
+
+// New returns a Client with the YTS and Torrents-CSV providers registered,
+// followed by one provider per configured Torznab indexer.
+func New(opts Options) *Client {
+	if opts.Enrich == nil {
+		opts.Enrich = throttle.NewPool(1)
+	}
+	if opts.Trackers == nil {
+		opts.Trackers = tracker.NewList(tracker.DefaultTrackers)
+	}
+	c := &Client{opts: opts}
+	c.Register(ytsProvider{c})
+	c.Register(torrentsCSVProvider{c})
+	for _, cfg := range opts.Torznab {
+		if cfg.URL == "" {
+			continue
+		}
+		if cfg.Name == "" {
+			cfg.Name = "torznab"
+		}
+		c.Register(newTorznabProvider(c, cfg))
+	}
+	return c
+}
+
+// httpClient is used for .torrent downloads and by the download clients.
+var httpClient = &http.Client{
+	Timeout: 15 * time.Second,
+}
+
+// client returns the HTTP client for requests to service.
+func (c *Client) client(service string) *http.Client {
+	if c.opts.HTTP == nil {
+		return httpClient
+	}
+	return c.opts.HTTP(service)
+}
+
+// httpGet issues a GET with ctx on the client for source. Failures to reach
+// the provider come back as an *UpstreamError.
+func (c *Client) httpGet(ctx context.Context, source SearchSource, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client(string(source)).Do(req)
+	if err != nil {
+		return nil, &UpstreamError{Source: source, Err: err}
+	}
+	return resp, nil
+}
+
+// UpstreamError is a failure to reach a provider, as opposed to a bad
+// request or an answer that couldn't be read.
+type UpstreamError struct {
+	Source SearchSource
+	Err    error
+}
+
+func (e *UpstreamError) Error() string { return e.Err.Error() }
+func (e *UpstreamError) Unwrap() error { return e.Err }
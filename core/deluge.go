@@ -0,0 +1,201 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"sync"
+)
+
+// DelugeConfig is the [deluge] section of config.toml, or the
+// DELUGE_* variables for c-cli-web.
+type DelugeConfig struct {
+	URL         string `toml:"url"` // Web UI address, e.g. http://localhost:8112
+	Password    string `toml:"password"`
+	DownloadDir string `toml:"download_dir"`
+	Label       string `toml:"label"` // Requires the Label plugin
+}
+
+// delugeClient speaks the Deluge Web UI JSON-RPC protocol on /json.
+type delugeClient struct {
+	rpcURL   string
+	password string
+	defaults AddOptions
+	client   *http.Client
+
+	mu       sync.Mutex
+	loggedIn bool
+	nextID   int
+}
+
+func newDelugeClient(cfg DelugeConfig) (DownloadClient, error) {
+	baseURL := strings.TrimRight(cfg.URL, "/")
+	if baseURL == "" {
+		baseURL = "http://localhost:8112"
+	}
+	jar, _ := cookiejar.New(nil)
+	return &delugeClient{
+		rpcURL:   strings.TrimSuffix(baseURL, "/json") + "/json",
+		password: cfg.Password,
+		defaults: AddOptions{Category: cfg.Label, SavePath: cfg.DownloadDir},
+		client:   &http.Client{Timeout: httpClient.Timeout, Jar: jar},
+	}, nil
+}
+
+func (c *delugeClient) Name() string { return "Deluge" }
+
+type delugeResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Message string `json:"message"`
+		Code    int    `json:"code"`
+	} `json:"error"`
+}
+
+// delugeErrNotAuthenticated is the error code Deluge returns for expired sessions.
+const delugeErrNotAuthenticated = 1
+
+type delugeError struct {
+	code    int
+	message string
+}
+
+func (e *delugeError) Error() string { return e.message }
+
+func (c *delugeClient) call(ctx context.Context, method string, out interface{}, params ...interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+	c.nextID++
+	body, err := json.Marshal(map[string]interface{}{
+		"method": method,
+		"params": params,
+		"id":     c.nextID,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.rpcURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("rpc failed with status: %d", resp.StatusCode)
+	}
+
+	var rpcResp delugeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("invalid rpc response: %w", err)
+	}
+	if rpcResp.Error != nil {
+		return &delugeError{code: rpcResp.Error.Code, message: rpcResp.Error.Message}
+	}
+	if out != nil {
+		return json.Unmarshal(rpcResp.Result, out)
+	}
+	return nil
+}
+
+// login authenticates the web session and makes sure the web UI is
+// connected to a daemon, connecting to the first known host if not.
+func (c *delugeClient) login(ctx context.Context) error {
+	var ok bool
+	if err := c.call(ctx, "auth.login", &ok, c.password); err != nil {
+		return fmt.Errorf("login failed: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("login failed: check password")
+	}
+	c.loggedIn = true
+
+	var connected bool
+	if err := c.call(ctx, "web.connected", &connected); err != nil {
+		return err
+	}
+	if connected {
+		return nil
+	}
+
+	var hosts [][]interface{}
+	if err := c.call(ctx, "web.get_hosts", &hosts); err != nil {
+		return err
+	}
+	if len(hosts) == 0 || len(hosts[0]) == 0 {
+		return fmt.Errorf("web UI is not connected to a Deluge daemon")
+	}
+	hostID, _ := hosts[0][0].(string)
+	return c.call(ctx, "web.connect", nil, hostID)
+}
+
+func (c *delugeClient) Add(ctx context.Context, g Grab, opts AddOptions) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	opts = mergeOptions(opts, c.defaults)
+
+	if !c.loggedIn {
+		if err := c.login(ctx); err != nil {
+			return "", err
+		}
+	}
+
+	id, err := c.add(ctx, g, opts)
+	var de *delugeError
+	if err != nil && errors.As(err, &de) && de.code == delugeErrNotAuthenticated {
+		// Session expired; log in again and retry once
+		if err := c.login(ctx); err != nil {
+			return "", err
+		}
+		id, err = c.add(ctx, g, opts)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// Labels are best effort: the Label plugin may not be enabled
+	if opts.Category != "" && id != "" {
+		label := strings.ToLower(opts.Category)
+		c.call(ctx, "label.add", nil, label)
+		c.call(ctx, "label.set_torrent", nil, id, label)
+	}
+	return id, nil
+}
+
+func (c *delugeClient) add(ctx context.Context, g Grab, opts AddOptions) (string, error) {
+	options := map[string]interface{}{}
+	if opts.SavePath != "" {
+		options["download_location"] = opts.SavePath
+	}
+
+	var id *string
+	var err error
+	if len(g.Torrent) > 0 {
+		err = c.call(ctx, "core.add_torrent_file", &id, SanitizeFilename(g.Name)+".torrent",
+			base64.StdEncoding.EncodeToString(g.Torrent), options)
+	} else {
+		err = c.call(ctx, "core.add_torrent_magnet", &id, g.Magnet, options)
+	}
+	if err != nil {
+		return "", err
+	}
+	// Deluge returns null when the torrent is already in the session
+	if id == nil {
+		return "", fmt.Errorf("torrent already added")
+	}
+	return *id, nil
+}
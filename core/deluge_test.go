@@ -0,0 +1,115 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// delugeweb is a stand-in Deluge Web UI. auth.login hands out a session
+// cookie; other calls answer error code 1 unless the current one is sent.
+type delugeweb struct {
+	logins    int
+	session   string
+	duplicate bool     // core.add_torrent_* returns null
+	calls     []string // Authenticated methods, in order
+}
+
+func (d *delugeweb) start(t *testing.T, password string) DownloadClient {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     int           `json:"id"`
+			Method string        `json:"method"`
+			Params []interface{} `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		reply := func(result interface{}, rpcErr interface{}) {
+			json.NewEncoder(w).Encode(map[string]interface{}{"id": req.ID, "result": result, "error": rpcErr})
+		}
+
+		if req.Method == "auth.login" {
+			ok := len(req.Params) == 1 && req.Params[0] == "deluge"
+			if ok {
+				d.logins++
+				d.session = "s" + strconv.Itoa(d.logins)
+				http.SetCookie(w, &http.Cookie{Name: "_session_id", Value: d.session, Path: "/"})
+			}
+			reply(ok, nil)
+			return
+		}
+		if c, err := r.Cookie("_session_id"); err != nil || c.Value != d.session {
+			reply(nil, map[string]interface{}{"message": "Not authenticated", "code": delugeErrNotAuthenticated})
+			return
+		}
+		d.calls = append(d.calls, req.Method)
+		switch req.Method {
+		case "web.connected":
+			reply(true, nil)
+		case "core.add_torrent_magnet", "core.add_torrent_file":
+			if d.duplicate {
+				reply(nil, nil)
+				return
+			}
+			reply("abc", nil)
+		default:
+			reply(nil, nil)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	dl, err := newDelugeClient(DelugeConfig{URL: srv.URL, Password: password, Label: "Movies"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dl
+}
+
+func TestDelugeAdd(t *testing.T) {
+	d := &delugeweb{}
+	dl := d.start(t, "deluge")
+	g := Grab{Magnet: "magnet:?xt=urn:btih:abc"}
+
+	id, err := dl.Add(context.Background(), g, AddOptions{})
+	if err != nil || id != "abc" {
+		t.Fatalf("Add = %q, %v; want abc", id, err)
+	}
+	want := "web.connected core.add_torrent_magnet label.add label.set_torrent"
+	if got := strings.Join(d.calls, " "); got != want {
+		t.Errorf("calls = %s, want %s", got, want)
+	}
+
+	// Error code 1 means the session expired: log in again and retry once
+	d.session, d.calls = "expired", nil
+	if _, err := dl.Add(context.Background(), g, AddOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if d.logins != 2 || !strings.Contains(strings.Join(d.calls, " "), "core.add_torrent_magnet") {
+		t.Errorf("%d logins, calls %v after expiry; want a second login and the add", d.logins, d.calls)
+	}
+}
+
+func TestDelugeDuplicate(t *testing.T) {
+	d := &delugeweb{duplicate: true}
+	_, err := d.start(t, "deluge").Add(context.Background(), Grab{Torrent: []byte("d4:infode")}, AddOptions{})
+	if err == nil || !strings.Contains(err.Error(), "already added") {
+		t.Errorf("err = %v, want already added", err)
+	}
+	if strings.Contains(strings.Join(d.calls, " "), "label.") {
+		t.Errorf("calls = %v, want no label for a duplicate", d.calls)
+	}
+}
+
+func TestDelugeWrongPassword(t *testing.T) {
+	d := &delugeweb{}
+	_, err := d.start(t, "guess").Add(context.Background(), Grab{Magnet: "magnet:?xt=urn:btih:abc"}, AddOptions{})
+	if err == nil || !strings.Contains(err.Error(), "check password") {
+		t.Errorf("err = %v, want a login failure", err)
+	}
+}
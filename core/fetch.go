@@ -0,0 +1,170 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// maxTorrentSize caps .torrent downloads. Metainfo for even very large
+// torrents is a few megabytes.
+const maxTorrentSize = 16 << 20
+
+// ProviderHosts are the hosts the built-in providers serve .torrent files
+// from, including YTS's mirrors.
+var ProviderHosts = []string{"yts.bz", "yts.mx", "yts.lt", "yts.am", "yts.ag"}
+
+// ErrNotAllowed is returned for .torrent URLs outside Options.TorrentHosts,
+// and with PublicOnly, for hosts that resolve to non-public addresses.
+var ErrNotAllowed = errors.New("url not allowed")
+
+// torrentClient returns the client .torrent files are downloaded with.
+func (c *Client) torrentClient() *http.Client {
+	if !c.opts.PublicOnly {
+		return httpClient
+	}
+	return publicClient
+}
+
+// publicClient only connects to public addresses. The check runs on the
+// address actually dialed, after DNS resolution and on every redirect, so
+// neither a hostname nor a redirect can point it at the local network.
+var publicClient = &http.Client{
+	Timeout: httpClient.Timeout,
+	Transport: &http.Transport{
+		Proxy: nil, // A proxy would dial on our behalf
+		DialContext: (&net.Dialer{
+			Timeout: 10 * time.Second,
+			Control: refusePrivate,
+		}).DialContext,
+		ForceAttemptHTTP2:   true,
+		TLSHandshakeTimeout: 10 * time.Second,
+	},
+}
+
+func refusePrivate(network, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrNotAllowed, address)
+	}
+	if ip := ap.Addr().Unmap(); !isPublic(ip) {
+		return fmt.Errorf("%w: %s is not a public address", ErrNotAllowed, ip)
+	}
+	return nil
+}
+
+// isPublic reports whether ip is a global unicast address outside the
+// private, shared (CGNAT) and documentation ranges.
+func isPublic(ip netip.Addr) bool {
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, p := range nonPublic {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+var nonPublic = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64 can reach IPv4 private ranges
+}
+
+// checkTorrentURL refuses URLs that aren't http(s) on an allowed host.
+func (c *Client) checkTorrentURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: scheme %q", ErrNotAllowed, u.Scheme)
+	}
+	if len(c.opts.TorrentHosts) == 0 {
+		return nil
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if slices.ContainsFunc(c.opts.TorrentHosts, func(h string) bool {
+		h = strings.ToLower(h)
+		return host == h || strings.HasSuffix(host, "."+h)
+	}) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s is not a provider host", ErrNotAllowed, u.Hostname())
+}
+
+// fetchTorrentData downloads a .torrent file into memory from a provider
+// URL.
+func (c *Client) fetchTorrentData(ctx context.Context, torrentURL string) ([]byte, error) {
+	u, err := url.Parse(torrentURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotAllowed, err)
+	}
+	if err := c.checkTorrentURL(u); err != nil {
+		return nil, err
+	}
+	return download(ctx, c.torrentClient(), u.String(), func(req *http.Request) error {
+		return c.checkTorrentURL(req.URL)
+	})
+}
+
+// download GETs a .torrent file with client, checking every redirect with
+// check, and refuses bodies over maxTorrentSize or that aren't bencoded.
+func download(ctx context.Context, client *http.Client, rawURL string, check func(*http.Request) error) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download: %w", err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0")
+
+	// A per-request copy, so checking redirects doesn't touch the shared client
+	cl := *client
+	cl.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		if check != nil {
+			return check(req)
+		}
+		return nil
+	}
+	resp, err := cl.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download failed with status: %d", resp.StatusCode)
+	}
+	if resp.ContentLength > maxTorrentSize {
+		return nil, fmt.Errorf("download refused: %d bytes is too large for a .torrent", resp.ContentLength)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxTorrentSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download: %w", err)
+	}
+	if len(data) > maxTorrentSize {
+		return nil, fmt.Errorf("download refused: too large for a .torrent")
+	}
+	// Metainfo is a bencoded dictionary; anything else is an error page
+	if len(data) == 0 || data[0] != 'd' {
+		return nil, fmt.Errorf("invalid .torrent: not bencoded metainfo")
+	}
+	return data, nil
+}
@@ -0,0 +1,144 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Grab is a torrent handed to a download client.
+type Grab struct {
+	Name    string // Display name, used for files and client labels
+	Hash    string // v1 infohash
+	Magnet  string // Magnet URI, always set
+	Torrent []byte // .torrent metainfo, when it could be fetched
+}
+
+// AddOptions override a client's configured defaults for a single grab.
+type AddOptions struct {
+	Category string
+	SavePath string
+	Tags     []string
+}
+
+// DownloadClient sends grabs to a BitTorrent client.
+type DownloadClient interface {
+	// Name is the human-readable client name shown in status messages.
+	Name() string
+	// Add queues the grab and returns the client's ID for it, if any.
+	Add(ctx context.Context, g Grab, opts AddOptions) (string, error)
+}
+
+// DownloadStatus is a snapshot of one download's progress.
+type DownloadStatus struct {
+	ID        string
+	Name      string
+	State     string // Client-specific, e.g. "active", "waiting", "complete", "error"
+	Total     int64  // Bytes, 0 while metadata is unknown
+	Completed int64
+	Speed     int64 // Bytes per second
+	Peers     int
+	Seeders   int
+	Error     string
+}
+
+// Progress returns the completed fraction in [0, 1].
+func (s DownloadStatus) Progress() float64 {
+	if s.Total <= 0 {
+		return 0
+	}
+	return float64(s.Completed) / float64(s.Total)
+}
+
+// ETA estimates the time remaining at the current speed, or 0 if unknown.
+func (s DownloadStatus) ETA() time.Duration {
+	if s.Speed <= 0 || s.Total <= s.Completed {
+		return 0
+	}
+	return time.Duration((s.Total-s.Completed)/s.Speed) * time.Second
+}
+
+// ProgressReporter is implemented by clients that can report download progress.
+type ProgressReporter interface {
+	Status(ids []string) ([]DownloadStatus, error)
+}
+
+// ClientConfig selects a download client and holds the settings of each.
+// Only the section for Kind is used.
+type ClientConfig struct {
+	Kind         string // "qbittorrent", "transmission", "aria2", "deluge", "rtorrent" or "watch"
+	WatchDir     string
+	QBittorrent  QBittorrentConfig
+	Transmission TransmissionConfig
+	Aria2        Aria2Config
+	Deluge       DelugeConfig
+	RTorrent     RTorrentConfig
+}
+
+// NewDownloadClient builds the client selected by cfg.Kind, or returns nil
+// when no kind is set. The watch folder client fetches .torrent files for
+// infohash-only grabs through c.
+func (c *Client) NewDownloadClient(cfg ClientConfig) (DownloadClient, error) {
+	switch strings.ToLower(cfg.Kind) {
+	case "":
+		return nil, nil
+	case "qbittorrent", "qbit":
+		return newQBittorrentClient(cfg.QBittorrent)
+	case "transmission":
+		return newTransmissionClient(cfg.Transmission)
+	case "aria2":
+		return newAria2Client(cfg.Aria2)
+	case "deluge":
+		return newDelugeClient(cfg.Deluge)
+	case "rtorrent":
+		return newRTorrentClient(cfg.RTorrent)
+	case "watch":
+		return newWatchClient(c, cfg.WatchDir)
+	default:
+		return nil, fmt.Errorf("unknown download client %q", cfg.Kind)
+	}
+}
+
+// Send builds a grab for the torrent and queues it on dl, returning the
+// client's ID for the download. A *MagnetFallback error comes with a valid
+// ID: the grab was queued, only not as the .torrent.
+func (c *Client) Send(ctx context.Context, dl DownloadClient, torrent Torrent, name string, opts AddOptions) (string, error) {
+	g := Grab{
+		Name:   name,
+		Hash:   torrent.Hash,
+		Magnet: c.Magnet(torrent.Hash, name),
+	}
+	// Prefer the real .torrent when the provider has one; fall back to the
+	// magnet if it can't be fetched or isn't the torrent we asked for
+	if torrent.URL != "" {
+		if _, data, err := c.FetchTorrent(ctx, torrent); err == nil {
+			g.Torrent = data
+		}
+	}
+
+	id, err := dl.Add(ctx, g, opts)
+	var fallback *MagnetFallback
+	if errors.As(err, &fallback) {
+		return id, err
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", dl.Name(), err)
+	}
+	return id, nil
+}
+
+// mergeOptions fills unset per-grab options from the client's defaults.
+func mergeOptions(opts, defaults AddOptions) AddOptions {
+	if opts.Category == "" {
+		opts.Category = defaults.Category
+	}
+	if opts.SavePath == "" {
+		opts.SavePath = defaults.SavePath
+	}
+	if len(opts.Tags) == 0 {
+		opts.Tags = defaults.Tags
+	}
+	return opts
+}
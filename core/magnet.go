@@ -0,0 +1,203 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"c-cli/metafetch"
+	"c-cli/metainfo"
+	"c-cli/quality"
+)
+
+// MagnetTrackers returns the healthiest trackers to put in magnets and
+// .torrent files.
+func (c *Client) MagnetTrackers() []string {
+	return c.opts.Trackers.Healthiest(c.opts.MagnetTrackers)
+}
+
+// Magnet builds a magnet URI for hash with the healthiest trackers.
+func (c *Client) Magnet(hash, name string) string {
+	var trackerParams strings.Builder
+	for _, t := range c.MagnetTrackers() {
+		trackerParams.WriteString("&tr=")
+		trackerParams.WriteString(url.QueryEscape(t))
+	}
+
+	return fmt.Sprintf("magnet:?xt=urn:btih:%s&dn=%s%s",
+		hash, url.QueryEscape(name), trackerParams.String())
+}
+
+// Torrent cache services that provide .torrent files from infohash
+var torrentCacheURLs = []string{
+	"https://itorrents.org/torrent/%s.torrent",
+	"http://btcache.me/torrent/%s",
+}
+
+// fetchTorrentFromCache tries to download a .torrent file from cache services
+func (c *Client) fetchTorrentFromCache(ctx context.Context, infohash string) ([]byte, error) {
+	upperHash := strings.ToUpper(infohash)
+
+	for _, urlTemplate := range torrentCacheURLs {
+		data, err := download(ctx, c.torrentClient(), fmt.Sprintf(urlTemplate, upperHash), nil)
+		if err != nil {
+			continue
+		}
+		// Caches sometimes serve error pages or the wrong torrent
+		if _, err := VerifyTorrent(data, infohash); err == nil {
+			return data, nil
+		}
+	}
+
+	return nil, fmt.Errorf("could not fetch .torrent from any cache service")
+}
+
+// metadataFetchTimeout bounds a swarm metadata lookup for one torrent.
+const metadataFetchTimeout = 30 * time.Second
+
+// FetchTorrentByHash gets a .torrent for an infohash-only result. With
+// MetadataFetch the swarm is asked first; cache services are the fallback.
+func (c *Client) FetchTorrentByHash(ctx context.Context, infohash string) ([]byte, error) {
+	if !c.opts.MetadataFetch {
+		return c.fetchTorrentFromCache(ctx, infohash)
+	}
+	data, swarmErr := c.fetchTorrentFromSwarm(ctx, infohash)
+	if swarmErr == nil {
+		return data, nil
+	}
+	data, err := c.fetchTorrentFromCache(ctx, infohash)
+	if err != nil {
+		return nil, fmt.Errorf("%v; %w", swarmErr, err)
+	}
+	return data, nil
+}
+
+// fetchTorrentFromSwarm downloads the info dictionary from peers found via
+// the healthy trackers and the DHT, and wraps it into a .torrent.
+func (c *Client) fetchTorrentFromSwarm(ctx context.Context, infohash string) ([]byte, error) {
+	hash, err := metainfo.ParseHash(infohash)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, metadataFetchTimeout)
+	defer cancel()
+
+	f := metafetch.Fetcher{Trackers: c.opts.Trackers.Healthiest(0), DHT: true}
+	if c.opts.PublicOnly {
+		// Peers come from trackers and the DHT, so anyone can point us at
+		// an internal address
+		f.Dialer = &net.Dialer{Timeout: 5 * time.Second, Control: refusePrivate}
+	}
+	info, err := f.Fetch(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	return metafetch.Torrent(info, c.MagnetTrackers())
+}
+
+// VerifyTorrent parses .torrent data and, when hash is given, checks that
+// its infohash is the one we asked for.
+func VerifyTorrent(data []byte, hash string) (*metainfo.MetaInfo, error) {
+	meta, err := metainfo.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid .torrent: %w", err)
+	}
+	if hash != "" {
+		if err := meta.Verify(hash); err != nil {
+			return nil, err
+		}
+	}
+	return meta, nil
+}
+
+// FetchTorrent downloads the torrent's .torrent file from its URL, or from the
+// swarm or a cache service for infohash-only results, and verifies its infohash.
+func (c *Client) FetchTorrent(ctx context.Context, torrent Torrent) (*metainfo.MetaInfo, []byte, error) {
+	var data []byte
+	var err error
+	if torrent.URL != "" {
+		data, err = c.fetchTorrentData(ctx, torrent.URL)
+	} else {
+		data, err = c.FetchTorrentByHash(ctx, torrent.Hash)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	meta, err := VerifyTorrent(data, torrent.Hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	return meta, data, nil
+}
+
+// TorrentFilename is the name a torrent of title is saved under.
+func TorrentFilename(title, quality string) string {
+	return fmt.Sprintf("%s.%s.torrent", SanitizeFilename(title), quality)
+}
+
+// WriteFileAtomic writes data to a hidden temp file in the target directory
+// and renames it into place, so watch-folder clients never see a partial file.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir, name := filepath.Split(path)
+	tmp, err := os.CreateTemp(dir, "."+name+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	tmpName := tmp.Name()
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpName, perm)
+	}
+	if err == nil {
+		err = os.Rename(tmpName, path)
+	}
+	if err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+// SanitizeFilename replaces the characters that are problematic in
+// filenames on any platform.
+func SanitizeFilename(name string) string {
+	replacer := strings.NewReplacer(
+		"/", "-", "\\", "-", ":", "-", "*", "-",
+		"?", "-", "\"", "-", "<", "-", ">", "-", "|", "-",
+	)
+	return replacer.Replace(name)
+}
+
+// SelectBestTorrent picks the torrent profile p prefers. The Choice says
+// why, or why every torrent was ruled out when it returns nil.
+func SelectBestTorrent(torrents []Torrent, p quality.Profile) (*Torrent, quality.Choice) {
+	cands := make([]quality.Candidate, len(torrents))
+	for i, t := range torrents {
+		cands[i] = quality.Candidate{
+			Name:    t.Name,
+			Quality: t.Quality,
+			Type:    t.Type,
+			Codec:   t.VideoCodec,
+			Size:    t.SizeBytes,
+			Seeds:   t.Seeds,
+		}
+	}
+	choice := p.Pick(cands)
+	if choice.Index < 0 {
+		return nil, choice
+	}
+	return &torrents[choice.Index], choice
+}
@@ -0,0 +1,157 @@
+package core
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"c-cli/release"
+)
+
+// omdbEnabled reports whether OMDB lookups are configured.
+func (c *Client) omdbEnabled() bool {
+	return c.opts.OMDB != nil && c.opts.OMDB.APIKey != ""
+}
+
+// OMDBByID looks a title up on OMDB by IMDb ID. It returns nil, with no
+// error, when OMDB isn't configured or has no such title.
+func (c *Client) OMDBByID(ctx context.Context, imdbID string) (*OMDBMovie, error) {
+	if !c.omdbEnabled() || imdbID == "" {
+		return nil, nil
+	}
+
+	var movie OMDBMovie
+	found, err := c.opts.OMDB.ByID(ctx, imdbID, &movie)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return &movie, nil
+}
+
+// SearchOMDB finds a title on OMDB by name and year, trying a series search
+// when the title looks like a TV release.
+func (c *Client) SearchOMDB(ctx context.Context, title string, year int) (*OMDBMovie, error) {
+	if !c.omdbEnabled() {
+		return nil, nil
+	}
+
+	// First try without type restriction
+	result := c.searchOMDBWithType(ctx, title, year, "")
+	if result != nil {
+		return result, nil
+	}
+
+	// If no result and title looks like a TV show, try searching as series
+	if rel := release.Parse(title); rel.IsTV() {
+		return c.searchOMDBWithType(ctx, rel.Title, year, "series"), nil
+	}
+
+	return nil, nil
+}
+
+func (c *Client) searchOMDBWithType(ctx context.Context, title string, year int, mediaType string) *OMDBMovie {
+	if !c.omdbEnabled() {
+		return nil
+	}
+
+	// Try with year first
+	if year > 0 {
+		if result := c.doOMDBSearch(ctx, title, year, mediaType); result != nil {
+			return result
+		}
+	}
+
+	// Try without year as fallback
+	return c.doOMDBSearch(ctx, title, 0, mediaType)
+}
+
+func (c *Client) doOMDBSearch(ctx context.Context, title string, year int, mediaType string) *OMDBMovie {
+	var movie OMDBMovie
+	if found, err := c.opts.OMDB.Search(ctx, title, year, mediaType, &movie); err != nil || !found {
+		return nil
+	}
+
+	return &movie
+}
+
+// EnrichMovie looks movie up on OMDB: by IMDb ID when the provider gave
+// one, otherwise by the title parsed out of its release name.
+func (c *Client) EnrichMovie(ctx context.Context, movie *Movie) {
+	// YTS and indexers that report an IMDb ID can be looked up directly
+	if movie.IMDBCode != "" {
+		if omdb, err := c.OMDBByID(ctx, movie.IMDBCode); err == nil && omdb != nil {
+			movie.OMDB = omdb
+		}
+		return
+	}
+
+	rel := release.Parse(movie.Title)
+	var omdb *OMDBMovie
+	if rel.IsTV() {
+		// For TV content, search specifically as series first
+		omdb = c.searchOMDBWithType(ctx, rel.Title, movie.Year, "series")
+		if omdb == nil {
+			omdb = c.searchOMDBWithType(ctx, rel.Title, movie.Year, "")
+		}
+	} else {
+		// For non-TV content, try general search
+		omdb = c.searchOMDBWithType(ctx, rel.Title, movie.Year, "")
+	}
+
+	if omdb != nil {
+		movie.OMDB = omdb
+		movie.IMDBCode = omdb.IMDBID
+	}
+}
+
+// EnrichMovies adds OMDB data to movies in place, on the shared enrichment
+// pool. each, if not nil, is called with every movie as soon as its lookup
+// is done, from the pool's goroutines. It returns once all lookups are done
+// or ctx is done.
+func (c *Client) EnrichMovies(ctx context.Context, movies []Movie, each func(i int, movie Movie)) {
+	if !c.omdbEnabled() {
+		return
+	}
+	c.opts.Enrich.Each(ctx, len(movies), func(i int) {
+		// Each index is only touched by one worker
+		c.EnrichMovie(ctx, &movies[i])
+		if each != nil {
+			each(i, movies[i])
+		}
+	})
+}
+
+// SortByVotes orders movies by IMDb vote count, most voted first.
+func SortByVotes(movies []Movie) {
+	slices.SortStableFunc(movies, func(a, b Movie) int {
+		return cmp.Compare(ParseVotes(b.OMDB), ParseVotes(a.OMDB))
+	})
+}
+
+// ParseVotes returns OMDB's IMDb vote count as a number; 0 when unknown.
+func ParseVotes(omdb *OMDBMovie) int {
+	if omdb == nil || omdb.IMDBVotes == "" || omdb.IMDBVotes == "N/A" {
+		return 0
+	}
+	// Remove commas from vote count (e.g., "1,234,567" -> "1234567")
+	voteStr := strings.ReplaceAll(omdb.IMDBVotes, ",", "")
+	votes, _ := strconv.Atoi(voteStr)
+	return votes
+}
+
+func FormatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.2f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
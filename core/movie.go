@@ -0,0 +1,95 @@
+package core
+
+import "time"
+
+// Movie is one search result. Movie providers such as YTS return a title
+// with several torrents; release providers return a single torrent, with
+// Infohash, Size, Seeders and Leechers copied from it.
+type Movie struct {
+	ID          int          `json:"id"`
+	Title       string       `json:"title"`
+	Year        int          `json:"year"`
+	Rating      float64      `json:"rating"`
+	Runtime     int          `json:"runtime"`
+	Genres      []string     `json:"genres"`
+	Summary     string       `json:"summary"`
+	Description string       `json:"description_full"`
+	IMDBCode    string       `json:"imdb_code"`
+	SmallCover  string       `json:"small_cover_image"`
+	MediumCover string       `json:"medium_cover_image"`
+	LargeCover  string       `json:"large_cover_image"`
+	Torrents    []Torrent    `json:"torrents"`
+	OMDB        *OMDBMovie   `json:"omdb,omitempty"`
+	Source      SearchSource `json:"source"`             // Provider that returned this result
+	Infohash    string       `json:"infohash,omitempty"` // For release results
+	Size        string       `json:"size,omitempty"`     // For release results
+	Seeders     int          `json:"seeders,omitempty"`  // For release results
+	Leechers    int          `json:"leechers,omitempty"` // For release results
+}
+
+type Torrent struct {
+	URL              string `json:"url"`
+	Hash             string `json:"hash"`
+	Quality          string `json:"quality"`
+	Type             string `json:"type"`
+	VideoCodec       string `json:"video_codec"`
+	Size             string `json:"size"`
+	SizeBytes        int64  `json:"size_bytes"`
+	Seeds            int    `json:"seeds"`
+	Peers            int    `json:"peers"`
+	DateUploadedUnix int64  `json:"date_uploaded_unix"`
+	// Release name, for providers that list releases rather than movies
+	Name string `json:"name,omitempty"`
+	// Set when Seeds and Peers come from a live tracker scrape rather than
+	// the provider's cached counts
+	ScrapedAt time.Time `json:"-"`
+}
+
+type OMDBMovie struct {
+	Title        string `json:"Title"`
+	Year         string `json:"Year"`
+	Rated        string `json:"Rated"`
+	Released     string `json:"Released"`
+	Runtime      string `json:"Runtime"`
+	Genre        string `json:"Genre"`
+	Director     string `json:"Director"`
+	Writer       string `json:"Writer"`
+	Actors       string `json:"Actors"`
+	Plot         string `json:"Plot"`
+	Poster       string `json:"Poster"`
+	IMDBRating   string `json:"imdbRating"`
+	IMDBVotes    string `json:"imdbVotes"`
+	IMDBID       string `json:"imdbID"`
+	Type         string `json:"Type"`         // "movie", "series", or "episode"
+	TotalSeasons string `json:"totalSeasons"` // Only for series
+	Response     string `json:"Response"`
+	Error        string `json:"Error"`
+}
+
+// IsSeries reports whether OMDB lists the title as a show or an episode.
+func (o *OMDBMovie) IsSeries() bool {
+	return o != nil && (o.Type == "series" || o.Type == "episode")
+}
+
+// SearchResult contains paginated search results
+type SearchResult struct {
+	Movies     []Movie
+	Total      int
+	Page       int
+	PerPage    int
+	TotalPages int
+}
+
+// SearchSource identifies a provider: "yts", "torrents-csv" or
+// "torznab:<name>".
+type SearchSource string
+
+const (
+	SourceYTS         SearchSource = "yts"
+	SourceTorrentsCSV SearchSource = "torrents-csv"
+)
+
+// totalPages is the page count for total results at perPage; at least one.
+func totalPages(total, perPage int) int {
+	return max((total+perPage-1)/perPage, 1)
+}
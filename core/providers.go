@@ -0,0 +1,85 @@
+package core
+
+import (
+	"context"
+	"fmt"
+)
+
+// Capabilities describes what a search provider supports beyond plain search.
+type Capabilities struct {
+	Paging  bool // Provider pages results server-side
+	Details bool // Provider has a separate details lookup (otherwise results are complete)
+	IMDBIDs bool // Results carry IMDb IDs without OMDB enrichment
+}
+
+// Provider is a search backend. Providers are registered with a Client and
+// listed in registration order.
+type Provider interface {
+	// Source is the identifier stored in Movie.Source.
+	Source() SearchSource
+	// Label is the human-readable name shown in the UI.
+	Label() string
+	Capabilities() Capabilities
+	// Search and Details give up when ctx is done, e.g. when the user
+	// navigates away. Search leaves OMDB enrichment to the caller, so
+	// results can be shown before the lookups finish.
+	Search(ctx context.Context, query string, page, perPage int) (SearchResult, error)
+	// Details returns the full record for a search result. Providers without
+	// the Details capability return the movie unchanged.
+	Details(ctx context.Context, movie Movie) (*Movie, error)
+}
+
+// Register adds a provider. Registering a source twice replaces the earlier
+// provider in place.
+func (c *Client) Register(p Provider) {
+	for i, existing := range c.providers {
+		if existing.Source() == p.Source() {
+			c.providers[i] = p
+			return
+		}
+	}
+	c.providers = append(c.providers, p)
+}
+
+// Providers returns all registered providers in registration order.
+func (c *Client) Providers() []Provider {
+	return c.providers
+}
+
+// Provider finds a registered provider by source.
+func (c *Client) Provider(source SearchSource) (Provider, bool) {
+	for _, p := range c.providers {
+		if p.Source() == source {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+func (c *Client) providerFor(source SearchSource) (Provider, error) {
+	p, ok := c.Provider(source)
+	if !ok {
+		return nil, fmt.Errorf("unknown search source %q", source)
+	}
+	return p, nil
+}
+
+// Search runs a search against the registered provider for source and
+// returns the results as listed, without OMDB data; see EnrichMovies. The
+// search is abandoned when ctx is done.
+func (c *Client) Search(ctx context.Context, query string, page, perPage int, source SearchSource) (SearchResult, error) {
+	p, err := c.providerFor(source)
+	if err != nil {
+		return SearchResult{}, err
+	}
+	return p.Search(ctx, query, page, perPage)
+}
+
+// Details fetches the full record for a search result from its provider.
+func (c *Client) Details(ctx context.Context, movie Movie) (*Movie, error) {
+	p, err := c.providerFor(movie.Source)
+	if err != nil {
+		return nil, err
+	}
+	return p.Details(ctx, movie)
+}
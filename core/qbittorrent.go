@@ -0,0 +1,159 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// QBittorrentConfig is the [qbittorrent] section of config.toml, or the
+// QBITTORRENT_* variables for c-cli-web.
+type QBittorrentConfig struct {
+	URL      string   `toml:"url"` // WebUI address, e.g. http://localhost:8080
+	Username string   `toml:"username"`
+	Password string   `toml:"password"`
+	Category string   `toml:"category"`
+	SavePath string   `toml:"save_path"`
+	Tags     []string `toml:"tags"`
+}
+
+// qbittorrentClient talks to the qBittorrent Web API v2.
+type qbittorrentClient struct {
+	baseURL  string
+	username string
+	password string
+	defaults AddOptions
+	client   *http.Client
+
+	mu       sync.Mutex
+	loggedIn bool
+}
+
+func newQBittorrentClient(cfg QBittorrentConfig) (DownloadClient, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("qbittorrent: url is required")
+	}
+	jar, _ := cookiejar.New(nil)
+	return &qbittorrentClient{
+		baseURL:  strings.TrimRight(cfg.URL, "/"),
+		username: cfg.Username,
+		password: cfg.Password,
+		defaults: AddOptions{Category: cfg.Category, SavePath: cfg.SavePath, Tags: cfg.Tags},
+		client:   &http.Client{Timeout: httpClient.Timeout, Jar: jar},
+	}, nil
+}
+
+func (c *qbittorrentClient) Name() string { return "qBittorrent" }
+
+// login starts a session; the SID cookie is kept in the client's jar.
+func (c *qbittorrentClient) login(ctx context.Context) error {
+	form := url.Values{}
+	form.Set("username", c.username)
+	form.Set("password", c.password)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/v2/auth/login", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// qBittorrent's CSRF protection rejects requests without a matching Referer
+	req.Header.Set("Referer", c.baseURL)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("login failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("login refused: IP banned after too many failed attempts")
+	}
+	if resp.StatusCode != http.StatusOK || strings.TrimSpace(string(body)) != "Ok." {
+		return fmt.Errorf("login failed: check username and password")
+	}
+	c.loggedIn = true
+	return nil
+}
+
+func (c *qbittorrentClient) Add(ctx context.Context, g Grab, opts AddOptions) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	opts = mergeOptions(opts, c.defaults)
+
+	if !c.loggedIn {
+		if err := c.login(ctx); err != nil {
+			return "", err
+		}
+	}
+
+	status, err := c.add(ctx, g, opts)
+	if err == nil && status == http.StatusForbidden {
+		// Session expired; log in again and retry once
+		if err := c.login(ctx); err != nil {
+			return "", err
+		}
+		status, err = c.add(ctx, g, opts)
+	}
+	if err != nil {
+		return "", err
+	}
+	if status != http.StatusOK {
+		return "", fmt.Errorf("add failed with status: %d", status)
+	}
+	return strings.ToLower(g.Hash), nil
+}
+
+// add posts torrents/add, uploading the .torrent if we have one, else the magnet.
+func (c *qbittorrentClient) add(ctx context.Context, g Grab, opts AddOptions) (int, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	if len(g.Torrent) > 0 {
+		fw, err := mw.CreateFormFile("torrents", SanitizeFilename(g.Name)+".torrent")
+		if err != nil {
+			return 0, err
+		}
+		fw.Write(g.Torrent)
+	} else {
+		mw.WriteField("urls", g.Magnet)
+	}
+	if opts.Category != "" {
+		mw.WriteField("category", opts.Category)
+	}
+	if opts.SavePath != "" {
+		mw.WriteField("savepath", opts.SavePath)
+	}
+	if len(opts.Tags) > 0 {
+		mw.WriteField("tags", strings.Join(opts.Tags, ","))
+	}
+	mw.Close()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/v2/torrents/add", &body)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Referer", c.baseURL)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+
+	// qBittorrent answers 200 "Fails." when the torrent is invalid or a duplicate
+	if resp.StatusCode == http.StatusOK && strings.TrimSpace(string(respBody)) == "Fails." {
+		return 0, fmt.Errorf("qBittorrent rejected the torrent (invalid or already added)")
+	}
+	return resp.StatusCode, nil
+}
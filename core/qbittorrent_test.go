@@ -0,0 +1,94 @@
+package core
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// qbittorrentd is a stand-in qBittorrent WebUI. Logins hand out a new SID
+// cookie; torrents/add answers 403 unless the current one is sent.
+type qbittorrentd struct {
+	password string
+
+	logins int
+	sid    string
+	urls   []string // "urls" fields of accepted adds
+}
+
+func (d *qbittorrentd) start(t *testing.T) DownloadClient {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Referer") != srv.URL {
+			t.Errorf("%s: Referer = %q, want %q", r.URL.Path, r.Header.Get("Referer"), srv.URL)
+		}
+		switch r.URL.Path {
+		case "/api/v2/auth/login":
+			if r.FormValue("username") != "admin" || r.FormValue("password") != d.password {
+				io.WriteString(w, "Fails.")
+				return
+			}
+			d.logins++
+			d.sid = "sid" + strconv.Itoa(d.logins)
+			http.SetCookie(w, &http.Cookie{Name: "SID", Value: d.sid, Path: "/"})
+			io.WriteString(w, "Ok.")
+		case "/api/v2/torrents/add":
+			if c, err := r.Cookie("SID"); err != nil || c.Value != d.sid {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			d.urls = append(d.urls, r.FormValue("urls"))
+			io.WriteString(w, "Ok.")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	dl, err := newQBittorrentClient(QBittorrentConfig{URL: srv.URL + "/", Username: "admin", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dl
+}
+
+func TestQBittorrentAdd(t *testing.T) {
+	d := &qbittorrentd{password: "secret"}
+	dl := d.start(t)
+	g := Grab{Magnet: "magnet:?xt=urn:btih:ABC", Hash: "ABC"}
+
+	id, err := dl.Add(context.Background(), g, AddOptions{})
+	if err != nil || id != "abc" {
+		t.Fatalf("Add = %q, %v; want abc", id, err)
+	}
+	// The SID cookie from the login is reused
+	if _, err := dl.Add(context.Background(), g, AddOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if d.logins != 1 || len(d.urls) != 2 || d.urls[0] != g.Magnet {
+		t.Errorf("%d logins, adds %q; want 1 login and 2 adds", d.logins, d.urls)
+	}
+
+	// An expired session is renewed once
+	d.sid = "expired"
+	if _, err := dl.Add(context.Background(), g, AddOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if d.logins != 2 || len(d.urls) != 3 {
+		t.Errorf("%d logins and %d adds after expiry, want 2 and 3", d.logins, len(d.urls))
+	}
+}
+
+func TestQBittorrentLoginFailed(t *testing.T) {
+	d := &qbittorrentd{password: "other"}
+	dl := d.start(t)
+	_, err := dl.Add(context.Background(), Grab{Magnet: "magnet:?xt=urn:btih:abc"}, AddOptions{})
+	if err == nil || !strings.Contains(err.Error(), "check username and password") {
+		t.Errorf("err = %v, want a login failure", err)
+	}
+}
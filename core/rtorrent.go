@@ -0,0 +1,151 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// RTorrentConfig is the [rtorrent] section of config.toml, or the
+// RTORRENT_* variables for c-cli-web.
+type RTorrentConfig struct {
+	URL         string `toml:"url"` // XML-RPC endpoint, e.g. http://localhost/RPC2
+	Username    string `toml:"username"`
+	Password    string `toml:"password"`
+	DownloadDir string `toml:"download_dir"`
+	Label       string `toml:"label"` // Stored in d.custom1, as ruTorrent does
+}
+
+// rtorrentClient talks to rTorrent over XML-RPC (usually an HTTP/SCGI bridge).
+type rtorrentClient struct {
+	rpcURL   string
+	username string
+	password string
+	defaults AddOptions
+	client   *http.Client
+}
+
+func newRTorrentClient(cfg RTorrentConfig) (DownloadClient, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("rtorrent: url is required")
+	}
+	return &rtorrentClient{
+		rpcURL:   cfg.URL,
+		username: cfg.Username,
+		password: cfg.Password,
+		defaults: AddOptions{Category: cfg.Label, SavePath: cfg.DownloadDir},
+		client:   &http.Client{Timeout: httpClient.Timeout},
+	}, nil
+}
+
+func (c *rtorrentClient) Name() string { return "rTorrent" }
+
+func (c *rtorrentClient) Add(ctx context.Context, g Grab, opts AddOptions) (string, error) {
+	opts = mergeOptions(opts, c.defaults)
+
+	// rTorrent 0.9+ takes an empty target as the first argument, then the
+	// torrent, then commands run on the new download before it starts
+	params := []xmlrpcValue{xmlrpcString("")}
+	method := "load.start"
+	if len(g.Torrent) > 0 {
+		method = "load.raw_start"
+		params = append(params, xmlrpcBase64(g.Torrent))
+	} else {
+		params = append(params, xmlrpcString(g.Magnet))
+	}
+	if opts.SavePath != "" {
+		params = append(params, xmlrpcString("d.directory.set="+opts.SavePath))
+	}
+	if opts.Category != "" {
+		params = append(params, xmlrpcString("d.custom1.set="+opts.Category))
+	}
+
+	if err := c.call(ctx, method, params); err != nil {
+		return "", err
+	}
+	return strings.ToUpper(g.Hash), nil
+}
+
+// xmlrpcValue is a pre-encoded XML-RPC <value> element.
+type xmlrpcValue string
+
+func xmlrpcString(s string) xmlrpcValue {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return xmlrpcValue("<value><string>" + b.String() + "</string></value>")
+}
+
+func xmlrpcBase64(data []byte) xmlrpcValue {
+	return xmlrpcValue("<value><base64>" + base64.StdEncoding.EncodeToString(data) + "</base64></value>")
+}
+
+type xmlrpcResponse struct {
+	Fault *struct {
+		Members []struct {
+			Name  string `xml:"name"`
+			Value struct {
+				Int    string `xml:"int"`
+				I4     string `xml:"i4"`
+				String string `xml:"string"`
+				Text   string `xml:",chardata"`
+			} `xml:"value"`
+		} `xml:"value>struct>member"`
+	} `xml:"fault"`
+}
+
+func (c *rtorrentClient) call(ctx context.Context, method string, params []xmlrpcValue) error {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0"?><methodCall><methodName>`)
+	b.WriteString(method)
+	b.WriteString(`</methodName><params>`)
+	for _, p := range params {
+		b.WriteString("<param>")
+		b.WriteString(string(p))
+		b.WriteString("</param>")
+	}
+	b.WriteString(`</params></methodCall>`)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.rpcURL, strings.NewReader(b.String()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/xml")
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return fmt.Errorf("authentication failed: check username and password")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("rpc failed with status: %d", resp.StatusCode)
+	}
+
+	var result xmlrpcResponse
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("invalid rpc response: %w", err)
+	}
+	if result.Fault != nil {
+		msg := "unknown fault"
+		for _, m := range result.Fault.Members {
+			if m.Name == "faultString" {
+				msg = m.Value.String
+				if msg == "" {
+					msg = strings.TrimSpace(m.Value.Text)
+				}
+			}
+		}
+		return fmt.Errorf("%s", msg)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package core
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// rtorrentd is a stand-in XML-RPC bridge that records calls and answers with
+// fault, when set, instead of success.
+type rtorrentd struct {
+	fault string // <value> of faultString
+
+	method string
+	params []string
+}
+
+func (d *rtorrentd) start(t *testing.T) DownloadClient {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "rt" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var call struct {
+			Method string `xml:"methodName"`
+			Params []struct {
+				Value string `xml:",innerxml"`
+			} `xml:"params>param>value"`
+		}
+		if err := xml.NewDecoder(r.Body).Decode(&call); err != nil {
+			t.Error(err)
+		}
+		d.method, d.params = call.Method, nil
+		for _, p := range call.Params {
+			d.params = append(d.params, p.Value)
+		}
+
+		if d.fault != "" {
+			io.WriteString(w, `<?xml version="1.0"?><methodResponse><fault><value><struct>`+
+				`<member><name>faultCode</name><value><i4>-501</i4></value></member>`+
+				`<member><name>faultString</name><value>`+d.fault+`</value></member>`+
+				`</struct></value></fault></methodResponse>`)
+			return
+		}
+		io.WriteString(w, `<?xml version="1.0"?><methodResponse><params><param><value><i4>0</i4></value></param></params></methodResponse>`)
+	}))
+	t.Cleanup(srv.Close)
+
+	dl, err := newRTorrentClient(RTorrentConfig{URL: srv.URL + "/RPC2", Username: "rt", Password: "secret", Label: "movies"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dl
+}
+
+func TestRTorrentAdd(t *testing.T) {
+	d := &rtorrentd{}
+	g := Grab{Hash: "abc", Magnet: "magnet:?xt=urn:btih:abc&dn=A&B"}
+	id, err := d.start(t).Add(context.Background(), g, AddOptions{SavePath: "/data"})
+	if err != nil || id != "ABC" {
+		t.Fatalf("Add = %q, %v; want ABC", id, err)
+	}
+	want := []string{
+		"<string></string>",
+		"<string>magnet:?xt=urn:btih:abc&amp;dn=A&amp;B</string>",
+		"<string>d.directory.set=/data</string>",
+		"<string>d.custom1.set=movies</string>",
+	}
+	if d.method != "load.start" || !reflect.DeepEqual(d.params, want) {
+		t.Errorf("called %s%q, want load.start%q", d.method, d.params, want)
+	}
+
+	g.Torrent = []byte("d4:infode")
+	if _, err := d.start(t).Add(context.Background(), g, AddOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if d.method != "load.raw_start" || len(d.params) < 2 || d.params[1] != "<base64>ZDQ6aW5mb2Rl</base64>" {
+		t.Errorf("called %s%q, want load.raw_start with the base64 torrent", d.method, d.params)
+	}
+}
+
+func TestRTorrentFault(t *testing.T) {
+	for _, fault := range []string{
+		"<string>Could not create download</string>",
+		"Could not create download", // Some bridges leave out the <string> type
+	} {
+		d := &rtorrentd{fault: fault}
+		_, err := d.start(t).Add(context.Background(), Grab{Magnet: "magnet:?xt=urn:btih:abc"}, AddOptions{})
+		if err == nil || err.Error() != "Could not create download" {
+			t.Errorf("fault %q: err = %v, want the faultString", fault, err)
+		}
+	}
+}
+
+func TestRTorrentUnauthorized(t *testing.T) {
+	d := &rtorrentd{}
+	dl := d.start(t).(*rtorrentClient)
+	dl.password = "guess"
+	_, err := dl.Add(context.Background(), Grab{Magnet: "magnet:?xt=urn:btih:abc"}, AddOptions{})
+	if err == nil || !strings.Contains(err.Error(), "authentication failed") {
+		t.Errorf("err = %v, want an authentication failure", err)
+	}
+}
@@ -0,0 +1,189 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+
+	"c-cli/release"
+)
+
+const (
+	ytsBaseURL     = "https://yts.bz/api/v2"
+	torrentsCSVURL = "https://torrents-csv.com/service/search"
+)
+
+type searchResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		MovieCount int     `json:"movie_count"`
+		Movies     []Movie `json:"movies"`
+	} `json:"data"`
+}
+
+type detailResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		Movie Movie `json:"movie"`
+	} `json:"data"`
+}
+
+// TorrentsCSV types
+type torrentsCSVResponse struct {
+	Torrents []torrentsCSVItem `json:"torrents"`
+}
+
+type torrentsCSVItem struct {
+	Infohash    string `json:"infohash"`
+	Name        string `json:"name"`
+	SizeBytes   int64  `json:"size_bytes"`
+	Seeders     int    `json:"seeders"`
+	Leechers    int    `json:"leechers"`
+	CreatedUnix int64  `json:"created_unix"`
+}
+
+// ytsProvider searches YTS, which pages server-side and has full movie details.
+type ytsProvider struct{ c *Client }
+
+func (ytsProvider) Source() SearchSource { return SourceYTS }
+func (ytsProvider) Label() string        { return "YTS (Movies)" }
+
+func (ytsProvider) Capabilities() Capabilities {
+	return Capabilities{Paging: true, Details: true, IMDBIDs: true}
+}
+
+func (p ytsProvider) Search(ctx context.Context, query string, page, perPage int) (SearchResult, error) {
+	params := url.Values{}
+	params.Set("query_term", query)
+	params.Set("limit", strconv.Itoa(perPage))
+	params.Set("page", strconv.Itoa(page))
+
+	var result searchResponse
+	if err := p.c.getJSON(ctx, SourceYTS, fmt.Sprintf("%s/list_movies.json?%s", ytsBaseURL, params.Encode()), &result); err != nil {
+		return SearchResult{}, err
+	}
+
+	movies := result.Data.Movies
+	if movies == nil {
+		movies = []Movie{}
+	}
+	for i := range movies {
+		movies[i].Source = SourceYTS
+	}
+
+	return SearchResult{
+		Movies:     movies,
+		Total:      result.Data.MovieCount,
+		Page:       page,
+		PerPage:    perPage,
+		TotalPages: totalPages(result.Data.MovieCount, perPage),
+	}, nil
+}
+
+// Details fetches the movie with all its torrents, and its OMDB data when
+// OMDB is configured.
+func (p ytsProvider) Details(ctx context.Context, movie Movie) (*Movie, error) {
+	params := url.Values{}
+	params.Set("movie_id", strconv.Itoa(movie.ID))
+	params.Set("with_images", "true")
+	params.Set("with_cast", "true")
+
+	var result detailResponse
+	if err := p.c.getJSON(ctx, SourceYTS, fmt.Sprintf("%s/movie_details.json?%s", ytsBaseURL, params.Encode()), &result); err != nil {
+		return nil, err
+	}
+
+	details := &result.Data.Movie
+	details.Source = SourceYTS
+	if omdb, err := p.c.OMDBByID(ctx, details.IMDBCode); err == nil && omdb != nil {
+		details.OMDB = omdb
+	}
+	return details, nil
+}
+
+// torrentsCSVProvider searches Torrents-CSV, whose results are single torrents.
+type torrentsCSVProvider struct{ c *Client }
+
+func (torrentsCSVProvider) Source() SearchSource { return SourceTorrentsCSV }
+func (torrentsCSVProvider) Label() string        { return "Torrents-CSV (All)" }
+
+func (torrentsCSVProvider) Capabilities() Capabilities {
+	return Capabilities{}
+}
+
+// imdbPattern finds an IMDB ID in a torrent name, which some uploaders add.
+var imdbPattern = regexp.MustCompile(`tt\d{7,}`)
+
+func (p torrentsCSVProvider) Search(ctx context.Context, query string, page, perPage int) (SearchResult, error) {
+	// Fetch larger batch and paginate locally (Torrents-CSV uses cursor pagination)
+	fetchSize := 200
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("size", strconv.Itoa(fetchSize))
+
+	var result torrentsCSVResponse
+	if err := p.c.getJSON(ctx, SourceTorrentsCSV, fmt.Sprintf("%s?%s", torrentsCSVURL, params.Encode()), &result); err != nil {
+		return SearchResult{}, err
+	}
+
+	allMovies := make([]Movie, 0, len(result.Torrents))
+	for _, t := range result.Torrents {
+		rel := release.Parse(t.Name)
+		allMovies = append(allMovies, Movie{
+			Title:    rel.Name(),
+			Year:     rel.Year,
+			IMDBCode: imdbPattern.FindString(t.Name),
+			Source:   SourceTorrentsCSV,
+			Infohash: t.Infohash,
+			Size:     FormatBytes(t.SizeBytes),
+			Seeders:  t.Seeders,
+			Leechers: t.Leechers,
+			// Create a single "torrent" entry for consistency
+			Torrents: []Torrent{{
+				Hash:             t.Infohash,
+				Quality:          "Full",
+				Size:             FormatBytes(t.SizeBytes),
+				SizeBytes:        t.SizeBytes,
+				Seeds:            t.Seeders,
+				Peers:            t.Leechers,
+				DateUploadedUnix: t.CreatedUnix,
+				Name:             t.Name,
+			}},
+		})
+	}
+
+	// Paginate
+	total := len(allMovies)
+	start := min((page-1)*perPage, total)
+	end := min(start+perPage, total)
+
+	return SearchResult{
+		Movies:     allMovies[start:end],
+		Total:      total,
+		Page:       page,
+		PerPage:    perPage,
+		TotalPages: totalPages(total, perPage),
+	}, nil
+}
+
+func (torrentsCSVProvider) Details(ctx context.Context, movie Movie) (*Movie, error) {
+	return &movie, nil
+}
+
+// getJSON fetches url from source and decodes the response into v.
+func (c *Client) getJSON(ctx context.Context, source SearchSource, url string, v any) error {
+	resp, err := c.httpGet(ctx, source, url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &UpstreamError{Source: source, Err: fmt.Errorf("%s returned status %d", source, resp.StatusCode)}
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,312 @@
+package core
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"c-cli/release"
+)
+
+// TorznabConfig describes one Torznab/Newznab indexer endpoint, a
+// [[torznab]] table in config.toml.
+type TorznabConfig struct {
+	Name       string `toml:"name"`
+	URL        string `toml:"url"` // Full API URL, e.g. http://localhost:9117/api/v2.0/indexers/all/results/torznab/api
+	APIKey     string `toml:"api_key"`
+	Categories []int  `toml:"categories"` // Optional category filter (2000 = movies, 5000 = TV)
+}
+
+// torznabProvider queries a Torznab-compatible indexer (Jackett, Prowlarr, ...).
+type torznabProvider struct {
+	name       string
+	baseURL    string
+	apiKey     string
+	categories []int
+	c          *Client
+}
+
+func newTorznabProvider(c *Client, cfg TorznabConfig) *torznabProvider {
+	return &torznabProvider{
+		name:       cfg.Name,
+		baseURL:    cfg.URL,
+		apiKey:     cfg.APIKey,
+		categories: cfg.Categories,
+		c:          c,
+	}
+}
+
+func (p *torznabProvider) Source() SearchSource { return SearchSource("torznab:" + p.name) }
+func (p *torznabProvider) Label() string        { return p.name + " (Torznab)" }
+
+func (p *torznabProvider) Capabilities() Capabilities {
+	return Capabilities{Paging: true, IMDBIDs: true}
+}
+
+func (p *torznabProvider) Details(ctx context.Context, movie Movie) (*Movie, error) {
+	return &movie, nil
+}
+
+var (
+	imdbIDPattern  = regexp.MustCompile(`^(?i)tt\d{7,}$`)
+	episodePattern = regexp.MustCompile(`(?i)^(.*?)\s*s(\d{1,2})(?:e(\d{1,3}))?\s*$`)
+)
+
+// torznabParams picks the search function for a query: an IMDb ID becomes a
+// t=movie lookup, "Show S01E02" becomes t=tvsearch, anything else t=search.
+func torznabParams(query string) url.Values {
+	params := url.Values{}
+	query = strings.TrimSpace(query)
+
+	if imdbIDPattern.MatchString(query) {
+		params.Set("t", "movie")
+		params.Set("imdbid", strings.TrimPrefix(strings.ToLower(query), "tt"))
+		return params
+	}
+
+	if m := episodePattern.FindStringSubmatch(query); m != nil && m[1] != "" {
+		params.Set("t", "tvsearch")
+		params.Set("q", m[1])
+		season, _ := strconv.Atoi(m[2])
+		params.Set("season", strconv.Itoa(season))
+		if m[3] != "" {
+			ep, _ := strconv.Atoi(m[3])
+			params.Set("ep", strconv.Itoa(ep))
+		}
+		return params
+	}
+
+	params.Set("t", "search")
+	params.Set("q", query)
+	return params
+}
+
+func (p *torznabProvider) Search(ctx context.Context, query string, page, perPage int) (SearchResult, error) {
+	params := torznabParams(query)
+	params.Set("apikey", p.apiKey)
+	params.Set("limit", strconv.Itoa(perPage))
+	params.Set("offset", strconv.Itoa((page-1)*perPage))
+	params.Set("extended", "1")
+	if len(p.categories) > 0 {
+		cats := make([]string, len(p.categories))
+		for i, c := range p.categories {
+			cats[i] = strconv.Itoa(c)
+		}
+		params.Set("cat", strings.Join(cats, ","))
+	}
+
+	sep := "?"
+	if strings.Contains(p.baseURL, "?") {
+		sep = "&"
+	}
+	resp, err := p.c.httpGet(ctx, p.Source(), p.baseURL+sep+params.Encode())
+	if err != nil {
+		return SearchResult{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return SearchResult{}, &UpstreamError{Source: p.Source(), Err: fmt.Errorf("%s: indexer returned status %d", p.name, resp.StatusCode)}
+	}
+
+	feed, err := parseTorznabFeed(resp.Body)
+	if err != nil {
+		return SearchResult{}, fmt.Errorf("%s: %w", p.name, err)
+	}
+
+	movies := make([]Movie, 0, len(feed.Channel.Items))
+	for _, item := range feed.Channel.Items {
+		m := item.toMovie()
+		m.Source = p.Source()
+		movies = append(movies, m)
+	}
+
+	// Indexers that omit torznab:response get a total that still allows "next page"
+	total := feed.Channel.Response.Total
+	if total == 0 {
+		total = (page-1)*perPage + len(movies)
+		if len(movies) == perPage {
+			total++
+		}
+	}
+
+	return SearchResult{
+		Movies:     movies,
+		Total:      total,
+		Page:       page,
+		PerPage:    perPage,
+		TotalPages: totalPages(total, perPage),
+	}, nil
+}
+
+// Torznab RSS feed types. Attribute elements are matched by local name so
+// both the torznab: and newznab: namespaces decode into Attrs.
+type torznabFeed struct {
+	XMLName xml.Name `xml:"rss"`
+	Channel struct {
+		Response struct {
+			Offset int `xml:"offset,attr"`
+			Total  int `xml:"total,attr"`
+		} `xml:"response"`
+		Items []torznabItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type torznabError struct {
+	XMLName     xml.Name `xml:"error"`
+	Code        int      `xml:"code,attr"`
+	Description string   `xml:"description,attr"`
+}
+
+type torznabItem struct {
+	Title     string `xml:"title"`
+	GUID      string `xml:"guid"`
+	Link      string `xml:"link"`
+	Size      int64  `xml:"size"`
+	Enclosure struct {
+		URL    string `xml:"url,attr"`
+		Length int64  `xml:"length,attr"`
+	} `xml:"enclosure"`
+	Attrs []struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:"value,attr"`
+	} `xml:"attr"`
+}
+
+func parseTorznabFeed(r io.Reader) (*torznabFeed, error) {
+	dec := xml.NewDecoder(r)
+	// Indexers sometimes declare non-UTF-8 charsets for plain ASCII feeds
+	dec.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) { return input, nil }
+
+	// Peek at the root element: errors come back as <error code="" description=""/>
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("invalid torznab response: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "error":
+			var e torznabError
+			if err := dec.DecodeElement(&e, &start); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("indexer error %d: %s", e.Code, e.Description)
+		case "rss":
+			var feed torznabFeed
+			if err := dec.DecodeElement(&feed, &start); err != nil {
+				return nil, err
+			}
+			return &feed, nil
+		default:
+			return nil, fmt.Errorf("unexpected torznab root element <%s>", start.Name.Local)
+		}
+	}
+}
+
+func (it torznabItem) attr(name string) string {
+	for _, a := range it.Attrs {
+		if strings.EqualFold(a.Name, name) {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func (it torznabItem) attrInt(name string) int {
+	n, _ := strconv.Atoi(it.attr(name))
+	return n
+}
+
+// toMovie maps a feed item onto a single-torrent Movie.
+func (it torznabItem) toMovie() Movie {
+	size := it.Size
+	if size == 0 {
+		size = it.Enclosure.Length
+	}
+	if s, err := strconv.ParseInt(it.attr("size"), 10, 64); err == nil && size == 0 {
+		size = s
+	}
+
+	// torznab "peers" counts seeders and leechers together
+	seeders := it.attrInt("seeders")
+	leechers := it.attrInt("peers") - seeders
+	if leechers < 0 {
+		leechers = 0
+	}
+	if l := it.attrInt("leechers"); l > 0 {
+		leechers = l
+	}
+
+	downloadURL := it.Enclosure.URL
+	if downloadURL == "" {
+		downloadURL = it.Link
+	}
+	magnetURL := it.attr("magneturl")
+	if strings.HasPrefix(downloadURL, "magnet:") {
+		magnetURL = downloadURL
+		downloadURL = ""
+	}
+
+	hash := strings.ToLower(it.attr("infohash"))
+	if hash == "" && magnetURL != "" {
+		hash = infohashFromMagnet(magnetURL)
+	}
+
+	imdbCode := it.attr("imdbid")
+	if imdbCode == "" {
+		imdbCode = it.attr("imdb")
+	}
+	if imdbCode != "" && !strings.HasPrefix(imdbCode, "tt") {
+		imdbCode = fmt.Sprintf("tt%07s", imdbCode)
+	}
+
+	rel := release.Parse(it.Title)
+	quality := rel.Resolution
+	if quality == "" {
+		quality = "Full"
+	}
+
+	return Movie{
+		Title:    rel.Name(),
+		Year:     rel.Year,
+		IMDBCode: imdbCode,
+		Infohash: hash,
+		Size:     FormatBytes(size),
+		Seeders:  seeders,
+		Leechers: leechers,
+		Torrents: []Torrent{{
+			URL:       downloadURL,
+			Hash:      hash,
+			Quality:   quality,
+			Size:      FormatBytes(size),
+			SizeBytes: size,
+			Seeds:     seeders,
+			Peers:     leechers,
+			Name:      it.Title,
+		}},
+	}
+}
+
+// infohashFromMagnet extracts the btih infohash from a magnet URI.
+func infohashFromMagnet(magnet string) string {
+	u, err := url.Parse(magnet)
+	if err != nil {
+		return ""
+	}
+	for _, xt := range u.Query()["xt"] {
+		if strings.HasPrefix(xt, "urn:btih:") {
+			return strings.ToLower(strings.TrimPrefix(xt, "urn:btih:"))
+		}
+	}
+	return ""
+}
@@ -0,0 +1,145 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// Abridged from Jackett's aggregate "all" indexer.
+const jackettFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom" xmlns:torznab="http://torznab.com/schemas/2015/feed">
+  <channel>
+    <atom:link href="http://127.0.0.1:9117/" rel="self" type="application/rss+xml" />
+    <title>AggregateSearch</title>
+    <item>
+      <title>The.Shawshank.Redemption.1994.1080p.BluRay.x264-AMIABLE</title>
+      <guid>http://127.0.0.1:9117/dl/1337x/?jackett_apikey=k&amp;path=abc</guid>
+      <jackettindexer id="1337x">1337x</jackettindexer>
+      <link>http://127.0.0.1:9117/dl/1337x/?jackett_apikey=k&amp;path=abc&amp;file=x</link>
+      <size>10468982784</size>
+      <enclosure url="http://127.0.0.1:9117/dl/1337x/?jackett_apikey=k&amp;path=abc&amp;file=x" length="10468982784" type="application/x-bittorrent" />
+      <torznab:attr name="category" value="2040" />
+      <torznab:attr name="seeders" value="812" />
+      <torznab:attr name="peers" value="860" />
+      <torznab:attr name="infohash" value="0123456789ABCDEF0123456789ABCDEF01234567" />
+      <torznab:attr name="imdb" value="0111161" />
+    </item>
+  </channel>
+</rss>`
+
+// Abridged from a Prowlarr indexer feed, which links magnets and reports
+// leechers in "peers" the same way.
+const prowlarrFeed = `<?xml version="1.0" encoding="utf-8"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom" xmlns:torznab="http://torznab.com/schemas/2015/feed">
+  <channel>
+    <title>Prowlarr</title>
+    <torznab:response offset="0" total="1" />
+    <item>
+      <title>Breaking.Bad.S01E05.720p.BluRay.x264-DEMAND</title>
+      <guid>magnet:?xt=urn:btih:89ABCDEF0123456789ABCDEF0123456789ABCDEF</guid>
+      <link>magnet:?xt=urn:btih:89ABCDEF0123456789ABCDEF0123456789ABCDEF&amp;dn=Breaking.Bad</link>
+      <size>1395864371</size>
+      <torznab:attr name="seeders" value="45" />
+      <torznab:attr name="peers" value="51" />
+      <torznab:attr name="imdbid" value="tt0903747" />
+    </item>
+  </channel>
+</rss>`
+
+// indexer serves feed and records the query of the last request.
+func indexer(t *testing.T, feed string) (apiURL string, last *url.Values) {
+	t.Helper()
+	last = new(url.Values)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*last = r.URL.Query()
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(feed))
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL + "/api/v2.0/indexers/all/results/torznab/api", last
+}
+
+func searchTorznab(t *testing.T, feed, query string) (Movie, url.Values) {
+	t.Helper()
+	apiURL, last := indexer(t, feed)
+	c := New(Options{Torznab: []TorznabConfig{{Name: "test", URL: apiURL, APIKey: "k", Categories: []int{2000, 5000}}}})
+	res, err := c.Search(context.Background(), query, 1, 20, "torznab:test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Movies) != 1 {
+		t.Fatalf("got %d results, want 1", len(res.Movies))
+	}
+	return res.Movies[0], *last
+}
+
+func TestTorznabJackett(t *testing.T) {
+	m, _ := searchTorznab(t, jackettFeed, "shawshank")
+	if m.Seeders != 812 || m.Leechers != 860-812 {
+		t.Errorf("seeders/leechers = %d/%d, want 812/48", m.Seeders, m.Leechers)
+	}
+	if m.Infohash != "0123456789abcdef0123456789abcdef01234567" {
+		t.Errorf("infohash = %q", m.Infohash)
+	}
+	if m.IMDBCode != "tt0111161" {
+		t.Errorf("imdb = %q, want tt0111161", m.IMDBCode)
+	}
+	if m.Year != 1994 || m.Source != "torznab:test" {
+		t.Errorf("year, source = %d, %q", m.Year, m.Source)
+	}
+	tor := m.Torrents[0]
+	if tor.SizeBytes != 10468982784 || tor.Quality != "1080p" || tor.URL == "" {
+		t.Errorf("torrent = %+v", tor)
+	}
+}
+
+func TestTorznabProwlarr(t *testing.T) {
+	m, _ := searchTorznab(t, prowlarrFeed, "breaking bad")
+	if m.Seeders != 45 || m.Leechers != 6 {
+		t.Errorf("seeders/leechers = %d/%d, want 45/6", m.Seeders, m.Leechers)
+	}
+	if m.Infohash != "89abcdef0123456789abcdef0123456789abcdef" {
+		t.Errorf("infohash = %q, want the magnet's", m.Infohash)
+	}
+	if m.IMDBCode != "tt0903747" {
+		t.Errorf("imdb = %q, want tt0903747", m.IMDBCode)
+	}
+	// A magnet link is not a .torrent download
+	if tor := m.Torrents[0]; tor.URL != "" || tor.SizeBytes != 1395864371 {
+		t.Errorf("torrent = %+v", tor)
+	}
+}
+
+func TestTorznabQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  map[string]string
+	}{
+		{"tt0111161", map[string]string{"t": "movie", "imdbid": "0111161", "q": ""}},
+		{"Breaking Bad S01E05", map[string]string{"t": "tvsearch", "q": "Breaking Bad", "season": "1", "ep": "5"}},
+		{"The Wire s3", map[string]string{"t": "tvsearch", "q": "The Wire", "season": "3", "ep": ""}},
+		{"blade runner 2049", map[string]string{"t": "search", "q": "blade runner 2049"}},
+	}
+	for _, tt := range tests {
+		_, q := searchTorznab(t, jackettFeed, tt.query)
+		for k, v := range tt.want {
+			if q.Get(k) != v {
+				t.Errorf("%q: %s = %q, want %q", tt.query, k, q.Get(k), v)
+			}
+		}
+		if q.Get("apikey") != "k" || q.Get("cat") != "2000,5000" || q.Get("limit") != "20" || q.Get("offset") != "0" {
+			t.Errorf("%q: query %v is missing the key, categories or paging", tt.query, q)
+		}
+	}
+}
+
+func TestTorznabError(t *testing.T) {
+	apiURL, _ := indexer(t, `<?xml version="1.0" encoding="UTF-8"?><error code="100" description="Invalid API Key" />`)
+	c := New(Options{Torznab: []TorznabConfig{{Name: "test", URL: apiURL}}})
+	if _, err := c.Search(context.Background(), "x", 1, 20, "torznab:test"); err == nil {
+		t.Error("indexer error was not reported")
+	}
+}
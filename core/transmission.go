@@ -0,0 +1,172 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const transmissionSessionHeader = "X-Transmission-Session-Id"
+
+// TransmissionConfig is the [transmission] section of config.toml, or the
+// TRANSMISSION_* variables for c-cli-web.
+type TransmissionConfig struct {
+	URL         string   `toml:"url"` // RPC endpoint, e.g. http://localhost:9091/transmission/rpc
+	Username    string   `toml:"username"`
+	Password    string   `toml:"password"`
+	DownloadDir string   `toml:"download_dir"`
+	Labels      []string `toml:"labels"` // Transmission 3.00+
+}
+
+// transmissionClient speaks the Transmission JSON-RPC protocol.
+type transmissionClient struct {
+	rpcURL   string
+	username string
+	password string
+	defaults AddOptions
+	client   *http.Client
+
+	mu        sync.Mutex
+	sessionID string
+}
+
+func newTransmissionClient(cfg TransmissionConfig) (DownloadClient, error) {
+	rpcURL := strings.TrimRight(cfg.URL, "/")
+	if rpcURL == "" {
+		rpcURL = "http://localhost:9091/transmission/rpc"
+	} else if !strings.HasSuffix(rpcURL, "/rpc") {
+		rpcURL += "/transmission/rpc"
+	}
+	return &transmissionClient{
+		rpcURL:   rpcURL,
+		username: cfg.Username,
+		password: cfg.Password,
+		defaults: AddOptions{SavePath: cfg.DownloadDir, Tags: cfg.Labels},
+		client:   &http.Client{Timeout: httpClient.Timeout},
+	}, nil
+}
+
+func (c *transmissionClient) Name() string { return "Transmission" }
+
+type transmissionRequest struct {
+	Method    string      `json:"method"`
+	Arguments interface{} `json:"arguments,omitempty"`
+}
+
+type transmissionResponse struct {
+	Result    string          `json:"result"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type transmissionTorrent struct {
+	ID         int    `json:"id"`
+	HashString string `json:"hashString"`
+	Name       string `json:"name"`
+}
+
+func (c *transmissionClient) Add(ctx context.Context, g Grab, opts AddOptions) (string, error) {
+	opts = mergeOptions(opts, c.defaults)
+
+	args := map[string]interface{}{}
+	if len(g.Torrent) > 0 {
+		args["metainfo"] = base64.StdEncoding.EncodeToString(g.Torrent)
+	} else {
+		args["filename"] = g.Magnet
+	}
+	if opts.SavePath != "" {
+		args["download-dir"] = opts.SavePath
+	}
+	labels := opts.Tags
+	if opts.Category != "" {
+		labels = append([]string{opts.Category}, labels...)
+	}
+	if len(labels) > 0 {
+		args["labels"] = labels
+	}
+
+	var result struct {
+		Added     *transmissionTorrent `json:"torrent-added"`
+		Duplicate *transmissionTorrent `json:"torrent-duplicate"`
+	}
+	if err := c.call(ctx, "torrent-add", args, &result); err != nil {
+		return "", err
+	}
+
+	switch {
+	case result.Added != nil:
+		return result.Added.HashString, nil
+	case result.Duplicate != nil:
+		return "", fmt.Errorf("torrent already added: %s", result.Duplicate.Name)
+	default:
+		return strings.ToLower(g.Hash), nil
+	}
+}
+
+// call performs an RPC, completing the session-ID handshake when Transmission
+// answers 409 Conflict, and decodes the response arguments into out.
+func (c *transmissionClient) call(ctx context.Context, method string, args interface{}, out interface{}) error {
+	body, err := json.Marshal(transmissionRequest{Method: method, Arguments: args})
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for attempt := 0; attempt < 2; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, "POST", c.rpcURL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if c.sessionID != "" {
+			req.Header.Set(transmissionSessionHeader, c.sessionID)
+		}
+		if c.username != "" {
+			req.SetBasicAuth(c.username, c.password)
+		}
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return err
+		}
+
+		switch resp.StatusCode {
+		case http.StatusConflict:
+			// Session expired or first request: retry with the ID Transmission hands out
+			c.sessionID = resp.Header.Get(transmissionSessionHeader)
+			resp.Body.Close()
+			if c.sessionID == "" {
+				return fmt.Errorf("409 without %s header", transmissionSessionHeader)
+			}
+			continue
+		case http.StatusUnauthorized:
+			resp.Body.Close()
+			return fmt.Errorf("authentication failed: check username and password")
+		case http.StatusOK:
+		default:
+			resp.Body.Close()
+			return fmt.Errorf("rpc failed with status: %d", resp.StatusCode)
+		}
+
+		var rpcResp transmissionResponse
+		err = json.NewDecoder(resp.Body).Decode(&rpcResp)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("invalid rpc response: %w", err)
+		}
+		if rpcResp.Result != "success" {
+			return fmt.Errorf("%s", rpcResp.Result)
+		}
+		if out != nil && len(rpcResp.Arguments) > 0 {
+			return json.Unmarshal(rpcResp.Arguments, out)
+		}
+		return nil
+	}
+	return fmt.Errorf("could not establish a Transmission RPC session")
+}
@@ -0,0 +1,110 @@
+package core
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// transmissiond is a stand-in Transmission daemon. It answers 409 until the
+// session ID it hands out is sent back, then records torrent-add arguments.
+type transmissiond struct {
+	session   string // Empty to answer 409 without the header
+	duplicate bool   // Answer torrent-add with torrent-duplicate
+
+	requests int
+	args     map[string]interface{}
+}
+
+func (d *transmissiond) start(t *testing.T) *transmissionClient {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d.requests++
+		if d.session == "" || r.Header.Get(transmissionSessionHeader) != d.session {
+			if d.session != "" {
+				w.Header().Set(transmissionSessionHeader, d.session)
+			}
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		var req struct {
+			Method    string                 `json:"method"`
+			Arguments map[string]interface{} `json:"arguments"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method != "torrent-add" {
+			t.Errorf("request = %+v, %v; want torrent-add", req, err)
+		}
+		d.args = req.Arguments
+
+		added := map[string]interface{}{"id": 1, "hashString": "abc", "name": "Movie"}
+		key := "torrent-added"
+		if d.duplicate {
+			key = "torrent-duplicate"
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"result":    "success",
+			"arguments": map[string]interface{}{key: added},
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	dl, err := newTransmissionClient(TransmissionConfig{URL: srv.URL + "/transmission/rpc", Labels: []string{"c-cli"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dl.(*transmissionClient)
+}
+
+func TestTransmissionAdd(t *testing.T) {
+	torrent := []byte("d4:infode")
+	tests := []struct {
+		name string
+		g    Grab
+		want map[string]interface{}
+	}{
+		{"magnet", Grab{Name: "Movie", Magnet: "magnet:?xt=urn:btih:abc"}, map[string]interface{}{
+			"filename": "magnet:?xt=urn:btih:abc",
+			"labels":   []interface{}{"movies", "c-cli"},
+		}},
+		{"metainfo", Grab{Name: "Movie", Magnet: "magnet:?xt=urn:btih:abc", Torrent: torrent}, map[string]interface{}{
+			"metainfo": base64.StdEncoding.EncodeToString(torrent),
+			"labels":   []interface{}{"movies", "c-cli"},
+		}},
+	}
+	for _, tt := range tests {
+		d := &transmissiond{session: "s1"}
+		dl := d.start(t)
+		id, err := dl.Add(context.Background(), tt.g, AddOptions{Category: "movies"})
+		if err != nil || id != "abc" {
+			t.Errorf("%s: Add = %q, %v; want abc", tt.name, id, err)
+		}
+		if !reflect.DeepEqual(d.args, tt.want) {
+			t.Errorf("%s: arguments = %v, want %v", tt.name, d.args, tt.want)
+		}
+		// The first request learns the session ID from the 409
+		if d.requests != 2 || dl.sessionID != "s1" {
+			t.Errorf("%s: %d requests with session %q, want 2 with s1", tt.name, d.requests, dl.sessionID)
+		}
+	}
+}
+
+func TestTransmissionDuplicate(t *testing.T) {
+	d := &transmissiond{session: "s1", duplicate: true}
+	_, err := d.start(t).Add(context.Background(), Grab{Magnet: "magnet:?xt=urn:btih:abc"}, AddOptions{})
+	if err == nil || !strings.Contains(err.Error(), "already added: Movie") {
+		t.Errorf("err = %v, want already added", err)
+	}
+}
+
+func TestTransmissionConflictWithoutSession(t *testing.T) {
+	d := &transmissiond{}
+	_, err := d.start(t).Add(context.Background(), Grab{Magnet: "magnet:?xt=urn:btih:abc"}, AddOptions{})
+	if err == nil || !strings.Contains(err.Error(), transmissionSessionHeader) {
+		t.Errorf("err = %v, want the missing header reported", err)
+	}
+}
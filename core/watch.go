@@ -0,0 +1,74 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// watchClient drops grabs into a directory watched by a BitTorrent client
+// (Transmission, rTorrent, Vuze, Synology Download Station, ...).
+type watchClient struct {
+	dir string
+	c   *Client
+}
+
+func newWatchClient(c *Client, dir string) (DownloadClient, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("watch: a watch directory is required")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("watch: %w", err)
+	}
+	return &watchClient{dir: dir, c: c}, nil
+}
+
+func (c *watchClient) Name() string { return "watch folder" }
+
+// MagnetFallback is returned along with the .magnet path when the .torrent
+// for an infohash-only grab couldn't be fetched. The grab was still written.
+type MagnetFallback struct {
+	Err error // Why the .torrent fetch failed
+}
+
+func (e *MagnetFallback) Error() string {
+	return "wrote a .magnet instead of the .torrent: " + e.Err.Error()
+}
+
+func (e *MagnetFallback) Unwrap() error { return e.Err }
+
+// Add writes the grab's .torrent and returns the path written. For
+// infohash-only results the .torrent is fetched from the swarm or a cache
+// service; when that fails a .magnet is written and returned with a
+// *MagnetFallback saying why. Per-grab options are left to the watching
+// client.
+func (c *watchClient) Add(ctx context.Context, g Grab, opts AddOptions) (string, error) {
+	data := g.Torrent
+	var fetchErr error
+	if len(data) == 0 && g.Hash != "" {
+		data, fetchErr = c.c.FetchTorrentByHash(ctx, g.Hash)
+		if fetchErr != nil && ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+	}
+
+	name := SanitizeFilename(g.Name)
+	if name == "" {
+		name = g.Hash
+	}
+
+	path := filepath.Join(c.dir, name+".torrent")
+	if len(data) == 0 {
+		path = filepath.Join(c.dir, name+".magnet")
+		data = []byte(g.Magnet)
+	}
+
+	if err := WriteFileAtomic(path, data, 0644); err != nil {
+		return "", err
+	}
+	if fetchErr != nil {
+		return path, &MagnetFallback{Err: fetchErr}
+	}
+	return path, nil
+}
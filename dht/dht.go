@@ -0,0 +1,231 @@
+// Package dht looks up peers for an infohash on the BitTorrent mainline DHT
+// (BEP 5). It is a client only: it never answers queries or stores peers.
+package dht
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"sort"
+	"time"
+
+	"c-cli/bencode"
+)
+
+// DefaultBootstrap are well-known routers used to enter the DHT.
+var DefaultBootstrap = []string{
+	"router.bittorrent.com:6881",
+	"dht.transmissionbt.com:6881",
+	"router.utorrent.com:6881",
+	"dht.libtorrent.org:25401",
+}
+
+const (
+	alpha        = 8               // Queries in flight
+	queryTimeout = 2 * time.Second // Before a node is written off
+	maxQueried   = 200             // Nodes contacted per lookup
+)
+
+type node struct {
+	id   [20]byte
+	addr netip.AddrPort
+}
+
+// GetPeers walks the DHT towards infohash with get_peers queries and returns
+// the peers found, stopping after maxPeers or when ctx is done. Results are
+// returned even if the lookup ends early.
+func GetPeers(ctx context.Context, infohash [20]byte, bootstrap []string, maxPeers int) ([]netip.AddrPort, error) {
+	if len(bootstrap) == 0 {
+		bootstrap = DefaultBootstrap
+	}
+
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, fmt.Errorf("dht: %w", err)
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	var selfID [20]byte
+	rand.Read(selfID[:])
+
+	l := &lookup{
+		conn:     conn,
+		selfID:   selfID,
+		target:   infohash,
+		maxPeers: maxPeers,
+		pending:  map[string]time.Time{},
+		queried:  map[netip.AddrPort]bool{},
+		seenPeer: map[netip.AddrPort]bool{},
+	}
+
+	// Bootstrap routers have unknown IDs; query them directly
+	for _, host := range bootstrap {
+		addr, err := net.ResolveUDPAddr("udp4", host)
+		if err != nil {
+			continue
+		}
+		l.query(addr.AddrPort())
+	}
+	if len(l.pending) == 0 {
+		return nil, errors.New("dht: no bootstrap node reachable")
+	}
+
+	buf := make([]byte, 4096)
+	for len(l.peers) < l.maxPeers {
+		l.expire()
+		for len(l.pending) < alpha && len(l.candidates) > 0 && len(l.queried) < maxQueried {
+			next := l.candidates[0]
+			l.candidates = l.candidates[1:]
+			l.query(next.addr)
+		}
+		if len(l.pending) == 0 {
+			break // Exhausted
+		}
+
+		conn.SetReadDeadline(time.Now().Add(queryTimeout / 4))
+		n, _, err := conn.ReadFromUDPAddrPort(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				continue
+			}
+			return l.peers, fmt.Errorf("dht: %w", err)
+		}
+		l.handle(buf[:n])
+	}
+
+	if len(l.peers) == 0 && ctx.Err() != nil {
+		return nil, fmt.Errorf("dht: %w", ctx.Err())
+	}
+	return l.peers, nil
+}
+
+// lookup is the state of one iterative get_peers search.
+type lookup struct {
+	conn       *net.UDPConn
+	selfID     [20]byte
+	target     [20]byte
+	maxPeers   int
+	nextTID    uint16
+	pending    map[string]time.Time // Transaction ID -> sent
+	queried    map[netip.AddrPort]bool
+	candidates []node // Closest to target first
+	peers      []netip.AddrPort
+	seenPeer   map[netip.AddrPort]bool
+}
+
+func (l *lookup) query(addr netip.AddrPort) {
+	if l.queried[addr] {
+		return
+	}
+	l.queried[addr] = true
+
+	l.nextTID++
+	tid := string(binary.BigEndian.AppendUint16(nil, l.nextTID))
+	msg, err := bencode.Encode(map[string]interface{}{
+		"t": tid,
+		"y": "q",
+		"q": "get_peers",
+		"a": map[string]interface{}{
+			"id":        string(l.selfID[:]),
+			"info_hash": string(l.target[:]),
+		},
+	})
+	if err != nil {
+		return
+	}
+	if _, err := l.conn.WriteToUDPAddrPort(msg, addr); err == nil {
+		l.pending[tid] = time.Now()
+	}
+}
+
+func (l *lookup) expire() {
+	for tid, sent := range l.pending {
+		if time.Since(sent) > queryTimeout {
+			delete(l.pending, tid)
+		}
+	}
+}
+
+func (l *lookup) handle(packet []byte) {
+	v, err := bencode.Decode(packet)
+	if err != nil {
+		return
+	}
+	msg, _ := v.(map[string]interface{})
+	tid, _ := msg["t"].(string)
+	if _, ok := l.pending[tid]; !ok {
+		return
+	}
+	delete(l.pending, tid)
+
+	r, _ := msg["r"].(map[string]interface{})
+	if r == nil {
+		return // Error reply
+	}
+
+	if values, ok := r["values"].([]interface{}); ok {
+		for _, v := range values {
+			if len(l.peers) >= l.maxPeers {
+				break
+			}
+			s, _ := v.(string)
+			if len(s) != 6 && len(s) != 18 {
+				continue
+			}
+			addr, ok := netip.AddrFromSlice([]byte(s[:len(s)-2]))
+			port := binary.BigEndian.Uint16([]byte(s[len(s)-2:]))
+			if !ok || port == 0 {
+				continue
+			}
+			peer := netip.AddrPortFrom(addr.Unmap(), port)
+			if !l.seenPeer[peer] {
+				l.seenPeer[peer] = true
+				l.peers = append(l.peers, peer)
+			}
+		}
+	}
+
+	// Compact node info: 20-byte ID, 4-byte IPv4, 2-byte port
+	if nodes, ok := r["nodes"].(string); ok {
+		for i := 0; i+26 <= len(nodes); i += 26 {
+			var n node
+			copy(n.id[:], nodes[i:i+20])
+			addr, _ := netip.AddrFromSlice([]byte(nodes[i+20 : i+24]))
+			port := binary.BigEndian.Uint16([]byte(nodes[i+24 : i+26]))
+			if port == 0 || !addr.IsValid() || addr.IsUnspecified() {
+				continue
+			}
+			n.addr = netip.AddrPortFrom(addr, port)
+			if !l.queried[n.addr] {
+				l.candidates = append(l.candidates, n)
+			}
+		}
+		sort.Slice(l.candidates, func(i, j int) bool {
+			return closer(l.candidates[i].id, l.candidates[j].id, l.target)
+		})
+		if len(l.candidates) > maxQueried {
+			l.candidates = l.candidates[:maxQueried]
+		}
+	}
+}
+
+// closer reports whether a is XOR-closer to target than b.
+func closer(a, b, target [20]byte) bool {
+	var da, db [20]byte
+	for i := range target {
+		da[i] = a[i] ^ target[i]
+		db[i] = b[i] ^ target[i]
+	}
+	return bytes.Compare(da[:], db[:]) < 0
+}
@@ -0,0 +1,169 @@
+package dht
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+
+	"c-cli/bencode"
+)
+
+// reply bencodes a get_peers response to transaction tid.
+func reply(t *testing.T, tid string, r map[string]interface{}) []byte {
+	t.Helper()
+	msg, err := bencode.Encode(map[string]interface{}{"t": tid, "y": "r", "r": r})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+// compactNode is a 26-byte compact node info entry.
+func compactNode(id byte, addr string) string {
+	ap := netip.MustParseAddrPort(addr)
+	b := id20(id)
+	ip := ap.Addr().As4()
+	entry := append(b[:], ip[:]...)
+	return string(binary.BigEndian.AppendUint16(entry, ap.Port()))
+}
+
+// id20 is a node ID of 19 zero bytes after b.
+func id20(b byte) [20]byte { return [20]byte{b} }
+
+func newLookup(maxPeers int) *lookup {
+	return &lookup{
+		maxPeers: maxPeers,
+		pending:  map[string]time.Time{"aa": time.Now()},
+		queried:  map[netip.AddrPort]bool{},
+		seenPeer: map[netip.AddrPort]bool{},
+	}
+}
+
+func TestHandleValues(t *testing.T) {
+	l := newLookup(10)
+	l.handle(reply(t, "aa", map[string]interface{}{
+		"id": string(make([]byte, 20)),
+		"values": []interface{}{
+			"\x0a\x00\x00\x01\x1a\xe1",                                     // 10.0.0.1:6881
+			"\x0a\x00\x00\x01\x1a\xe1",                                     // Duplicate
+			"\x0a\x00\x00\x02\x00\x00",                                     // Port 0
+			"\x0a\x00\x00\x03\x1a",                                         // Truncated
+			"\x20\x01\x0d\xb8" + string(make([]byte, 11)) + "\x01\x1a\xe2", // [2001:db8::1]:6882
+			int64(7),
+		},
+	}))
+	want := []netip.AddrPort{
+		netip.MustParseAddrPort("10.0.0.1:6881"),
+		netip.MustParseAddrPort("[2001:db8::1]:6882"),
+	}
+	if !reflect.DeepEqual(l.peers, want) {
+		t.Errorf("peers = %v, want %v", l.peers, want)
+	}
+	if len(l.pending) != 0 {
+		t.Error("the transaction is still pending")
+	}
+
+	// Replies to transactions we didn't send are dropped
+	l.handle(reply(t, "zz", map[string]interface{}{"values": []interface{}{"\x0a\x00\x00\x09\x1a\xe1"}}))
+	if len(l.peers) != 2 {
+		t.Errorf("peers = %v after an unsolicited reply", l.peers)
+	}
+}
+
+func TestHandleValuesMaxPeers(t *testing.T) {
+	l := newLookup(2)
+	l.handle(reply(t, "aa", map[string]interface{}{"values": []interface{}{
+		"\x0a\x00\x00\x01\x1a\xe1",
+		"\x0a\x00\x00\x02\x1a\xe1",
+		"\x0a\x00\x00\x03\x1a\xe1",
+	}}))
+	if len(l.peers) != 2 {
+		t.Errorf("peers = %v, want the first 2", l.peers)
+	}
+}
+
+func TestHandleNodes(t *testing.T) {
+	l := newLookup(10)
+	l.target = id20(0x10)
+	l.queried[netip.MustParseAddrPort("10.0.0.4:6881")] = true
+	nodes := compactNode(0x80, "10.0.0.1:6881") +
+		compactNode(0x11, "10.0.0.2:6881") +
+		compactNode(0x30, "10.0.0.3:6881") +
+		compactNode(0x10, "10.0.0.4:6881") + // Already queried
+		compactNode(0x10, "10.0.0.5:0") + // Port 0
+		compactNode(0x10, "0.0.0.0:6881") + // Unspecified
+		"\x01\x02" // Trailing partial entry
+	l.handle(reply(t, "aa", map[string]interface{}{"nodes": nodes}))
+
+	var got []string
+	for _, n := range l.candidates {
+		got = append(got, n.addr.String())
+	}
+	want := []string{"10.0.0.2:6881", "10.0.0.3:6881", "10.0.0.1:6881"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("candidates = %v, want %v closest first", got, want)
+	}
+}
+
+func TestCloser(t *testing.T) {
+	target := [20]byte{0xf0, 0x0f}
+	tests := []struct {
+		a, b [20]byte
+		want bool
+	}{
+		{target, [20]byte{0xf0, 0x0e}, true},
+		{[20]byte{0xf0, 0x0e}, target, false},
+		{target, target, false},
+		{[20]byte{0xf1}, [20]byte{0xf0, 0xff}, false}, // The first differing byte decides
+		{[20]byte{0xe0}, [20]byte{0x70}, true},
+		{[20]byte{0xf0, 0x0f, 0x01}, [20]byte{0xf0, 0x0f, 0x02}, true},
+	}
+	for _, tt := range tests {
+		if got := closer(tt.a, tt.b, target); got != tt.want {
+			t.Errorf("closer(%x, %x) = %v, want %v", tt.a[:3], tt.b[:3], got, tt.want)
+		}
+	}
+}
+
+func TestGetPeers(t *testing.T) {
+	// A stand-in router that answers every get_peers with five peers
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, from, err := conn.ReadFromUDPAddrPort(buf)
+			if err != nil {
+				return
+			}
+			v, err := bencode.Decode(buf[:n])
+			if err != nil {
+				continue
+			}
+			tid, _ := v.(map[string]interface{})["t"].(string)
+			var values []interface{}
+			for i := byte(1); i <= 5; i++ {
+				values = append(values, string([]byte{10, 0, 0, i, 0x1a, 0xe1}))
+			}
+			msg, _ := bencode.Encode(map[string]interface{}{"t": tid, "y": "r", "r": map[string]interface{}{"values": values}})
+			conn.WriteToUDPAddrPort(msg, from)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	peers, err := GetPeers(ctx, id20(1), []string{conn.LocalAddr().String()}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 3 || peers[0] != netip.MustParseAddrPort("10.0.0.1:6881") {
+		t.Errorf("peers = %v, want the first 3", peers)
+	}
+}
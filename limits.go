@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"c-cli/throttle"
+)
+
+// omdbLimits keep lookups under the rate at which OMDB starts refusing
+// requests, well before the daily quota is at risk.
+var omdbLimits = throttle.Limits{Concurrency: 4, Rate: 5, Burst: 5, Retries: 2, Timeout: 10 * time.Second}
+
+// providerLimits apply to each search provider: YTS, Torrents-CSV and every
+// Torznab indexer.
+var providerLimits = throttle.Limits{Concurrency: 2, Rate: 2, Burst: 4, Retries: 2, Timeout: 15 * time.Second}
+
+// LimitsConfig overrides the request limits for one service ("omdb" or a
+// search source). Zero fields keep the defaults; retries = -1 disables
+// retrying.
+type LimitsConfig struct {
+	Concurrency int           `toml:"concurrency"` // Requests in flight
+	Rate        float64       `toml:"rate"`        // Requests started per second
+	Burst       int           `toml:"burst"`
+	Retries     int           `toml:"retries"` // After a 5xx, 429 or timeout
+	Timeout     time.Duration `toml:"timeout"` // Per attempt, e.g. "10s"
+}
+
+// limitsFor returns the limits for service, with any configured overrides.
+func limitsFor(service string) throttle.Limits {
+	l := providerLimits
+	if service == "omdb" {
+		l = omdbLimits
+	}
+	c := config.Limits[service]
+	if c.Concurrency > 0 {
+		l.Concurrency = c.Concurrency
+	}
+	if c.Rate > 0 {
+		l.Rate = c.Rate
+	}
+	if c.Burst > 0 {
+		l.Burst = c.Burst
+	}
+	if c.Retries != 0 {
+		l.Retries = max(c.Retries, 0)
+	}
+	if c.Timeout > 0 {
+		l.Timeout = c.Timeout
+	}
+	return l
+}
+
+var (
+	limitedClientsMu sync.Mutex
+	limitedClients   = map[string]*http.Client{}
+)
+
+// limitedClient returns the HTTP client for requests to service, shared by
+// everything that talks to it so its limits hold across searches.
+func limitedClient(service string) *http.Client {
+	limitedClientsMu.Lock()
+	defer limitedClientsMu.Unlock()
+	c, ok := limitedClients[service]
+	if !ok {
+		c = throttle.Client(throttle.New(limitsFor(service)))
+		limitedClients[service] = c
+	}
+	return c
+}
+
+// enrichPool runs OMDB enrichment for all searches, one worker per OMDB
+// request allowed in flight.
+var enrichPool = sync.OnceValue(func() *throttle.Pool {
+	return throttle.NewPool(limitsFor("omdb").Concurrency)
+})
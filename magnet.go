@@ -1,101 +1,55 @@
 package main
 
 import (
-	"fmt"
-	"io"
-	"net/http"
-	"net/url"
-	"os"
+	"context"
 	"path/filepath"
-	"strings"
-)
-
-var trackers = []string{
-	"udp://open.demonii.com:1337/announce",
-	"udp://tracker.openbittorrent.com:80/announce",
-	"udp://tracker.coppersurfer.tk:6969/announce",
-	"udp://glotorrents.pw:6969/announce",
-	"udp://tracker.opentrackr.org:1337/announce",
-}
-
-func BuildMagnet(hash, name string) string {
-	var trackerParams strings.Builder
-	for _, t := range trackers {
-		trackerParams.WriteString("&tr=")
-		trackerParams.WriteString(url.QueryEscape(t))
-	}
+	"time"
 
-	return fmt.Sprintf("magnet:?xt=urn:btih:%s&dn=%s%s",
-		hash, url.QueryEscape(name), trackerParams.String())
-}
-
-func DownloadTorrentFile(torrentURL, movieTitle, quality string) (string, error) {
-	resp, err := http.Get(torrentURL)
-	if err != nil {
-		return "", fmt.Errorf("failed to download: %w", err)
-	}
-	defer resp.Body.Close()
+	"c-cli/core"
+	"c-cli/tracker"
+)
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("download failed with status: %d", resp.StatusCode)
-	}
+// trackerList holds the configured trackers, ranked by background probes.
+var trackerList *tracker.List
 
-	// Sanitize filename
-	safeTitle := sanitizeFilename(movieTitle)
-	filename := fmt.Sprintf("%s.%s.torrent", safeTitle, quality)
-	filepath := filepath.Join(config.DownloadDir, filename)
+// trackerProbeTimeout bounds the startup health check of all trackers.
+const trackerProbeTimeout = 15 * time.Second
 
-	out, err := os.Create(filepath)
-	if err != nil {
-		return "", fmt.Errorf("failed to create file: %w", err)
+// loadTrackers builds the tracker list from config: the trackers setting
+// replaces the built-in defaults, and trackers_file adds to them.
+func loadTrackers(cfg Config) *tracker.List {
+	base := tracker.DefaultTrackers
+	if len(cfg.Trackers) > 0 {
+		base = cfg.Trackers
 	}
-	defer out.Close()
-
-	_, err = io.Copy(out, resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to write file: %w", err)
+	var extra []string
+	if cfg.TrackersFile != "" {
+		// An unreadable list shouldn't stop the app; the base list still works
+		extra, _ = tracker.ReadListFile(cfg.TrackersFile)
 	}
-
-	return filepath, nil
+	return tracker.NewList(base, extra)
 }
 
-func sanitizeFilename(name string) string {
-	// Replace characters that are problematic in filenames
-	replacer := strings.NewReplacer(
-		"/", "-",
-		"\\", "-",
-		":", "-",
-		"*", "-",
-		"?", "-",
-		"\"", "-",
-		"<", "-",
-		">", "-",
-		"|", "-",
-	)
-	return replacer.Replace(name)
+// probeTrackers scores every tracker once so magnets leave out dead ones.
+// Only the TUI and grab run it; other commands would exit before it ends,
+// having sent a probe to every tracker for nothing.
+func probeTrackers() {
+	ctx, cancel := context.WithTimeout(context.Background(), trackerProbeTimeout)
+	defer cancel()
+	trackerList.Probe(ctx)
 }
 
-func SelectBestTorrent(torrents []Torrent) *Torrent {
-	if len(torrents) == 0 {
-		return nil
+// DownloadTorrentFile saves the torrent's .torrent file in download_dir and
+// returns its path.
+func DownloadTorrentFile(ctx context.Context, torrent core.Torrent, movieTitle string) (string, error) {
+	_, data, err := lib.FetchTorrent(ctx, torrent)
+	if err != nil {
+		return "", err
 	}
 
-	qualityRank := map[string]int{
-		"2160p": 3,
-		"1080p": 2,
-		"720p":  1,
+	path := filepath.Join(config.DownloadDir, core.TorrentFilename(movieTitle, torrent.Quality))
+	if err := core.WriteFileAtomic(path, data, 0644); err != nil {
+		return "", err
 	}
-
-	best := &torrents[0]
-	bestScore := qualityRank[best.Quality]*1000 + best.Seeds
-
-	for i := 1; i < len(torrents); i++ {
-		score := qualityRank[torrents[i].Quality]*1000 + torrents[i].Seeds
-		if score > bestScore {
-			best = &torrents[i]
-			bestScore = score
-		}
-	}
-
-	return best
+	return path, nil
 }
@@ -8,9 +8,25 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		code := runCommand(os.Args[1:])
+		saveOMDBState()
+		os.Exit(code)
+	}
+
+	go probeTrackers()
 	p := tea.NewProgram(NewModel(), tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
+	_, err := p.Run()
+	saveOMDBState()
+	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
 }
+
+// saveOMDBState flushes cached OMDB responses and request counts that are
+// still waiting for their delayed write.
+func saveOMDBState() {
+	omdbClient.Cache.Save()
+	omdbClient.Quota.Save()
+}
@@ -0,0 +1,250 @@
+// Package metafetch downloads a torrent's info dictionary straight from the
+// swarm, turning a bare infohash into a .torrent file. Peers come from
+// trackers and the DHT; the metadata itself is fetched with the extension
+// protocol (BEP 10) and ut_metadata (BEP 9).
+package metafetch
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"c-cli/bencode"
+	"c-cli/dht"
+	"c-cli/tracker"
+)
+
+// ErrNoPeers is returned when no peer could be found or contacted.
+var ErrNoPeers = errors.New("metafetch: no peers found")
+
+// Fetcher finds peers for a torrent and downloads its metadata from them.
+type Fetcher struct {
+	Trackers  []string // Announce URLs asked for peers
+	DHT       bool     // Also look for peers on the mainline DHT
+	Bootstrap []string // DHT bootstrap nodes; dht.DefaultBootstrap when empty
+	Workers   int      // Peers tried in parallel; 8 when zero
+	PeerID    [20]byte // Generated when zero
+
+	// Dialer connects to peers, e.g. with a Control that refuses private
+	// addresses; one with a 5s timeout when nil
+	Dialer *net.Dialer
+}
+
+// Fetch discovers peers for infohash and returns the first verified info
+// dictionary one of them serves. ctx bounds the whole search.
+func (f *Fetcher) Fetch(ctx context.Context, infohash [20]byte) ([]byte, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	peerID := f.peerID()
+	peers := make(chan string, 64)
+	var (
+		mu      sync.Mutex
+		discErr error
+	)
+	go func() {
+		defer close(peers)
+		err := f.discover(ctx, infohash, peerID, peers)
+		mu.Lock()
+		discErr = err
+		mu.Unlock()
+	}()
+
+	info, err := f.fetch(ctx, infohash, peerID, peers)
+	if errors.Is(err, ErrNoPeers) {
+		mu.Lock()
+		defer mu.Unlock()
+		if discErr != nil {
+			return nil, fmt.Errorf("%w: %v", err, discErr)
+		}
+	}
+	return info, err
+}
+
+// FetchFromPeers skips discovery and asks the given "host:port" peers
+// directly, e.g. a known seed or a local peer stand-in.
+func (f *Fetcher) FetchFromPeers(ctx context.Context, infohash [20]byte, peers []string) ([]byte, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ch := make(chan string, len(peers))
+	for _, p := range peers {
+		ch <- p
+	}
+	close(ch)
+	return f.fetch(ctx, infohash, f.peerID(), ch)
+}
+
+// discover announces to every tracker and walks the DHT concurrently,
+// sending each new peer address once. It returns the last lookup error, if
+// any source failed.
+func (f *Fetcher) discover(ctx context.Context, infohash, peerID [20]byte, out chan<- string) error {
+	var (
+		mu      sync.Mutex
+		seen    = map[netip.AddrPort]bool{}
+		lastErr error
+		wg      sync.WaitGroup
+	)
+	fail := func(err error) {
+		mu.Lock()
+		lastErr = err
+		mu.Unlock()
+	}
+	emit := func(peers []netip.AddrPort) {
+		for _, p := range peers {
+			mu.Lock()
+			dup := seen[p]
+			seen[p] = true
+			mu.Unlock()
+			if dup {
+				continue
+			}
+			select {
+			case out <- p.String():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+
+	req := tracker.AnnounceRequest{InfoHash: infohash, PeerID: peerID, Port: 6881, NumWant: 50}
+	for _, tr := range f.Trackers {
+		wg.Add(1)
+		go func(tr string) {
+			defer wg.Done()
+			resp, err := tracker.Announce(ctx, tr, req)
+			if err != nil {
+				fail(err)
+				return
+			}
+			emit(resp.Peers)
+		}(tr)
+	}
+	if f.DHT {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			peers, err := dht.GetPeers(ctx, infohash, f.Bootstrap, 100)
+			if err != nil {
+				fail(err)
+			}
+			emit(peers)
+		}()
+	}
+	wg.Wait()
+	return lastErr
+}
+
+// fetch runs workers over the peer stream until one returns the metadata.
+func (f *Fetcher) fetch(ctx context.Context, infohash, peerID [20]byte, peers <-chan string) ([]byte, error) {
+	workers := f.Workers
+	if workers <= 0 {
+		workers = 8
+	}
+
+	var (
+		once    sync.Once
+		result  []byte
+		mu      sync.Mutex
+		lastErr error
+		tried   int
+		wg      sync.WaitGroup
+	)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				var addr string
+				select {
+				case <-ctx.Done():
+					return
+				case a, ok := <-peers:
+					if !ok {
+						return
+					}
+					addr = a
+				}
+				info, err := fetchFromPeer(ctx, f.dialer(), addr, infohash, peerID)
+				mu.Lock()
+				tried++
+				if err != nil {
+					lastErr = err
+				}
+				mu.Unlock()
+				if err == nil {
+					once.Do(func() {
+						result = info
+						cancel()
+					})
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if result != nil {
+		return result, nil
+	}
+	if tried == 0 {
+		return nil, ErrNoPeers
+	}
+	return nil, fmt.Errorf("metafetch: %d peer(s) tried, last error: %w", tried, lastErr)
+}
+
+func (f *Fetcher) dialer() *net.Dialer {
+	if f.Dialer != nil {
+		return f.Dialer
+	}
+	return &net.Dialer{Timeout: 5 * time.Second}
+}
+
+func (f *Fetcher) peerID() [20]byte {
+	if f.PeerID != [20]byte{} {
+		return f.PeerID
+	}
+	var id [20]byte
+	copy(id[:], "-CC0100-")
+	rand.Read(id[8:])
+	return id
+}
+
+// Torrent wraps a raw info dictionary into a .torrent file announcing to
+// trackers, each in its own tier.
+func Torrent(info []byte, trackers []string) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('d')
+	if len(trackers) > 0 {
+		tiers := make([]interface{}, len(trackers))
+		for i, t := range trackers {
+			tiers[i] = []string{t}
+		}
+		announce, err := bencode.Encode(trackers[0])
+		if err != nil {
+			return nil, err
+		}
+		list, err := bencode.Encode(tiers)
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString("8:announce")
+		b.Write(announce)
+		b.WriteString("13:announce-list")
+		b.Write(list)
+	}
+	b.WriteString("10:created by5:c-cli")
+	b.WriteString("4:info")
+	b.Write(info)
+	b.WriteByte('e')
+	return b.Bytes(), nil
+}
@@ -0,0 +1,190 @@
+package metafetch
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"c-cli/bencode"
+)
+
+// remoteMetadataID is the ut_metadata ID the stand-in peer advertises.
+const remoteMetadataID = 3
+
+// peer is a stand-in BitTorrent peer that answers the handshake and serves
+// ut_metadata pieces of info.
+type peer struct {
+	info   []byte // Served metadata
+	size   int    // Advertised metadata_size; len(info) when zero
+	reject bool   // Answer every request with utReject
+}
+
+// listen starts p on a loopback port and returns its address.
+func (p peer) listen(t *testing.T, infohash [20]byte) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go p.serve(conn, infohash)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func (p peer) serve(conn net.Conn, infohash [20]byte) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	hs := make([]byte, 68)
+	if _, err := io.ReadFull(conn, hs); err != nil {
+		return
+	}
+	reply := make([]byte, 0, 68)
+	reply = append(reply, byte(len(protocol)))
+	reply = append(reply, protocol...)
+	reply = append(reply, 0, 0, 0, 0, 0, 0x10, 0, 0)
+	reply = append(reply, infohash[:]...)
+	reply = append(reply, "-XX0001-000000000000"...)
+	conn.Write(reply)
+
+	size := p.size
+	if size == 0 {
+		size = len(p.info)
+	}
+	ext, _ := bencode.Encode(map[string]interface{}{
+		"m":             map[string]interface{}{"ut_metadata": remoteMetadataID},
+		"metadata_size": size,
+	})
+	writeExtended(conn, extHandshake, ext)
+
+	for {
+		msg, err := readMessage(conn)
+		if err != nil {
+			return
+		}
+		if len(msg) < 2 || msg[0] != msgExtended || msg[1] != remoteMetadataID {
+			continue
+		}
+		v, err := bencode.Decode(msg[2:])
+		if err != nil {
+			return
+		}
+		piece, _ := v.(map[string]interface{})["piece"].(int64)
+		if p.reject {
+			rej, _ := bencode.Encode(map[string]interface{}{"msg_type": utReject, "piece": piece})
+			writeExtended(conn, localMetadataID, rej)
+			continue
+		}
+		start := int(piece) * metadataPieceSize
+		end := min(start+metadataPieceSize, len(p.info))
+		head, _ := bencode.Encode(map[string]interface{}{"msg_type": utData, "piece": piece, "total_size": len(p.info)})
+		writeExtended(conn, localMetadataID, append(head, p.info[start:end]...))
+	}
+}
+
+// testInfo returns an info dictionary spanning several metadata pieces.
+func testInfo(t *testing.T) []byte {
+	t.Helper()
+	info, err := bencode.Encode(map[string]interface{}{
+		"name":         "test.mkv",
+		"length":       1 << 30,
+		"piece length": 1 << 20,
+		"pieces":       strings.Repeat("x", 20*1024*2),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info) <= 2*metadataPieceSize {
+		t.Fatalf("test info is %d bytes, want more than two pieces", len(info))
+	}
+	return info
+}
+
+func fetchFrom(t *testing.T, infohash [20]byte, p peer) ([]byte, error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	f := &Fetcher{Workers: 1}
+	return f.FetchFromPeers(ctx, infohash, []string{p.listen(t, infohash)})
+}
+
+func TestFetchFromPeers(t *testing.T) {
+	info := testInfo(t)
+	got, err := fetchFrom(t, sha1.Sum(info), peer{info: info})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, info) {
+		t.Errorf("got %d bytes of metadata, want the %d served", len(got), len(info))
+	}
+}
+
+func TestFetchHashMismatch(t *testing.T) {
+	info := testInfo(t)
+	other := sha1.Sum([]byte("another torrent"))
+	_, err := fetchFrom(t, other, peer{info: info})
+	if err == nil || !strings.Contains(err.Error(), "does not match infohash") {
+		t.Errorf("err = %v, want a hash mismatch", err)
+	}
+}
+
+func TestFetchReject(t *testing.T) {
+	info := testInfo(t)
+	_, err := fetchFrom(t, sha1.Sum(info), peer{info: info, reject: true})
+	if err == nil || !strings.Contains(err.Error(), "rejected") {
+		t.Errorf("err = %v, want a rejection", err)
+	}
+}
+
+func TestFetchOversizedMetadata(t *testing.T) {
+	info := testInfo(t)
+	_, err := fetchFrom(t, sha1.Sum(info), peer{info: info, size: maxMetadataSize + 1})
+	if err == nil || !strings.Contains(err.Error(), "metadata size") {
+		t.Errorf("err = %v, want the size to be refused", err)
+	}
+}
+
+func TestFetchNoPeers(t *testing.T) {
+	f := &Fetcher{}
+	if _, err := f.FetchFromPeers(context.Background(), [20]byte{}, nil); err != ErrNoPeers {
+		t.Errorf("err = %v, want ErrNoPeers", err)
+	}
+}
+
+func TestReadMessageTooLarge(t *testing.T) {
+	var b bytes.Buffer
+	binary.Write(&b, binary.BigEndian, uint32(maxMessageSize+1))
+	if _, err := readMessage(&b); err == nil {
+		t.Error("readMessage accepted an oversized message")
+	}
+}
+
+func TestFetchDialer(t *testing.T) {
+	info := testInfo(t)
+	infohash := sha1.Sum(info)
+	addr := peer{info: info}.listen(t, infohash)
+
+	refused := errors.New("refused")
+	f := &Fetcher{Dialer: &net.Dialer{Control: func(network, address string, _ syscall.RawConn) error {
+		return refused
+	}}}
+	if _, err := f.FetchFromPeers(context.Background(), infohash, []string{addr}); !errors.Is(err, refused) {
+		t.Errorf("err = %v, want the dialer's refusal", err)
+	}
+}
@@ -0,0 +1,224 @@
+package metafetch
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"c-cli/bencode"
+)
+
+const (
+	protocol = "BitTorrent protocol"
+
+	msgExtended = 20 // BEP 10
+
+	extHandshake = 0
+	// localMetadataID is the ut_metadata ID we advertise; peers tag the
+	// metadata messages they send us with it.
+	localMetadataID = 1
+
+	utRequest = 0
+	utData    = 1
+	utReject  = 2
+
+	metadataPieceSize = 16 * 1024
+	// maxMetadataSize rejects absurd sizes before allocating; the largest
+	// real-world info dictionaries are a few MB.
+	maxMetadataSize = 8 << 20
+	maxMessageSize  = metadataPieceSize + 1<<10 + 1<<20 // Room for a large bitfield
+
+	peerTimeout = 20 * time.Second
+)
+
+// fetchFromPeer connects to one peer with d and downloads the info
+// dictionary for infohash with ut_metadata, verifying it hashes back to
+// infohash.
+func fetchFromPeer(ctx context.Context, d *net.Dialer, addr string, infohash, peerID [20]byte) ([]byte, error) {
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(peerTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if err := handshake(conn, infohash, peerID); err != nil {
+		return nil, err
+	}
+
+	hs, err := bencode.Encode(map[string]interface{}{
+		"m": map[string]interface{}{"ut_metadata": localMetadataID},
+		"v": "c-cli",
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := writeExtended(conn, extHandshake, hs); err != nil {
+		return nil, err
+	}
+
+	var (
+		remoteID byte
+		metadata []byte
+		received []bool
+		left     int
+	)
+	for {
+		msg, err := readMessage(conn)
+		if err != nil {
+			return nil, err
+		}
+		if len(msg) < 2 || msg[0] != msgExtended {
+			continue // Keep-alives, bitfield, have, ...
+		}
+
+		switch msg[1] {
+		case extHandshake:
+			if metadata != nil {
+				continue
+			}
+			id, size, err := parseExtHandshake(msg[2:])
+			if err != nil {
+				return nil, err
+			}
+			remoteID = id
+			metadata = make([]byte, size)
+			received = make([]bool, (size+metadataPieceSize-1)/metadataPieceSize)
+			left = len(received)
+			for piece := range received {
+				req, _ := bencode.Encode(map[string]interface{}{"msg_type": utRequest, "piece": piece})
+				if err := writeExtended(conn, remoteID, req); err != nil {
+					return nil, err
+				}
+			}
+
+		case localMetadataID:
+			if metadata == nil {
+				continue
+			}
+			v, n, err := bencode.DecodePrefix(msg[2:])
+			if err != nil {
+				return nil, fmt.Errorf("bad ut_metadata message: %w", err)
+			}
+			dict, _ := v.(map[string]interface{})
+			msgType, _ := dict["msg_type"].(int64)
+			piece, _ := dict["piece"].(int64)
+			switch msgType {
+			case utReject:
+				return nil, errors.New("peer rejected metadata request")
+			case utData:
+			default:
+				continue
+			}
+			if piece < 0 || int(piece) >= len(received) || received[piece] {
+				continue
+			}
+
+			data := msg[2+n:]
+			offset := int(piece) * metadataPieceSize
+			want := metadataPieceSize
+			if rest := len(metadata) - offset; rest < want {
+				want = rest
+			}
+			if len(data) != want {
+				return nil, fmt.Errorf("metadata piece %d has %d bytes, want %d", piece, len(data), want)
+			}
+			copy(metadata[offset:], data)
+			received[piece] = true
+			if left--; left == 0 {
+				if sha1.Sum(metadata) != infohash {
+					return nil, errors.New("metadata does not match infohash")
+				}
+				return metadata, nil
+			}
+		}
+	}
+}
+
+// handshake exchanges the BitTorrent handshake, advertising and requiring
+// the extension protocol.
+func handshake(conn net.Conn, infohash, peerID [20]byte) error {
+	var b bytes.Buffer
+	b.WriteByte(byte(len(protocol)))
+	b.WriteString(protocol)
+	reserved := make([]byte, 8)
+	reserved[5] |= 0x10 // BEP 10 extension protocol
+	b.Write(reserved)
+	b.Write(infohash[:])
+	b.Write(peerID[:])
+	if _, err := conn.Write(b.Bytes()); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 68)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return fmt.Errorf("handshake: %w", err)
+	}
+	if int(reply[0]) != len(protocol) || string(reply[1:20]) != protocol {
+		return errors.New("handshake: not a BitTorrent peer")
+	}
+	if !bytes.Equal(reply[28:48], infohash[:]) {
+		return errors.New("handshake: peer is in a different swarm")
+	}
+	if reply[25]&0x10 == 0 {
+		return errors.New("handshake: peer does not support extensions")
+	}
+	return nil
+}
+
+// parseExtHandshake returns the peer's ut_metadata ID and the metadata size.
+func parseExtHandshake(payload []byte) (byte, int, error) {
+	v, err := bencode.Decode(payload)
+	if err != nil {
+		return 0, 0, fmt.Errorf("bad extension handshake: %w", err)
+	}
+	dict, _ := v.(map[string]interface{})
+	m, _ := dict["m"].(map[string]interface{})
+	id, _ := m["ut_metadata"].(int64)
+	if id <= 0 || id > 255 {
+		return 0, 0, errors.New("peer does not support ut_metadata")
+	}
+	size, _ := dict["metadata_size"].(int64)
+	if size <= 0 || size > maxMetadataSize {
+		return 0, 0, fmt.Errorf("peer reports metadata size %d", size)
+	}
+	return byte(id), int(size), nil
+}
+
+func readMessage(r io.Reader) ([]byte, error) {
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(lenBuf[:])
+	if n > maxMessageSize {
+		return nil, fmt.Errorf("message of %d bytes is too large", n)
+	}
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func writeExtended(w io.Writer, extID byte, payload []byte) error {
+	msg := make([]byte, 6, 6+len(payload))
+	binary.BigEndian.PutUint32(msg, uint32(2+len(payload)))
+	msg[4] = msgExtended
+	msg[5] = extID
+	_, err := w.Write(append(msg, payload...))
+	return err
+}
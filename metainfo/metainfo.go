@@ -0,0 +1,264 @@
+// Package metainfo reads .torrent files: the info dictionary, file list,
+// trackers (BEP 3, BEP 12), private flag (BEP 27) and v1 infohash.
+package metainfo
+
+import (
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"c-cli/bencode"
+)
+
+// ErrHashMismatch is returned by Verify when a file is not the torrent asked for.
+var ErrHashMismatch = errors.New("infohash mismatch")
+
+// File is one file of a torrent. Path is relative to the torrent's name.
+type File struct {
+	Path   []string
+	Length int64
+}
+
+// Info is the info dictionary of a v1 torrent.
+type Info struct {
+	Name        string
+	PieceLength int64
+	Pieces      []byte // Concatenated SHA-1 piece hashes
+	Private     bool
+	Length      int64  // Single-file torrents only
+	Files       []File // Multi-file torrents only
+}
+
+// MetaInfo is a parsed .torrent file.
+type MetaInfo struct {
+	Announce     string
+	AnnounceList [][]string // Tiers of tracker URLs
+	Comment      string
+	CreatedBy    string
+	CreationDate time.Time
+	Info         Info
+	InfoHash     [20]byte // SHA-1 of the bencoded info dictionary
+}
+
+// Parse decodes and validates a .torrent file.
+func Parse(data []byte) (*MetaInfo, error) {
+	v, err := bencode.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	root, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("metainfo: not a dictionary")
+	}
+	infoDict, ok := root["info"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("metainfo: missing info dictionary")
+	}
+	rawInfo, err := bencode.DictValue(data, "info")
+	if err != nil {
+		return nil, err
+	}
+
+	m := &MetaInfo{
+		Announce:  str(root, "announce"),
+		Comment:   utf8Str(root, "comment"),
+		CreatedBy: str(root, "created by"),
+		InfoHash:  sha1.Sum(rawInfo),
+	}
+	if ts, ok := root["creation date"].(int64); ok && ts > 0 {
+		m.CreationDate = time.Unix(ts, 0)
+	}
+	if tiers, ok := root["announce-list"].([]interface{}); ok {
+		for _, t := range tiers {
+			urls, _ := t.([]interface{})
+			var tier []string
+			for _, u := range urls {
+				if s, ok := u.(string); ok && s != "" {
+					tier = append(tier, s)
+				}
+			}
+			if len(tier) > 0 {
+				m.AnnounceList = append(m.AnnounceList, tier)
+			}
+		}
+	}
+
+	if m.Info, err = parseInfo(infoDict); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func parseInfo(d map[string]interface{}) (Info, error) {
+	info := Info{
+		Name: utf8Str(d, "name"),
+	}
+	info.PieceLength, _ = d["piece length"].(int64)
+	if p, ok := d["private"].(int64); ok && p == 1 {
+		info.Private = true
+	}
+
+	pieces, ok := d["pieces"].(string)
+	if !ok {
+		if _, v2 := d["file tree"]; v2 {
+			return info, errors.New("metainfo: v2-only torrents are not supported")
+		}
+		return info, errors.New("metainfo: missing pieces")
+	}
+	if len(pieces)%sha1.Size != 0 {
+		return info, errors.New("metainfo: pieces length is not a multiple of 20")
+	}
+	info.Pieces = []byte(pieces)
+	if info.PieceLength <= 0 {
+		return info, errors.New("metainfo: invalid piece length")
+	}
+
+	if files, ok := d["files"].([]interface{}); ok {
+		for i, f := range files {
+			fd, ok := f.(map[string]interface{})
+			if !ok {
+				return info, fmt.Errorf("metainfo: file %d is not a dictionary", i)
+			}
+			length, _ := fd["length"].(int64)
+			path := pathList(fd, "path.utf-8")
+			if len(path) == 0 {
+				path = pathList(fd, "path")
+			}
+			if length < 0 || len(path) == 0 {
+				return info, fmt.Errorf("metainfo: file %d has no path or a bad length", i)
+			}
+			for _, elem := range path {
+				if elem == "" || elem == "." || elem == ".." || strings.ContainsAny(elem, "/\\") {
+					return info, fmt.Errorf("metainfo: file %d has an unsafe path", i)
+				}
+			}
+			info.Files = append(info.Files, File{Path: path, Length: length})
+		}
+	} else {
+		length, ok := d["length"].(int64)
+		if !ok || length < 0 {
+			return info, errors.New("metainfo: missing length")
+		}
+		info.Length = length
+	}
+
+	var total int64
+	for _, f := range info.FileList() {
+		total += f.Length
+	}
+	if want := (total + info.PieceLength - 1) / info.PieceLength; int64(info.NumPieces()) != want {
+		return info, fmt.Errorf("metainfo: %d pieces for %d bytes, want %d", info.NumPieces(), total, want)
+	}
+	return info, nil
+}
+
+// FileList returns the torrent's files; a single-file torrent yields one
+// entry named after the torrent.
+func (i Info) FileList() []File {
+	if i.Files != nil {
+		return i.Files
+	}
+	return []File{{Path: []string{i.Name}, Length: i.Length}}
+}
+
+// NumPieces returns the number of pieces.
+func (i Info) NumPieces() int {
+	return len(i.Pieces) / sha1.Size
+}
+
+// TotalLength returns the combined size of all files in bytes.
+func (m *MetaInfo) TotalLength() int64 {
+	var total int64
+	for _, f := range m.Info.FileList() {
+		total += f.Length
+	}
+	return total
+}
+
+// HashString returns the v1 infohash as lowercase hex.
+func (m *MetaInfo) HashString() string {
+	return hex.EncodeToString(m.InfoHash[:])
+}
+
+// Trackers returns every tracker URL, announce-list tiers first, without duplicates.
+func (m *MetaInfo) Trackers() []string {
+	seen := map[string]bool{}
+	var out []string
+	add := func(u string) {
+		if u != "" && !seen[u] {
+			seen[u] = true
+			out = append(out, u)
+		}
+	}
+	for _, tier := range m.AnnounceList {
+		for _, u := range tier {
+			add(u)
+		}
+	}
+	add(m.Announce)
+	return out
+}
+
+// Verify checks the infohash against hash, given as 40-char hex or 32-char
+// base32 as found in magnet links.
+func (m *MetaInfo) Verify(hash string) error {
+	want, err := ParseHash(hash)
+	if err != nil {
+		return err
+	}
+	if want != m.InfoHash {
+		return fmt.Errorf("%w: got %s, want %s", ErrHashMismatch, m.HashString(), hex.EncodeToString(want[:]))
+	}
+	return nil
+}
+
+// ParseHash decodes a v1 infohash in hex or base32 form.
+func ParseHash(hash string) ([20]byte, error) {
+	var h [20]byte
+	hash = strings.TrimSpace(hash)
+	switch len(hash) {
+	case 40:
+		if _, err := hex.Decode(h[:], []byte(hash)); err != nil {
+			return h, fmt.Errorf("invalid infohash %q", hash)
+		}
+	case 32:
+		b, err := base32.StdEncoding.DecodeString(strings.ToUpper(hash))
+		if err != nil || len(b) != 20 {
+			return h, fmt.Errorf("invalid infohash %q", hash)
+		}
+		copy(h[:], b)
+	default:
+		return h, fmt.Errorf("invalid infohash %q", hash)
+	}
+	return h, nil
+}
+
+func str(d map[string]interface{}, key string) string {
+	s, _ := d[key].(string)
+	return s
+}
+
+// utf8Str prefers the non-standard "key.utf-8" variant some clients write.
+func utf8Str(d map[string]interface{}, key string) string {
+	if s := str(d, key+".utf-8"); s != "" {
+		return s
+	}
+	return str(d, key)
+}
+
+func pathList(d map[string]interface{}, key string) []string {
+	list, _ := d[key].([]interface{})
+	var path []string
+	for _, p := range list {
+		s, ok := p.(string)
+		if !ok {
+			return nil
+		}
+		path = append(path, s)
+	}
+	return path
+}
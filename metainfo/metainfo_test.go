@@ -0,0 +1,119 @@
+package metainfo
+
+import (
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// torrent builds a .torrent around a raw info dictionary.
+func torrent(info string) []byte {
+	return []byte("d8:announce13:udp://t.io:8013:announce-listll13:udp://t.io:80el9:http://x/ee4:info" + info + "e")
+}
+
+// pieces returns n fake piece hashes as a bencoded string.
+func pieces(n int) string {
+	p := strings.Repeat("p", 20*n)
+	return "6:pieces" + strconv.Itoa(len(p)) + ":" + p
+}
+
+func TestParseSingleFile(t *testing.T) {
+	info := "d6:lengthi40000e4:name5:a.mkv12:piece lengthi16384e" + pieces(3) + "7:privatei1ee"
+	m, err := Parse(torrent(info))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.InfoHash != sha1.Sum([]byte(info)) {
+		t.Errorf("infohash %s is not the SHA-1 of the raw info bytes", m.HashString())
+	}
+	if m.Info.Name != "a.mkv" || m.TotalLength() != 40000 || m.Info.NumPieces() != 3 || !m.Info.Private {
+		t.Errorf("info = %+v", m.Info)
+	}
+	if got := m.Trackers(); len(got) != 2 || got[0] != "udp://t.io:80" || got[1] != "http://x/" {
+		t.Errorf("trackers = %q", got)
+	}
+}
+
+func TestParseMultiFile(t *testing.T) {
+	info := "d5:filesld6:lengthi10e4:pathl3:sub5:a.txteed6:lengthi20e4:pathl5:b.txteee" +
+		"4:name3:dir12:piece lengthi16e" + pieces(2) + "e"
+	m, err := Parse(torrent(info))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := m.Info.FileList()
+	if len(files) != 2 || strings.Join(files[0].Path, "/") != "sub/a.txt" || m.TotalLength() != 30 {
+		t.Errorf("files = %+v", files)
+	}
+}
+
+func TestParseRawInfoHash(t *testing.T) {
+	// Keys out of order: re-encoding would sort them and change the hash
+	info := "d12:piece lengthi16384e4:name5:a.mkv6:lengthi100e" + pieces(1) + "e"
+	m, err := Parse(torrent(info))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := sha1.Sum([]byte(info))
+	if m.InfoHash != want {
+		t.Errorf("infohash = %s, want %x", m.HashString(), want)
+	}
+	if err := m.Verify(hex.EncodeToString(want[:])); err != nil {
+		t.Error(err)
+	}
+	if err := m.Verify(strings.Repeat("0", 40)); !errors.Is(err, ErrHashMismatch) {
+		t.Errorf("Verify of another hash = %v, want ErrHashMismatch", err)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	tests := []struct {
+		name, info, want string
+	}{
+		{"too few pieces", "d6:lengthi40000e4:name1:a12:piece lengthi16384e" + pieces(2) + "e", "pieces for"},
+		{"too many pieces", "d6:lengthi40000e4:name1:a12:piece lengthi16384e" + pieces(4) + "e", "pieces for"},
+		{"ragged pieces", "d6:lengthi10e4:name1:a12:piece lengthi16e6:pieces19:" + strings.Repeat("p", 19) + "e", "multiple of 20"},
+		{"zero piece length", "d6:lengthi10e4:name1:a12:piece lengthi0e" + pieces(1) + "e", "piece length"},
+		{"negative length", "d6:lengthi-1e4:name1:a12:piece lengthi16e" + pieces(0) + "e", "missing length"},
+		{"missing pieces", "d6:lengthi10e4:name1:a12:piece lengthi16ee", "missing pieces"},
+		{"v2 only", "d9:file treede4:name1:a12:piece lengthi16ee", "v2-only"},
+		{"dot-dot path", "d5:filesld6:lengthi1e4:pathl2:..6:passwdeee4:name1:a12:piece lengthi16e" + pieces(1) + "e", "unsafe path"},
+		{"dot path", "d5:filesld6:lengthi1e4:pathl1:.eee4:name1:a12:piece lengthi16e" + pieces(1) + "e", "unsafe path"},
+		{"slash in path", "d5:filesld6:lengthi1e4:pathl6:etc/abeee4:name1:a12:piece lengthi16e" + pieces(1) + "e", "unsafe path"},
+		{"backslash in path", "d5:filesld6:lengthi1e4:pathl4:a\\bceee4:name1:a12:piece lengthi16e" + pieces(1) + "e", "unsafe path"},
+		{"empty path element", "d5:filesld6:lengthi1e4:pathl0:eee4:name1:a12:piece lengthi16e" + pieces(1) + "e", "unsafe path"},
+		{"no path", "d5:filesld6:lengthi1eee4:name1:a12:piece lengthi16e" + pieces(1) + "e", "no path"},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(torrent(tt.info)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+	for _, in := range []string{"", "le", "d8:announce1:ae", "d4:infoi1ee"} {
+		if _, err := Parse([]byte(in)); err == nil {
+			t.Errorf("Parse(%q) succeeded", in)
+		}
+	}
+}
+
+func TestParseHash(t *testing.T) {
+	raw := sha1.Sum([]byte("x"))
+	hexHash := hex.EncodeToString(raw[:])
+	b32 := base32.StdEncoding.EncodeToString(raw[:])
+
+	for _, in := range []string{hexHash, strings.ToUpper(hexHash), " " + hexHash + "\n", b32, strings.ToLower(b32)} {
+		got, err := ParseHash(in)
+		if err != nil || got != raw {
+			t.Errorf("ParseHash(%q) = %x, %v; want %x", in, got, err, raw)
+		}
+	}
+	for _, in := range []string{"", hexHash[:39], hexHash + "0", "g" + hexHash[1:], "1" + b32[1:], b32[:31] + "!"} {
+		if _, err := ParseHash(in); err == nil {
+			t.Errorf("ParseHash(%q) succeeded", in)
+		}
+	}
+}
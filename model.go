@@ -1,13 +1,21 @@
 package main
 
 import (
+	"cmp"
+	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+
+	"c-cli/core"
+	"c-cli/metainfo"
 )
 
 // View states
@@ -19,6 +27,8 @@ const (
 	viewResults
 	viewDetails
 	viewTorrents
+	viewDownloads
+	viewInspect
 )
 
 // Styles
@@ -59,20 +69,60 @@ var (
 		Foreground(lipgloss.Color("220"))
 )
 
-// Messages
+// Messages. Those answering a request made from viewLoading carry its seq,
+// so a reply to a cancelled or superseded request can be dropped.
 type searchResultMsg struct {
-	result SearchResult
+	seq    int
+	result core.SearchResult
 	err    error
 }
 
+// movieEnrichedMsg delivers one result row once its OMDB lookup is done.
+// Rows keep arriving on updates until enrichDoneMsg.
+type movieEnrichedMsg struct {
+	seq int // Of the search the rows belong to
+	enrichedMovie
+	updates <-chan enrichedMovie
+}
+
+type enrichedMovie struct {
+	idx   int
+	movie core.Movie
+}
+
+type enrichDoneMsg struct {
+	seq int
+}
+
 type movieDetailsMsg struct {
-	movie *Movie
+	seq   int
+	movie *core.Movie
 	err   error
 }
 
 type actionCompleteMsg struct {
-	message string
-	err     error
+	message    string
+	err        error
+	downloadID string // Set when a grab was queued on a download client
+}
+
+type downloadsTickMsg struct{}
+
+type downloadsStatusMsg struct {
+	statuses []core.DownloadStatus
+	err      error
+}
+
+type torrentsScrapedMsg struct {
+	torrents []core.Torrent
+	err      error
+}
+
+type torrentInspectedMsg struct {
+	seq      int
+	meta     *metainfo.MetaInfo
+	verified bool // The infohash was checked against the one listed
+	err      error
 }
 
 type torrentDownloadedMsg struct {
@@ -85,23 +135,41 @@ type Model struct {
 	state        viewState
 	textInput    textinput.Model
 	spinner      spinner.Model
-	movies       []Movie
+	movies       []core.Movie
 	selected     int
-	movie        *Movie
-	torrents     []Torrent
+	movie        *core.Movie
+	torrents     []core.Torrent
 	torrentIdx   int
 	err          error
 	message      string
 	magnetLink   string
 	width        int
 	height       int
-	searchSource SearchSource
+	searchSource core.SearchSource
 	// Pagination
 	page         int
 	totalPages   int
 	totalResults int
 	perPage      int
 	lastQuery    string
+	// Downloads panel
+	prevState      viewState
+	downloads      []string // Client IDs of grabs sent this session
+	downloadStatus []core.DownloadStatus
+	downloadsErr   error
+	polling        bool
+	// Torrent inspector
+	inspect         *metainfo.MetaInfo
+	inspectVerified bool
+	inspectScroll   int
+	// Request behind viewLoading
+	cancel      context.CancelFunc
+	seq         int       // Of the current request; replies with another are stale
+	loadingFrom viewState // Where esc returns to
+	// OMDB enrichment of the results, streamed in after they are shown
+	resultsSeq    int // seq of the search that produced movies
+	enrichCancel  context.CancelFunc
+	enrichPending int
 }
 
 func NewModel() Model {
@@ -115,19 +183,13 @@ func NewModel() Model {
 	s.Spinner = spinner.Dot
 	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
 
-	// Default source from config or env
-	source := SourceYTS
-	if config.SearchSource == "torrents-csv" {
-		source = SourceTorrentsCSV
-	}
-
 	return Model{
 		state:        viewSearch,
 		textInput:    ti,
 		spinner:      s,
 		width:        80,
 		height:       24,
-		searchSource: source,
+		searchSource: defaultSource(),
 		page:         1,
 		perPage:      20,
 	}
@@ -153,6 +215,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, cmd
 
 	case searchResultMsg:
+		if msg.seq != m.seq {
+			return m, nil
+		}
+		m = m.finishLoading()
+		m = m.stopEnrichment()
 		if msg.err != nil {
 			m.err = msg.err
 			m.state = viewSearch
@@ -170,9 +237,39 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.selected = 0
 		m.state = viewResults
 		m.err = nil
+		m.resultsSeq = msg.seq
+		if config.OMDBAPIKey == "" {
+			return m, nil
+		}
+		// Show the results now; ratings stream in row by row
+		ctx, cancel := context.WithCancel(context.Background())
+		m.enrichCancel = cancel
+		m.enrichPending = len(m.movies)
+		return m, m.enrichMovies(ctx)
+
+	case movieEnrichedMsg:
+		if msg.seq != m.resultsSeq || m.enrichCancel == nil {
+			return m, nil
+		}
+		m.movies[msg.idx] = msg.movie
+		m.enrichPending--
+		return m, waitEnriched(msg.seq, msg.updates)
+
+	case enrichDoneMsg:
+		if msg.seq != m.resultsSeq || m.enrichCancel == nil {
+			return m, nil
+		}
+		m = m.stopEnrichment()
+		if config.SortByVotes {
+			m = m.sortResults()
+		}
 		return m, nil
 
 	case movieDetailsMsg:
+		if msg.seq != m.seq {
+			return m, nil
+		}
+		m = m.finishLoading()
 		if msg.err != nil {
 			m.err = msg.err
 			m.state = viewResults
@@ -191,6 +288,64 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		} else {
 			m.message = msg.message
 		}
+		if msg.downloadID != "" {
+			m.downloads = append(m.downloads, msg.downloadID)
+		}
+		return m, nil
+
+	case downloadsTickMsg:
+		if m.state != viewDownloads {
+			m.polling = false
+			return m, nil
+		}
+		return m, m.pollDownloads()
+
+	case downloadsStatusMsg:
+		m.downloadStatus = msg.statuses
+		m.downloadsErr = msg.err
+		if m.state != viewDownloads {
+			m.polling = false
+			return m, nil
+		}
+		return m, tea.Tick(time.Second, func(time.Time) tea.Msg { return downloadsTickMsg{} })
+
+	case torrentsScrapedMsg:
+		if msg.err != nil {
+			m.message = ""
+			m.err = msg.err
+			return m, nil
+		}
+		// Ignore results for a torrent list we've since navigated away from
+		if m.movie == nil || len(msg.torrents) != len(m.torrents) ||
+			(len(m.torrents) > 0 && msg.torrents[0].Hash != m.torrents[0].Hash) {
+			return m, nil
+		}
+		live := 0
+		for _, t := range msg.torrents {
+			if !t.ScrapedAt.IsZero() {
+				live++
+			}
+		}
+		m.torrents = msg.torrents
+		m.movie.Torrents = msg.torrents
+		m.message = fmt.Sprintf("📡 Live counts for %d of %d torrents", live, len(msg.torrents))
+		return m, nil
+
+	case torrentInspectedMsg:
+		if msg.seq != m.seq {
+			return m, nil
+		}
+		m = m.finishLoading()
+		if msg.err != nil {
+			m.err = msg.err
+			m.state = viewDetails
+			return m, nil
+		}
+		m.inspect = msg.meta
+		m.inspectVerified = msg.verified
+		m.inspectScroll = 0
+		m.state = viewInspect
+		m.err = nil
 		return m, nil
 
 	case torrentDownloadedMsg:
@@ -213,6 +368,29 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// Downloads panel is reachable from every screen
+	if msg.String() == "ctrl+t" {
+		return m.toggleDownloads()
+	}
+	if m.state == viewDownloads {
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "esc":
+			return m.toggleDownloads()
+		}
+		return m, nil
+	}
+	if m.state == viewLoading {
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "esc":
+			return m.cancelLoading(), nil
+		}
+		return m, nil
+	}
+
 	// In search mode, pass most keys to text input first
 	if m.state == viewSearch {
 		switch msg.String() {
@@ -221,12 +399,8 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		case "enter":
 			return m.handleEnter()
 		case "tab":
-			// Toggle search source
-			if m.searchSource == SourceYTS {
-				m.searchSource = SourceTorrentsCSV
-			} else {
-				m.searchSource = SourceYTS
-			}
+			// Cycle through registered search providers
+			m.searchSource = nextProvider(m.searchSource)
 			return m, nil
 		default:
 			// Pass all other keys to text input
@@ -244,6 +418,7 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "esc":
 		if m.state == viewResults {
 			// From results, go back to search
+			m = m.stopEnrichment()
 			m.state = viewSearch
 			m.movies = nil
 			m.err = nil
@@ -270,16 +445,27 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case "a":
-		// Auto-select best torrent
+		// Auto-select the torrent the quality profile prefers
 		if m.state == viewTorrents || m.state == viewDetails {
-			if best := SelectBestTorrent(m.torrents); best != nil {
-				for i, t := range m.torrents {
-					if t.Hash == best.Hash {
-						m.torrentIdx = i
-						break
-					}
+			name, profile, err := qualityProfile("")
+			if err != nil {
+				m.err = err
+				return m, nil
+			}
+			best, choice := core.SelectBestTorrent(m.torrents, profile)
+			if best == nil {
+				m.message = ""
+				m.err = fmt.Errorf("no torrent fits the %s profile: %s", name, choice)
+				return m, nil
+			}
+			for i, t := range m.torrents {
+				if t.Hash == best.Hash {
+					m.torrentIdx = i
+					break
 				}
 			}
+			m.err = nil
+			m.message = fmt.Sprintf("★ %s (%s profile): %s", best.Quality, name, choice)
 		}
 		return m, nil
 
@@ -287,19 +473,48 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		// Show magnet link
 		if (m.state == viewTorrents || m.state == viewDetails) && len(m.torrents) > 0 {
 			torrent := m.torrents[m.torrentIdx]
-			m.magnetLink = BuildMagnet(torrent.Hash, fmt.Sprintf("%s %s", m.movie.Title, torrent.Quality))
+			m.magnetLink = lib.Magnet(torrent.Hash, fmt.Sprintf("%s %s", m.movie.Title, torrent.Quality))
+			m.message = ""
+			m.err = nil
+		}
+		return m, nil
+
+	case "r":
+		// Refresh seeds/peers from the trackers
+		if (m.state == viewTorrents || m.state == viewDetails) && len(m.torrents) > 0 {
+			m.err = nil
+			m.message = "📡 Scraping trackers..."
+			return m, m.scrapeTorrents()
+		}
+		return m, nil
+
+	case "i":
+		// Fetch and inspect the .torrent before grabbing
+		if (m.state == viewTorrents || m.state == viewDetails) && len(m.torrents) > 0 {
 			m.message = ""
 			m.err = nil
+			m.magnetLink = ""
+			ctx := m.startLoading()
+			return m, tea.Batch(m.spinner.Tick, m.inspectTorrent(ctx))
 		}
 		return m, nil
 
 	case "t":
 		// Download torrent file
-		if (m.state == viewTorrents || m.state == viewDetails) && len(m.torrents) > 0 {
+		if (m.state == viewTorrents || m.state == viewDetails || m.state == viewInspect) && len(m.torrents) > 0 {
 			return m, m.downloadTorrent()
 		}
 		return m, nil
 
+	case "s":
+		// Send to the configured download client
+		if (m.state == viewTorrents || m.state == viewDetails || m.state == viewInspect) && len(m.torrents) > 0 {
+			m.message = ""
+			m.err = nil
+			return m, m.sendToClient()
+		}
+		return m, nil
+
 	case "0", "1", "2", "3", "4", "5", "6", "7", "8", "9":
 		// Number keys to select torrent directly
 		if m.state == viewDetails || m.state == viewTorrents {
@@ -313,18 +528,16 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "left", "[":
 		// Previous page
 		if m.state == viewResults && m.page > 1 {
-			m.page--
-			m.state = viewLoading
-			return m, tea.Batch(m.spinner.Tick, m.searchMovies(m.lastQuery, m.page))
+			ctx := m.startLoading()
+			return m, tea.Batch(m.spinner.Tick, m.searchMovies(ctx, m.lastQuery, m.page-1))
 		}
 		return m, nil
 
 	case "right", "]":
 		// Next page
 		if m.state == viewResults && m.page < m.totalPages {
-			m.page++
-			m.state = viewLoading
-			return m, tea.Batch(m.spinner.Tick, m.searchMovies(m.lastQuery, m.page))
+			ctx := m.startLoading()
+			return m, tea.Batch(m.spinner.Tick, m.searchMovies(ctx, m.lastQuery, m.page+1))
 		}
 		return m, nil
 	}
@@ -332,9 +545,29 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// toggleDownloads opens the downloads panel, remembering the current screen,
+// or returns to that screen. Opening starts the status polling loop.
+func (m Model) toggleDownloads() (tea.Model, tea.Cmd) {
+	if m.state == viewDownloads {
+		m.state = m.prevState
+		return m, nil
+	}
+	if m.state == viewLoading {
+		return m, nil
+	}
+	m.prevState = m.state
+	m.state = viewDownloads
+	if m.polling {
+		return m, nil
+	}
+	m.polling = true
+	return m, m.pollDownloads()
+}
+
 func (m Model) goBack() Model {
 	switch m.state {
 	case viewResults:
+		m = m.stopEnrichment()
 		m.state = viewSearch
 		m.movies = nil
 		m.err = nil
@@ -345,6 +578,11 @@ func (m Model) goBack() Model {
 		m.err = nil
 		m.message = ""
 		m.magnetLink = ""
+	case viewInspect:
+		m.state = viewDetails
+		m.inspect = nil
+		m.err = nil
+		m.message = ""
 	case viewSearch:
 		// Already at root
 	}
@@ -361,6 +599,10 @@ func (m Model) handleUp() Model {
 		if m.torrentIdx > 0 {
 			m.torrentIdx--
 		}
+	case viewInspect:
+		if m.inspectScroll > 0 {
+			m.inspectScroll--
+		}
 	}
 	return m
 }
@@ -375,6 +617,10 @@ func (m Model) handleDown() Model {
 		if m.torrentIdx < len(m.torrents)-1 {
 			m.torrentIdx++
 		}
+	case viewInspect:
+		if m.inspectScroll < len(m.inspectLines())-m.inspectHeight() {
+			m.inspectScroll++
+		}
 	}
 	return m
 }
@@ -387,33 +633,32 @@ func (m Model) handleEnter() (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 		m.lastQuery = query
-		m.page = 1
-		m.state = viewLoading
 		m.err = nil
-		return m, tea.Batch(m.spinner.Tick, m.searchMovies(query, 1))
+		ctx := m.startLoading()
+		return m, tea.Batch(m.spinner.Tick, m.searchMovies(ctx, query, 1))
 
 	case viewResults:
 		if len(m.movies) == 0 {
 			return m, nil
 		}
 		selectedMovie := m.movies[m.selected]
-		if selectedMovie.Source == SourceTorrentsCSV {
-			// For torrents-csv, we already have all the info
+		if !hasDetails(selectedMovie.Source) {
+			// Provider results are already complete
 			m.movie = &selectedMovie
 			m.torrents = selectedMovie.Torrents
 			m.torrentIdx = 0
 			m.state = viewDetails
 			return m, nil
 		}
-		// For YTS, fetch full details
-		m.state = viewLoading
-		return m, tea.Batch(m.spinner.Tick, m.fetchMovieDetails(selectedMovie.ID))
+		// Fetch full details from the provider
+		ctx := m.startLoading()
+		return m, tea.Batch(m.spinner.Tick, m.fetchMovieDetails(ctx, selectedMovie))
 
 	case viewDetails, viewTorrents:
 		// Show magnet link for selected torrent
 		if len(m.torrents) > 0 {
 			torrent := m.torrents[m.torrentIdx]
-			m.magnetLink = BuildMagnet(torrent.Hash, fmt.Sprintf("%s %s", m.movie.Title, torrent.Quality))
+			m.magnetLink = lib.Magnet(torrent.Hash, fmt.Sprintf("%s %s", m.movie.Title, torrent.Quality))
 			m.message = ""
 			m.err = nil
 		}
@@ -423,17 +668,112 @@ func (m Model) handleEnter() (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
-func (m Model) searchMovies(query string, page int) tea.Cmd {
+// startLoading switches to viewLoading for a new request, cancelling any
+// request still in flight, and returns the new request's context.
+func (m *Model) startLoading() context.Context {
+	if m.cancel != nil {
+		m.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	m.seq++
+	m.loadingFrom = m.state
+	m.state = viewLoading
+	return ctx
+}
+
+// finishLoading releases the current request once its reply has arrived.
+func (m Model) finishLoading() Model {
+	if m.cancel != nil {
+		m.cancel()
+		m.cancel = nil
+	}
+	return m
+}
+
+// cancelLoading abandons the current request and returns to the screen it
+// was made from. A reply that still arrives is dropped as stale.
+func (m Model) cancelLoading() Model {
+	m = m.finishLoading()
+	m.seq++
+	m.state = m.loadingFrom
+	return m
+}
+
+// enrichMovies looks the results up on OMDB in the background, delivering
+// each row as a movieEnrichedMsg as soon as it is done.
+func (m Model) enrichMovies(ctx context.Context) tea.Cmd {
+	movies := slices.Clone(m.movies)
+	seq := m.resultsSeq
 	return func() tea.Msg {
-		result, err := SearchMovies(query, page, m.perPage, m.searchSource)
-		return searchResultMsg{result: result, err: err}
+		// Buffered so the lookups never wait on a model that stopped listening
+		updates := make(chan enrichedMovie, len(movies))
+		go func() {
+			lib.EnrichMovies(ctx, movies, func(i int, movie core.Movie) {
+				updates <- enrichedMovie{idx: i, movie: movie}
+			})
+			close(updates)
+		}()
+		return waitEnriched(seq, updates)()
 	}
 }
 
-func (m Model) fetchMovieDetails(id int) tea.Cmd {
+func waitEnriched(seq int, updates <-chan enrichedMovie) tea.Cmd {
 	return func() tea.Msg {
-		movie, err := GetMovieDetails(id)
-		return movieDetailsMsg{movie: movie, err: err}
+		u, ok := <-updates
+		if !ok {
+			return enrichDoneMsg{seq: seq}
+		}
+		return movieEnrichedMsg{seq: seq, enrichedMovie: u, updates: updates}
+	}
+}
+
+// stopEnrichment cancels the lookups still running for the results.
+func (m Model) stopEnrichment() Model {
+	if m.enrichCancel != nil {
+		m.enrichCancel()
+		m.enrichCancel = nil
+	}
+	m.enrichPending = 0
+	return m
+}
+
+// sortResults orders the results by IMDb votes, keeping the cursor on the
+// same movie.
+func (m Model) sortResults() Model {
+	order := make([]int, len(m.movies))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(core.ParseVotes(m.movies[b].OMDB), core.ParseVotes(m.movies[a].OMDB))
+	})
+	sorted := make([]core.Movie, len(order))
+	selected := 0
+	for i, idx := range order {
+		sorted[i] = m.movies[idx]
+		if idx == m.selected {
+			selected = i
+		}
+	}
+	m.movies = sorted
+	m.selected = selected
+	return m
+}
+
+func (m Model) searchMovies(ctx context.Context, query string, page int) tea.Cmd {
+	seq := m.seq
+	return func() tea.Msg {
+		result, err := lib.Search(ctx, query, page, m.perPage, m.searchSource)
+		return searchResultMsg{seq: seq, result: result, err: err}
+	}
+}
+
+func (m Model) fetchMovieDetails(ctx context.Context, movie core.Movie) tea.Cmd {
+	seq := m.seq
+	return func() tea.Msg {
+		movie, err := lib.Details(ctx, movie)
+		return movieDetailsMsg{seq: seq, movie: movie, err: err}
 	}
 }
 
@@ -443,10 +783,61 @@ func (m Model) downloadTorrent() tea.Cmd {
 			return torrentDownloadedMsg{err: fmt.Errorf("no torrents available")}
 		}
 		torrent := m.torrents[m.torrentIdx]
-		filepath, err := DownloadTorrentFile(torrent.URL, m.movie.Title, torrent.Quality)
+		filepath, err := DownloadTorrentFile(context.Background(), torrent, m.movie.Title)
 		if err != nil {
 			return torrentDownloadedMsg{err: err}
 		}
 		return torrentDownloadedMsg{filepath: filepath}
 	}
 }
+
+func (m Model) scrapeTorrents() tea.Cmd {
+	torrents := m.torrents
+	return func() tea.Msg {
+		scraped, err := ScrapeTorrents(torrents, trackerList.Healthiest(0))
+		return torrentsScrapedMsg{torrents: scraped, err: err}
+	}
+}
+
+func (m Model) inspectTorrent(ctx context.Context) tea.Cmd {
+	torrent := m.torrents[m.torrentIdx]
+	seq := m.seq
+	return func() tea.Msg {
+		meta, _, err := lib.FetchTorrent(ctx, torrent)
+		// FetchTorrent only compares infohashes when the result has one
+		return torrentInspectedMsg{seq: seq, meta: meta, verified: torrent.Hash != "", err: err}
+	}
+}
+
+func (m Model) sendToClient() tea.Cmd {
+	torrent := m.torrents[m.torrentIdx]
+	name := fmt.Sprintf("%s %s", m.movie.Title, torrent.Quality)
+	return func() tea.Msg {
+		id, err := SendToClient(context.Background(), torrent, name)
+		var fallback *core.MagnetFallback
+		if err != nil && !errors.As(err, &fallback) {
+			return actionCompleteMsg{err: err}
+		}
+		msg := actionCompleteMsg{message: fmt.Sprintf("📤 Sent to %s: %s", downloadClient.Name(), name)}
+		if fallback != nil {
+			msg.message += " (" + fallback.Error() + ")"
+		}
+		if _, ok := downloadClient.(core.ProgressReporter); ok {
+			msg.downloadID = id
+			msg.message += " • ctrl+t: downloads"
+		}
+		return msg
+	}
+}
+
+func (m Model) pollDownloads() tea.Cmd {
+	ids := append([]string(nil), m.downloads...)
+	return func() tea.Msg {
+		reporter, ok := downloadClient.(core.ProgressReporter)
+		if !ok || len(ids) == 0 {
+			return downloadsStatusMsg{}
+		}
+		statuses, err := reporter.Status(ids)
+		return downloadsStatusMsg{statuses: statuses, err: err}
+	}
+}
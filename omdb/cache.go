@@ -0,0 +1,280 @@
+package omdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTTL keeps found titles for a week; ratings and vote counts
+	// drift slowly.
+	DefaultTTL = 7 * 24 * time.Hour
+	// DefaultNegativeTTL keeps "not found" answers for a day, so new
+	// releases show up once OMDB lists them.
+	DefaultNegativeTTL = 24 * time.Hour
+
+	// saveDelay batches the writes of a burst of lookups into one save.
+	saveDelay = 2 * time.Second
+
+	cacheVersion = 1
+)
+
+// Cache is a persistent store of OMDB responses, shared by every c-cli
+// process through one JSON file. A nil *Cache caches nothing. It is safe for
+// concurrent use.
+type Cache struct {
+	TTL         time.Duration // Of found entries; DefaultTTL when zero
+	NegativeTTL time.Duration // Of "not found" entries; DefaultNegativeTTL when zero
+
+	path    string
+	mu      sync.Mutex
+	entries map[string]entry
+	pending *time.Timer // Scheduled save, nil when clean
+}
+
+// entry is one cached response. Data is the raw OMDB JSON; nil records that
+// OMDB had no match.
+type entry struct {
+	Data    json.RawMessage `json:"data,omitempty"`
+	Fetched time.Time       `json:"fetched"`
+}
+
+type cacheFile struct {
+	Version int              `json:"version"`
+	Entries map[string]entry `json:"entries"`
+}
+
+// Stats describes the contents of a cache.
+type Stats struct {
+	Path     string `json:"path,omitempty"`
+	Entries  int    `json:"entries"`
+	Negative int    `json:"negative"` // "Not found" entries
+	Expired  int    `json:"expired"`  // Entries past their TTL, dropped by Prune
+	Bytes    int64  `json:"bytes"`    // Size on disk
+}
+
+// DefaultPath is the cache file under the user's cache directory.
+func DefaultPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "c-cli", "omdb.json"), nil
+}
+
+// Open loads the cache at path. A missing file is an empty cache.
+func Open(path string) (*Cache, error) {
+	entries, err := readCacheFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Cache{path: path, entries: entries}, nil
+}
+
+func readCacheFile(path string) (map[string]entry, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]entry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f cacheFile
+	if err := json.Unmarshal(data, &f); err != nil || f.Version != cacheVersion {
+		// A corrupt or outdated cache is just an empty one
+		return map[string]entry{}, nil
+	}
+	if f.Entries == nil {
+		f.Entries = map[string]entry{}
+	}
+	return f.Entries, nil
+}
+
+// IDKey is the cache key of a lookup by IMDb ID.
+func IDKey(imdbID string) string {
+	return "id:" + strings.ToLower(strings.TrimSpace(imdbID))
+}
+
+var nonAlnum = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+// QueryKey is the cache key of a title search. Titles are normalised so
+// "The Matrix", "the.matrix" and "THE MATRIX!" share an entry; a zero year
+// or empty mediaType means unrestricted.
+func QueryKey(title string, year int, mediaType string) string {
+	norm := strings.TrimSpace(nonAlnum.ReplaceAllString(strings.ToLower(title), " "))
+	return "q:" + norm + "|" + strconv.Itoa(year) + "|" + strings.ToLower(mediaType)
+}
+
+// Get returns the cached response for key. ok reports a fresh hit; found
+// is false when the hit records that OMDB had no match.
+func (c *Cache) Get(key string) (data []byte, found, ok bool) {
+	if c == nil {
+		return nil, false, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, exists := c.entries[key]
+	if !exists || c.expired(e, time.Now()) {
+		return nil, false, false
+	}
+	return e.Data, e.Data != nil, true
+}
+
+// Put stores a response for key; nil data records a "not found". The file
+// is written shortly after, together with any other puts in the meantime.
+func (c *Cache) Put(key string, data []byte) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = entry{Data: data, Fetched: time.Now()}
+	if c.pending == nil {
+		c.pending = time.AfterFunc(saveDelay, func() { c.Save() })
+	}
+}
+
+func (c *Cache) expired(e entry, now time.Time) bool {
+	ttl := c.TTL
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	if e.Data == nil {
+		ttl = c.NegativeTTL
+		if ttl <= 0 {
+			ttl = DefaultNegativeTTL
+		}
+	}
+	return now.Sub(e.Fetched) > ttl
+}
+
+// Save writes pending changes to disk, merging in entries other processes
+// saved since the cache was opened; the most recently fetched copy of a key
+// wins. Expired entries are dropped.
+func (c *Cache) Save() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pending == nil {
+		return nil // Nothing new since the last save
+	}
+	return c.save()
+}
+
+// save merges with the file on disk and writes it. c.mu must be held.
+func (c *Cache) save() error {
+	if c.pending != nil {
+		c.pending.Stop()
+		c.pending = nil
+	}
+
+	onDisk, err := readCacheFile(c.path)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for k, e := range onDisk {
+		if cur, ok := c.entries[k]; !ok || e.Fetched.After(cur.Fetched) {
+			c.entries[k] = e
+		}
+	}
+	for k, e := range c.entries {
+		if c.expired(e, now) {
+			delete(c.entries, k)
+		}
+	}
+	return c.write()
+}
+
+// write saves the cache file. c.mu must be held.
+func (c *Cache) write() error {
+	data, err := json.Marshal(cacheFile{Version: cacheVersion, Entries: c.entries})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(c.path, data)
+}
+
+// writeFileAtomic replaces path with data via a temp file and rename, so
+// readers in other processes never see a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("omdb: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("omdb: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("omdb: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("omdb: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("omdb: %w", err)
+	}
+	return nil
+}
+
+// Path is the cache file's location.
+func (c *Cache) Path() string { return c.path }
+
+// Stats counts the cache's entries.
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := Stats{Path: c.path, Entries: len(c.entries)}
+	now := time.Now()
+	for _, e := range c.entries {
+		if e.Data == nil {
+			s.Negative++
+		}
+		if c.expired(e, now) {
+			s.Expired++
+		}
+	}
+	if fi, err := os.Stat(c.path); err == nil {
+		s.Bytes = fi.Size()
+	}
+	return s
+}
+
+// Prune removes expired entries and returns how many were dropped.
+func (c *Cache) Prune() (int, error) {
+	n := c.Stats().Expired
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.save(); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// Clear removes every entry, on disk too.
+func (c *Cache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pending != nil {
+		c.pending.Stop()
+		c.pending = nil
+	}
+	c.entries = map[string]entry{}
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("omdb: %w", err)
+	}
+	return nil
+}
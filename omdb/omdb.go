@@ -0,0 +1,121 @@
+// Package omdb queries the OMDb API (omdbapi.com) through a persistent
+// cache, so repeated searches don't spend the daily request quota.
+package omdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const baseURL = "http://www.omdbapi.com/"
+
+// Client looks titles up on OMDB. Found and "not found" answers are cached;
+// errors such as an exhausted quota are not. Once the quota is used up,
+// lookups are answered from the cache only.
+type Client struct {
+	APIKey string
+	HTTP   *http.Client // http.DefaultClient when nil
+	Cache  *Cache       // Optional
+	Quota  *Quota       // Optional
+}
+
+// APIError is an error OMDB reported in its response, e.g. "Request limit
+// reached!" or "Invalid API key!".
+type APIError struct {
+	Message string
+}
+
+func (e *APIError) Error() string { return "omdb: " + e.Message }
+
+// ByID looks up a title by IMDb ID and decodes OMDB's response into v. It
+// reports false, with no error, when OMDB has no such title. The request is
+// abandoned when ctx is done.
+func (c *Client) ByID(ctx context.Context, imdbID string, v any) (bool, error) {
+	params := url.Values{}
+	params.Set("i", imdbID)
+	return c.get(ctx, IDKey(imdbID), params, v)
+}
+
+// Search finds the best match for title and decodes it into v. A zero year
+// or empty mediaType ("movie", "series", "episode") leaves that unrestricted.
+// It reports false, with no error, when nothing matches.
+func (c *Client) Search(ctx context.Context, title string, year int, mediaType string, v any) (bool, error) {
+	params := url.Values{}
+	params.Set("t", title)
+	if year > 0 {
+		params.Set("y", strconv.Itoa(year))
+	}
+	if mediaType != "" {
+		params.Set("type", mediaType)
+	}
+	return c.get(ctx, QueryKey(title, year, mediaType), params, v)
+}
+
+func (c *Client) get(ctx context.Context, key string, params url.Values, v any) (bool, error) {
+	if data, found, ok := c.Cache.Get(key); ok {
+		if !found {
+			return false, nil
+		}
+		return true, json.Unmarshal(data, v)
+	}
+
+	if !c.Quota.reserve(c.APIKey) {
+		return false, ErrQuotaExhausted
+	}
+
+	params.Set("apikey", c.APIKey)
+	hc := c.HTTP
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"?"+params.Encode(), nil)
+	if err != nil {
+		c.Quota.release(c.APIKey)
+		return false, err
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		c.Quota.release(c.APIKey)
+		return false, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return false, err
+	}
+
+	var status struct {
+		Response string `json:"Response"`
+		Error    string `json:"Error"`
+	}
+	if err := json.Unmarshal(data, &status); err != nil {
+		return false, fmt.Errorf("omdb: %w", err)
+	}
+	if status.Response == "False" && isQuotaError(status.Error) {
+		c.Quota.exhaust(c.APIKey)
+		return false, ErrQuotaExhausted
+	}
+	if status.Response == "False" {
+		if !isNotFound(status.Error) {
+			return false, &APIError{Message: status.Error}
+		}
+		c.Cache.Put(key, nil)
+		return false, nil
+	}
+
+	c.Cache.Put(key, data)
+	return true, json.Unmarshal(data, v)
+}
+
+// isNotFound reports whether an OMDB error message means there is no such
+// title, as opposed to a problem with the request or the key.
+func isNotFound(msg string) bool {
+	msg = strings.ToLower(msg)
+	return strings.Contains(msg, "not found") || strings.Contains(msg, "incorrect imdb id")
+}
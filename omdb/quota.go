@@ -0,0 +1,249 @@
+package omdb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDailyLimit is the request allowance of a free OMDB key.
+const DefaultDailyLimit = 1000
+
+// ErrQuotaExhausted is returned instead of calling OMDB once the key's daily
+// requests are used up. Cached answers are still served.
+var ErrQuotaExhausted = errors.New("omdb: daily request limit reached")
+
+// Quota counts requests per API key per day, so a key that has run out stops
+// being used until the next day. Counts are saved next to the cache and
+// shared by every c-cli process. A nil *Quota never limits. It is safe for
+// concurrent use.
+type Quota struct {
+	Limit int // Requests per day; DefaultDailyLimit when zero
+
+	path    string // Empty keeps counts in memory only
+	mu      sync.Mutex
+	usage   map[string]usage // By key fingerprint
+	unsaved map[string]usage // Requests since the last save, added to the file's counts
+	pending *time.Timer
+}
+
+type usage struct {
+	Day       string `json:"day"` // UTC, YYYY-MM-DD
+	Used      int    `json:"used"`
+	Exhausted bool   `json:"exhausted"` // OMDB said so, whatever Used is
+}
+
+// QuotaStatus is a key's usage for the current day.
+type QuotaStatus struct {
+	Used      int       `json:"used"`
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Exhausted bool      `json:"exhausted"`
+	Resets    time.Time `json:"resets"`
+}
+
+// QuotaPath is the usage file kept beside the cache at cachePath.
+func QuotaPath(cachePath string) string {
+	return filepath.Join(filepath.Dir(cachePath), "omdb-quota.json")
+}
+
+// OpenQuota loads request counts from path. An empty path or a missing file
+// starts from zero.
+func OpenQuota(path string) (*Quota, error) {
+	q := &Quota{path: path, unsaved: map[string]usage{}}
+	usage, err := readQuotaFile(path)
+	if err != nil {
+		return nil, err
+	}
+	q.usage = usage
+	return q, nil
+}
+
+func readQuotaFile(path string) (map[string]usage, error) {
+	if path == "" {
+		return map[string]usage{}, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]usage{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	u := map[string]usage{}
+	if err := json.Unmarshal(data, &u); err != nil {
+		return map[string]usage{}, nil
+	}
+	return u, nil
+}
+
+// fingerprint identifies an API key without storing it.
+func fingerprint(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:8])
+}
+
+func today() string { return time.Now().UTC().Format(time.DateOnly) }
+
+func (q *Quota) limit() int {
+	if q.Limit > 0 {
+		return q.Limit
+	}
+	return DefaultDailyLimit
+}
+
+// current returns today's usage of apiKey. q.mu must be held.
+func (q *Quota) current(apiKey string) usage {
+	u := q.usage[fingerprint(apiKey)]
+	if u.Day != today() {
+		return usage{Day: today()}
+	}
+	return u
+}
+
+// Status reports apiKey's usage for today.
+func (q *Quota) Status(apiKey string) QuotaStatus {
+	limit := DefaultDailyLimit
+	var u usage
+	if q != nil {
+		q.mu.Lock()
+		limit = q.limit()
+		u = q.current(apiKey)
+		q.mu.Unlock()
+	}
+	exhausted := u.Exhausted || u.Used >= limit
+	remaining := max(limit-u.Used, 0)
+	if exhausted {
+		remaining = 0
+	}
+	midnight := time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	return QuotaStatus{
+		Used:      u.Used,
+		Limit:     limit,
+		Remaining: remaining,
+		Exhausted: exhausted,
+		Resets:    midnight,
+	}
+}
+
+// reserve counts a request with apiKey before it is made, so concurrent
+// lookups can't overshoot the limit. It reports false, counting nothing,
+// once today's requests are used up.
+func (q *Quota) reserve(apiKey string) bool {
+	if q == nil {
+		return true
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	u := q.current(apiKey)
+	if u.Exhausted || u.Used >= q.limit() {
+		return false
+	}
+	q.add(apiKey, 1, false)
+	return true
+}
+
+// release gives back a reservation for a request that never reached OMDB.
+func (q *Quota) release(apiKey string) {
+	if q == nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.add(apiKey, -1, false)
+}
+
+// exhaust marks OMDB refusing apiKey for the rest of the day.
+func (q *Quota) exhaust(apiKey string) {
+	if q == nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.add(apiKey, 0, true)
+}
+
+// add changes apiKey's count for today by n and schedules a save. q.mu
+// must be held.
+func (q *Quota) add(apiKey string, n int, exhausted bool) {
+	k := fingerprint(apiKey)
+	u := q.current(apiKey)
+	u.Used = max(u.Used+n, 0)
+	u.Exhausted = u.Exhausted || exhausted
+	q.usage[k] = u
+	if q.path == "" {
+		return
+	}
+	d := q.unsaved[k]
+	if d.Day != u.Day {
+		d = usage{Day: u.Day}
+	}
+	d.Used += n
+	d.Exhausted = d.Exhausted || exhausted
+	q.unsaved[k] = d
+	if q.pending == nil {
+		q.pending = time.AfterFunc(saveDelay, func() { q.Save() })
+	}
+}
+
+// Save adds the requests made since the last save to the counts on disk,
+// so processes sharing the file each contribute their own requests, and
+// picks up what the others have saved.
+func (q *Quota) Save() error {
+	if q == nil || q.path == "" {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pending == nil {
+		return nil // Nothing new since the last save
+	}
+	q.pending.Stop()
+	q.pending = nil
+
+	merged, err := readQuotaFile(q.path)
+	if err != nil {
+		return err
+	}
+	day := today()
+	for k, d := range q.unsaved {
+		if d.Day != day {
+			continue
+		}
+		u := merged[k]
+		if u.Day != day {
+			u = usage{Day: day}
+		}
+		u.Used = max(u.Used+d.Used, 0)
+		u.Exhausted = u.Exhausted || d.Exhausted
+		merged[k] = u
+	}
+	for k, u := range merged {
+		if u.Day != day {
+			delete(merged, k)
+		}
+	}
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(q.path, data); err != nil {
+		return err
+	}
+	q.usage = merged
+	q.unsaved = map[string]usage{}
+	return nil
+}
+
+// isQuotaError reports whether an OMDB error message means the key has no
+// requests left today.
+func isQuotaError(msg string) bool {
+	return strings.Contains(strings.ToLower(msg), "limit reached")
+}
@@ -0,0 +1,92 @@
+package omdb
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func openTestQuota(t *testing.T, path string, limit int) *Quota {
+	t.Helper()
+	q, err := OpenQuota(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.Limit = limit
+	t.Cleanup(func() { q.Save() })
+	return q
+}
+
+func TestQuotaSharedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "omdb-quota.json")
+	a := openTestQuota(t, path, 100)
+	b := openTestQuota(t, path, 100)
+
+	for range 3 {
+		a.reserve("key")
+	}
+	for range 5 {
+		b.reserve("key")
+	}
+	if err := a.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Save(); err != nil {
+		t.Fatal(err)
+	}
+	// Both processes' requests count, not just the larger share
+	if got := b.Status("key").Used; got != 8 {
+		t.Errorf("b sees %d used after saving, want 8", got)
+	}
+
+	a.reserve("key")
+	if err := a.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if got := openTestQuota(t, path, 100).Status("key").Used; got != 9 {
+		t.Errorf("file holds %d used, want 9", got)
+	}
+}
+
+func TestQuotaReleaseAndExhaust(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "omdb-quota.json")
+	q := openTestQuota(t, path, 100)
+	q.reserve("key")
+	q.reserve("key")
+	q.release("key")
+	q.exhaust("key")
+	if err := q.Save(); err != nil {
+		t.Fatal(err)
+	}
+	s := openTestQuota(t, path, 100).Status("key")
+	if s.Used != 1 || !s.Exhausted || s.Remaining != 0 {
+		t.Errorf("status = %+v, want 1 used and exhausted", s)
+	}
+	if q.reserve("key") {
+		t.Error("reserved a request on an exhausted key")
+	}
+}
+
+func TestQuotaReserveConcurrent(t *testing.T) {
+	q := openTestQuota(t, "", 10)
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		granted int
+	)
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if q.reserve("key") {
+				mu.Lock()
+				granted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if granted != 10 {
+		t.Errorf("granted %d requests, want the limit of 10", granted)
+	}
+}
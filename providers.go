@@ -0,0 +1,32 @@
+package main
+
+import "c-cli/core"
+
+// defaultSource is the search_source from config, falling back to YTS.
+func defaultSource() core.SearchSource {
+	if _, ok := lib.Provider(core.SearchSource(config.SearchSource)); ok {
+		return core.SearchSource(config.SearchSource)
+	}
+	return core.SourceYTS
+}
+
+// nextProvider returns the source registered after the given one, wrapping around.
+func nextProvider(source core.SearchSource) core.SearchSource {
+	providers := lib.Providers()
+	if len(providers) == 0 {
+		return source
+	}
+	for i, p := range providers {
+		if p.Source() == source {
+			return providers[(i+1)%len(providers)].Source()
+		}
+	}
+	return providers[0].Source()
+}
+
+// hasDetails reports whether results from source need a details lookup,
+// i.e. they are movie listings rather than individual torrents.
+func hasDetails(source core.SearchSource) bool {
+	p, ok := lib.Provider(source)
+	return ok && p.Capabilities().Details
+}
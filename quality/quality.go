@@ -0,0 +1,302 @@
+// Package quality picks the torrent to grab out of a title's releases,
+// following a named profile from config.toml instead of a fixed ranking.
+package quality
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"c-cli/release"
+)
+
+// Profile says which releases are acceptable and which are preferred. The
+// zero Profile accepts anything and prefers the best-seeded release.
+type Profile struct {
+	Allowed   []string `toml:"allowed"`   // Resolutions to consider; any when empty
+	Preferred []string `toml:"preferred"` // Resolutions, most wanted first
+
+	Sizes      map[string]SizeRange `toml:"sizes"` // By resolution, e.g. 1080p = { max = "8GB" }
+	MinSeeders int                  `toml:"min_seeders"`
+	// SeedRatio ranks releases with under 1/SeedRatio of the best-seeded
+	// one's seeds below all others, whatever their resolution; off when zero
+	SeedRatio int `toml:"seed_ratio"`
+
+	Codecs  []string `toml:"codecs"`  // e.g. ["x265", "x264"]; most wanted first
+	Sources []string `toml:"sources"` // e.g. ["BluRay", "WEB-DL"]
+	Types   []string `toml:"types"`   // YTS release types: "bluray", "web"
+
+	BannedGroups []string `toml:"banned_groups"`
+	BannedWords  []string `toml:"banned_words"` // Matched against the release name
+}
+
+// Default is used when no profile is configured: the highest resolution
+// that is reasonably well seeded, so a 2160p with one seed doesn't beat a
+// 1080p with hundreds.
+var Default = Profile{
+	Preferred:  []string{"2160p", "1080p", "720p"},
+	MinSeeders: 1,
+	SeedRatio:  10,
+}
+
+// SizeRange bounds the size of one resolution's releases. A zero bound is
+// open.
+type SizeRange struct {
+	Min Size `toml:"min"`
+	Max Size `toml:"max"`
+}
+
+// Size is a byte count written in config as "700MB", "1.5 GB" or "4GiB".
+type Size int64
+
+func (s *Size) UnmarshalText(text []byte) error {
+	str := strings.ToUpper(strings.TrimSpace(string(text)))
+	num := strings.TrimRight(str, "KMGTIB ")
+	unit := strings.TrimSpace(str[len(num):])
+	mult := map[string]float64{
+		"": 1, "B": 1,
+		"K": 1 << 10, "KB": 1 << 10, "KIB": 1 << 10,
+		"M": 1 << 20, "MB": 1 << 20, "MIB": 1 << 20,
+		"G": 1 << 30, "GB": 1 << 30, "GIB": 1 << 30,
+		"T": 1 << 40, "TB": 1 << 40, "TIB": 1 << 40,
+	}[unit]
+	n, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+	if err != nil || mult == 0 || n < 0 {
+		return fmt.Errorf("invalid size %q", text)
+	}
+	*s = Size(n * mult)
+	return nil
+}
+
+func (s Size) String() string {
+	const unit = 1024
+	if s < unit {
+		return fmt.Sprintf("%d B", s)
+	}
+	div, exp := int64(unit), 0
+	for n := int64(s) / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.2f %cB", float64(s)/float64(div), "KMGTPE"[exp])
+}
+
+// Candidate is one torrent as the profile sees it. Providers fill in what
+// they know; anything else is read from Name.
+type Candidate struct {
+	Name    string // Release name, when the provider has one
+	Quality string // Resolution as the provider reports it, e.g. "1080p"
+	Type    string // YTS release type
+	Codec   string // YTS video codec
+	Size    int64
+	Seeds   int
+}
+
+// Choice is the outcome of Pick.
+type Choice struct {
+	Index int      // Of the chosen candidate; -1 when none fits the profile
+	Why   []string // What won it, or what ruled each candidate out
+}
+
+func (c Choice) String() string { return strings.Join(c.Why, ", ") }
+
+// facts is what a candidate turned out to be once its name was parsed.
+type facts struct {
+	resolution string
+	source     string
+	codec      string
+	group      string
+	name       string
+}
+
+func describe(c Candidate) facts {
+	rel := release.Parse(c.Name)
+	f := facts{
+		resolution: strings.ToLower(c.Quality),
+		source:     rel.Source,
+		codec:      canonicalCodec(c.Codec),
+		group:      rel.Group,
+		name:       strings.ToLower(c.Name),
+	}
+	if rel.Resolution != "" && (f.resolution == "" || f.resolution == "full") {
+		f.resolution = strings.ToLower(rel.Resolution)
+	}
+	if f.codec == "" {
+		f.codec = rel.Codec
+	}
+	return f
+}
+
+// Pick chooses the candidate p likes best: among those it allows, the
+// well-seeded ones first when SeedRatio is set, then the most preferred
+// resolution, then source, codec and release type, then seeds.
+func (p Profile) Pick(cands []Candidate) Choice {
+	allowed := make([]bool, len(cands))
+	var rejected []string
+	maxSeeds := 0
+	for i, c := range cands {
+		f := describe(c)
+		if why := p.reject(c, f); why != "" {
+			rejected = append(rejected, fmt.Sprintf("%s %s", label(f), why))
+			continue
+		}
+		allowed[i] = true
+		maxSeeds = max(maxSeeds, c.Seeds)
+	}
+
+	best, bestRank := -1, []int(nil)
+	for i, c := range cands {
+		if !allowed[i] {
+			continue
+		}
+		f := describe(c)
+		rank := []int{
+			p.starved(c.Seeds, maxSeeds),
+			rankOf(p.Preferred, f.resolution, fold),
+			rankOf(p.Sources, f.source, foldSource),
+			rankOf(p.Codecs, f.codec, canonicalCodec),
+			rankOf(p.Types, c.Type, fold),
+			-c.Seeds,
+		}
+		if best < 0 || slices.Compare(rank, bestRank) < 0 {
+			best, bestRank = i, rank
+		}
+	}
+	if best < 0 {
+		if len(rejected) == 0 {
+			rejected = []string{"no torrents"}
+		}
+		return Choice{Index: -1, Why: rejected}
+	}
+	return Choice{Index: best, Why: p.explain(cands[best], describe(cands[best]), len(rejected))}
+}
+
+// starved is 1 when seeds falls under p's share of maxSeeds, else 0.
+func (p Profile) starved(seeds, maxSeeds int) int {
+	if p.SeedRatio > 0 && seeds*p.SeedRatio < maxSeeds {
+		return 1
+	}
+	return 0
+}
+
+// reject says why p turns c down, or "" if it doesn't.
+func (p Profile) reject(c Candidate, f facts) string {
+	if len(p.Allowed) > 0 && !slices.ContainsFunc(p.Allowed, func(a string) bool { return fold(a) == fold(f.resolution) }) {
+		return "not allowed"
+	}
+	if c.Seeds < p.MinSeeders {
+		return fmt.Sprintf("has %d seeds, needs %d", c.Seeds, p.MinSeeders)
+	}
+	for res, r := range p.Sizes {
+		if fold(res) != fold(f.resolution) || c.Size <= 0 {
+			continue
+		}
+		if r.Min > 0 && c.Size < int64(r.Min) {
+			return fmt.Sprintf("is %s, under %s", Size(c.Size), r.Min)
+		}
+		if r.Max > 0 && c.Size > int64(r.Max) {
+			return fmt.Sprintf("is %s, over %s", Size(c.Size), r.Max)
+		}
+	}
+	if f.group != "" && slices.ContainsFunc(p.BannedGroups, func(g string) bool { return fold(g) == fold(f.group) }) {
+		return fmt.Sprintf("is from banned group %s", f.group)
+	}
+	for _, w := range p.BannedWords {
+		if w != "" && containsWord(f.name, strings.ToLower(w)) {
+			return fmt.Sprintf("mentions %q", w)
+		}
+	}
+	return ""
+}
+
+// explain lists what made c the pick.
+func (p Profile) explain(c Candidate, f facts, rejected int) []string {
+	var why []string
+	if n := rankOf(p.Preferred, f.resolution, fold); n < len(p.Preferred) {
+		why = append(why, fmt.Sprintf("%s is preferred resolution #%d", f.resolution, n+1))
+	} else if f.resolution != "" {
+		why = append(why, f.resolution)
+	}
+	if rankOf(p.Sources, f.source, foldSource) < len(p.Sources) {
+		why = append(why, "preferred source "+f.source)
+	}
+	if rankOf(p.Codecs, f.codec, canonicalCodec) < len(p.Codecs) {
+		why = append(why, "preferred codec "+f.codec)
+	}
+	if rankOf(p.Types, c.Type, fold) < len(p.Types) {
+		why = append(why, "preferred type "+c.Type)
+	}
+	why = append(why, fmt.Sprintf("%d seeds", c.Seeds))
+	if rejected > 0 {
+		why = append(why, fmt.Sprintf("%d ruled out", rejected))
+	}
+	return why
+}
+
+func label(f facts) string {
+	if f.resolution == "" {
+		return "torrent"
+	}
+	return f.resolution
+}
+
+// rankOf is v's position in list, or len(list) when it isn't there.
+func rankOf(list []string, v string, norm func(string) string) int {
+	if v == "" {
+		return len(list)
+	}
+	if i := slices.IndexFunc(list, func(s string) bool { return norm(s) == norm(v) }); i >= 0 {
+		return i
+	}
+	return len(list)
+}
+
+func fold(s string) string { return strings.ToLower(strings.TrimSpace(s)) }
+
+// foldSource lets "WEB-DL", "webdl" and "web" name the same source.
+func foldSource(s string) string {
+	s = strings.NewReplacer("-", "", " ", "").Replace(fold(s))
+	if s == "web" {
+		return "webdl"
+	}
+	return s
+}
+
+// canonicalCodec maps the many spellings of a codec onto the names the
+// release parser uses.
+func canonicalCodec(s string) string {
+	switch strings.NewReplacer(".", "", "-", "", " ", "").Replace(fold(s)) {
+	case "":
+		return ""
+	case "x264", "h264", "avc":
+		return "H.264"
+	case "x265", "h265", "hevc":
+		return "H.265"
+	case "av1":
+		return "AV1"
+	case "xvid":
+		return "XviD"
+	}
+	return s
+}
+
+// containsWord reports whether w appears in name as a whole word, so that
+// banning "cam" doesn't ban "Camelot".
+func containsWord(name, w string) bool {
+	for i := 0; ; {
+		j := strings.Index(name[i:], w)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(w)
+		if (start == 0 || !isWordByte(name[start-1])) && (end == len(name) || !isWordByte(name[end])) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+func isWordByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= '0' && b <= '9'
+}
@@ -0,0 +1,101 @@
+package quality
+
+import "testing"
+
+func TestPick(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile Profile
+		cands   []Candidate
+		want    int
+	}{
+		{
+			"default passes over a starved 2160p",
+			Default,
+			[]Candidate{
+				{Name: "Dune.2021.2160p.WEB-DL.DDP5.1.HDR.H.265-FLUX", Seeds: 1},
+				{Name: "Dune.2021.1080p.BluRay.x264-SPARKS", Seeds: 500},
+			},
+			1,
+		},
+		{
+			"default takes a healthy 2160p",
+			Default,
+			[]Candidate{
+				{Name: "Dune.2021.2160p.WEB-DL.DDP5.1.HDR.H.265-FLUX", Seeds: 80},
+				{Name: "Dune.2021.1080p.BluRay.x264-SPARKS", Seeds: 500},
+			},
+			0,
+		},
+		{
+			"default skips unseeded releases",
+			Default,
+			[]Candidate{
+				{Quality: "2160p", Seeds: 0},
+				{Quality: "720p", Seeds: 3},
+			},
+			1,
+		},
+		{
+			"without a seed ratio resolution wins",
+			Profile{Preferred: []string{"2160p", "1080p"}},
+			[]Candidate{
+				{Quality: "2160p", Seeds: 1},
+				{Quality: "1080p", Seeds: 500},
+			},
+			0,
+		},
+		{
+			"starved releases still beat nothing",
+			Profile{Preferred: Default.Preferred, SeedRatio: 10, Sizes: map[string]SizeRange{"1080p": {Max: 8 << 30}}},
+			[]Candidate{
+				{Quality: "2160p", Seeds: 1, Size: 1},
+				{Quality: "1080p", Seeds: 500, Size: 10 << 30},
+			},
+			0,
+		},
+		{
+			"zero profile takes the best seeded",
+			Profile{},
+			[]Candidate{
+				{Quality: "720p", Seeds: 10},
+				{Quality: "1080p", Seeds: 30},
+			},
+			1,
+		},
+		{
+			"banned group and word",
+			Profile{BannedGroups: []string{"EVO"}, BannedWords: []string{"cam", "hdcam"}},
+			[]Candidate{
+				{Name: "Barbie.2023.HDCAM.x264-NoGrp", Seeds: 900},
+				{Name: "Barbie.2023.CAM.x264-NoGrp", Seeds: 800},
+				{Name: "Barbie.2023.1080p.WEBRip.x265-EVO", Seeds: 700},
+				{Name: "Barbie.2023.Camelot.1080p.WEB-DL-FLUX", Seeds: 5},
+			},
+			3,
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.profile.Pick(tt.cands); got.Index != tt.want {
+			t.Errorf("%s: picked %d (%s), want %d", tt.name, got.Index, got, tt.want)
+		}
+	}
+}
+
+func TestSizeUnmarshal(t *testing.T) {
+	for in, want := range map[string]Size{
+		"700MB":  700 << 20,
+		"1.5 GB": 3 << 29,
+		"4GiB":   4 << 30,
+		"512":    512,
+	} {
+		var s Size
+		if err := s.UnmarshalText([]byte(in)); err != nil || s != want {
+			t.Errorf("%q = %d, %v; want %d", in, s, err, want)
+		}
+	}
+	var s Size
+	if err := s.UnmarshalText([]byte("lots")); err == nil {
+		t.Error(`"lots" parsed as a size`)
+	}
+}
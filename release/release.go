@@ -0,0 +1,678 @@
+// Package release parses torrent release names such as
+// "The.Matrix.1999.REMASTERED.1080p.BluRay.x264.DTS-HD.MA.5.1-FGT" into the
+// title, year, episode and quality attributes they encode.
+package release
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Release is what a release name says about its contents. Attributes the
+// name doesn't mention are left empty.
+type Release struct {
+	Title    string
+	Year     int
+	Seasons  []int // Every season included, e.g. [1 2 3] for S01-S03
+	Episodes []int // Every episode included, e.g. [4 5] for S01E04E05
+	Complete bool  // A complete season or series pack
+
+	Resolution string // "2160p", "1080p", "720p", ...
+	Source     string // "BluRay", "WEB-DL", "WEBRip", "HDTV", "DVDRip", ...
+	Remux      bool
+	Codec      string // "H.264", "H.265", "AV1", "XviD", ...
+	BitDepth   int
+	HDR        []string // "HDR10", "HDR10+", "DV", "HLG", ...
+	Audio      []string // "DTS-HD MA", "TrueHD", "Atmos", "DD+", "AAC", ...
+	Channels   string   // "5.1", "7.1", "2.0", ...
+	Languages  []string
+	Edition    string // "Extended", "Director's Cut", "Remastered", ...
+	Group      string
+	Proper     bool
+	Repack     bool
+
+	series bool // "complete series" or "all seasons" without numbers
+}
+
+// IsTV reports whether the release is a TV episode, season or series.
+func (r Release) IsTV() bool {
+	return r.series || len(r.Seasons) > 0 || len(r.Episodes) > 0
+}
+
+// Name is the title with the episode tag a viewer needs to tell episodes of
+// the same show apart, e.g. "Breaking Bad S01E05" or "The Wire S01-S05".
+func (r Release) Name() string {
+	if tag := r.EpisodeTag(); tag != "" {
+		return r.Title + " " + tag
+	}
+	return r.Title
+}
+
+// EpisodeTag formats the seasons and episodes as S01E02, S01E02-E04, S01-S03,
+// S01 or E05; empty for a movie.
+func (r Release) EpisodeTag() string {
+	var b strings.Builder
+	if n := len(r.Seasons); n > 0 {
+		fmt.Fprintf(&b, "S%02d", r.Seasons[0])
+		if n > 1 && len(r.Episodes) == 0 {
+			fmt.Fprintf(&b, "-S%02d", r.Seasons[n-1])
+		}
+	}
+	if n := len(r.Episodes); n > 0 {
+		fmt.Fprintf(&b, "E%02d", r.Episodes[0])
+		if n > 1 {
+			fmt.Fprintf(&b, "-E%02d", r.Episodes[n-1])
+		}
+	}
+	return b.String()
+}
+
+// A token is one word of the name. key is its lower-case form without
+// hyphens, so that "WEB-DL", "web-dl" and "WEBDL" all read the same.
+type token struct {
+	text string
+	key  string
+}
+
+// role is how a matched word bears on where the title ends. Strong words
+// never appear in titles; weak ones ("4K", "FRENCH", "EXTENDED") might, so
+// they only end the title when written in capitals, as scene names do.
+type role int
+
+const (
+	strong role = iota
+	weak
+)
+
+// A rule recognises one word or phrase. words are token keys; apply records
+// what the phrase means.
+type rule struct {
+	words []string
+	role  role
+	apply func(*Release)
+}
+
+func source(s string) func(*Release)  { return func(r *Release) { r.Source = s } }
+func codec(c string) func(*Release)   { return func(r *Release) { r.Codec = c } }
+func edition(e string) func(*Release) { return func(r *Release) { r.Edition = e } }
+func resolution(s string) func(*Release) {
+	return func(r *Release) { r.Resolution = s }
+}
+func bitDepth(n int) func(*Release) { return func(r *Release) { r.BitDepth = n } }
+func hdr(h string) func(*Release)   { return func(r *Release) { r.HDR = appendNew(r.HDR, h) } }
+func audio(a string) func(*Release) { return func(r *Release) { r.Audio = appendNew(r.Audio, a) } }
+func language(l string) func(*Release) {
+	return func(r *Release) { r.Languages = appendNew(r.Languages, l) }
+}
+
+// rules lists every phrase the parser knows, longest first so that
+// "DTS-HD MA" wins over "DTS-HD" and "Director's Cut" over a lone "Cut".
+var rules = func() []rule {
+	rs := []rule{
+		{words: []string{"4k"}, role: weak, apply: resolution("2160p")},
+		{words: []string{"uhd"}, role: weak, apply: resolution("2160p")},
+
+		{words: []string{"bluray"}, apply: source("BluRay")},
+		{words: []string{"blu", "ray"}, apply: source("BluRay")},
+		{words: []string{"bdrip"}, apply: source("BluRay")},
+		{words: []string{"brrip"}, apply: source("BluRay")},
+		{words: []string{"bd25"}, apply: source("BluRay")},
+		{words: []string{"bd50"}, apply: source("BluRay")},
+		{words: []string{"bdmv"}, apply: source("BluRay")},
+		{words: []string{"bdremux"}, apply: func(r *Release) { r.Source, r.Remux = "BluRay", true }},
+		{words: []string{"remux"}, apply: func(r *Release) { r.Remux = true }},
+		{words: []string{"webdl"}, apply: source("WEB-DL")},
+		{words: []string{"web", "dl"}, apply: source("WEB-DL")},
+		{words: []string{"web"}, role: weak, apply: source("WEB-DL")},
+		{words: []string{"webrip"}, apply: source("WEBRip")},
+		{words: []string{"web", "rip"}, apply: source("WEBRip")},
+		{words: []string{"hdtv"}, apply: source("HDTV")},
+		{words: []string{"hdtvrip"}, apply: source("HDTV")},
+		{words: []string{"pdtv"}, apply: source("TVRip")},
+		{words: []string{"sdtv"}, apply: source("TVRip")},
+		{words: []string{"tvrip"}, apply: source("TVRip")},
+		{words: []string{"dvdrip"}, apply: source("DVDRip")},
+		{words: []string{"dvd"}, apply: source("DVD")},
+		{words: []string{"dvdr"}, apply: source("DVD")},
+		{words: []string{"dvd5"}, apply: source("DVD")},
+		{words: []string{"dvd9"}, apply: source("DVD")},
+		{words: []string{"hdrip"}, apply: source("HDRip")},
+		{words: []string{"hdcam"}, apply: source("CAM")},
+		{words: []string{"camrip"}, apply: source("CAM")},
+		{words: []string{"cam"}, role: weak, apply: source("CAM")},
+		{words: []string{"hdts"}, apply: source("TS")},
+		{words: []string{"telesync"}, apply: source("TS")},
+
+		{words: []string{"x264"}, apply: codec("H.264")},
+		{words: []string{"h264"}, apply: codec("H.264")},
+		{words: []string{"h", "264"}, apply: codec("H.264")},
+		{words: []string{"avc"}, apply: codec("H.264")},
+		{words: []string{"x265"}, apply: codec("H.265")},
+		{words: []string{"h265"}, apply: codec("H.265")},
+		{words: []string{"h", "265"}, apply: codec("H.265")},
+		{words: []string{"hevc"}, apply: codec("H.265")},
+		{words: []string{"av1"}, apply: codec("AV1")},
+		{words: []string{"vp9"}, apply: codec("VP9")},
+		{words: []string{"xvid"}, apply: codec("XviD")},
+		{words: []string{"divx"}, apply: codec("DivX")},
+		{words: []string{"mpeg2"}, apply: codec("MPEG-2")},
+		{words: []string{"8bit"}, apply: bitDepth(8)},
+		{words: []string{"10bit"}, apply: bitDepth(10)},
+		{words: []string{"10", "bit"}, apply: bitDepth(10)},
+		{words: []string{"hi10p"}, apply: bitDepth(10)},
+		{words: []string{"12bit"}, apply: bitDepth(12)},
+
+		{words: []string{"hdr"}, apply: hdr("HDR")},
+		{words: []string{"hdr10"}, apply: hdr("HDR10")},
+		{words: []string{"hdr10+"}, apply: hdr("HDR10+")},
+		{words: []string{"hdr10plus"}, apply: hdr("HDR10+")},
+		{words: []string{"dolby", "vision"}, apply: hdr("DV")},
+		{words: []string{"dovi"}, apply: hdr("DV")},
+		{words: []string{"dv"}, role: weak, apply: hdr("DV")},
+		{words: []string{"hlg"}, apply: hdr("HLG")},
+
+		{words: []string{"dtshd", "ma"}, apply: audio("DTS-HD MA")},
+		{words: []string{"dts", "hd", "ma"}, apply: audio("DTS-HD MA")},
+		{words: []string{"dtshdma"}, apply: audio("DTS-HD MA")},
+		{words: []string{"dtshd"}, apply: audio("DTS-HD")},
+		{words: []string{"dts", "hd"}, apply: audio("DTS-HD")},
+		{words: []string{"dtsx"}, apply: audio("DTS:X")},
+		{words: []string{"dts", "x"}, apply: audio("DTS:X")},
+		{words: []string{"dts"}, apply: audio("DTS")},
+		{words: []string{"truehd"}, apply: audio("TrueHD")},
+		{words: []string{"atmos"}, apply: audio("Atmos")},
+		{words: []string{"ddp"}, apply: audio("DD+")},
+		{words: []string{"dd+"}, apply: audio("DD+")},
+		{words: []string{"eac3"}, apply: audio("DD+")},
+		{words: []string{"dd"}, apply: audio("DD")},
+		{words: []string{"ac3"}, apply: audio("DD")},
+		{words: []string{"aac"}, apply: audio("AAC")},
+		{words: []string{"flac"}, apply: audio("FLAC")},
+		{words: []string{"mp3"}, apply: audio("MP3")},
+		{words: []string{"opus"}, apply: audio("Opus")},
+		{words: []string{"lpcm"}, apply: audio("LPCM")},
+
+		{words: []string{"multi"}, role: weak, apply: language("Multi")},
+		{words: []string{"dual", "audio"}, role: weak, apply: language("Dual Audio")},
+		{words: []string{"english"}, role: weak, apply: language("English")},
+		{words: []string{"french"}, role: weak, apply: language("French")},
+		{words: []string{"truefrench"}, role: weak, apply: language("French")},
+		{words: []string{"vff"}, role: weak, apply: language("French")},
+		{words: []string{"german"}, role: weak, apply: language("German")},
+		{words: []string{"spanish"}, role: weak, apply: language("Spanish")},
+		{words: []string{"castellano"}, role: weak, apply: language("Spanish")},
+		{words: []string{"latino"}, role: weak, apply: language("Spanish")},
+		{words: []string{"italian"}, role: weak, apply: language("Italian")},
+		{words: []string{"ita"}, role: weak, apply: language("Italian")},
+		{words: []string{"portuguese"}, role: weak, apply: language("Portuguese")},
+		{words: []string{"russian"}, role: weak, apply: language("Russian")},
+		{words: []string{"rus"}, role: weak, apply: language("Russian")},
+		{words: []string{"hindi"}, role: weak, apply: language("Hindi")},
+		{words: []string{"japanese"}, role: weak, apply: language("Japanese")},
+		{words: []string{"korean"}, role: weak, apply: language("Korean")},
+		{words: []string{"chinese"}, role: weak, apply: language("Chinese")},
+
+		{words: []string{"extended"}, role: weak, apply: edition("Extended")},
+		{words: []string{"extended", "cut"}, role: weak, apply: edition("Extended")},
+		{words: []string{"extended", "edition"}, role: weak, apply: edition("Extended")},
+		{words: []string{"directors", "cut"}, role: weak, apply: edition("Director's Cut")},
+		{words: []string{"director's", "cut"}, role: weak, apply: edition("Director's Cut")},
+		{words: []string{"dc"}, role: weak, apply: edition("Director's Cut")},
+		{words: []string{"final", "cut"}, role: weak, apply: edition("Final Cut")},
+		{words: []string{"theatrical"}, role: weak, apply: edition("Theatrical")},
+		{words: []string{"theatrical", "cut"}, role: weak, apply: edition("Theatrical")},
+		{words: []string{"unrated"}, role: weak, apply: edition("Unrated")},
+		{words: []string{"uncut"}, role: weak, apply: edition("Uncut")},
+		{words: []string{"remastered"}, role: weak, apply: edition("Remastered")},
+		{words: []string{"imax"}, role: weak, apply: edition("IMAX")},
+		{words: []string{"criterion"}, role: weak, apply: edition("Criterion")},
+		{words: []string{"special", "edition"}, role: weak, apply: edition("Special Edition")},
+		{words: []string{"limited"}, role: weak, apply: edition("Limited")},
+		{words: []string{"open", "matte"}, role: weak, apply: edition("Open Matte")},
+
+		{words: []string{"proper"}, role: weak, apply: func(r *Release) { r.Proper = true }},
+		{words: []string{"repack"}, role: weak, apply: func(r *Release) { r.Repack = true }},
+		{words: []string{"rerip"}, role: weak, apply: func(r *Release) { r.Repack = true }},
+
+		{words: []string{"complete"}, role: weak, apply: func(r *Release) { r.Complete = true }},
+		{words: []string{"the", "complete", "series"}, apply: func(r *Release) { r.Complete, r.series = true, true }},
+		{words: []string{"complete", "series"}, apply: func(r *Release) { r.Complete, r.series = true, true }},
+		{words: []string{"complete", "season"}, apply: func(r *Release) { r.Complete, r.series = true, true }},
+		{words: []string{"all", "seasons"}, apply: func(r *Release) { r.Complete, r.series = true, true }},
+	}
+	slices.SortStableFunc(rs, func(a, b rule) int { return len(b.words) - len(a.words) })
+	return rs
+}()
+
+var (
+	extRe     = regexp.MustCompile(`(?i)\.(mkv|mp4|avi|m4v|mov|wmv|webm|ts|torrent)$`)
+	siteRe    = regexp.MustCompile(`(?i)^\[?(www\.)?[a-z0-9-]+\.(com|org|net|to|mx|me|se|ws|lol)\]?\s*-\s*`)
+	leadTagRe = regexp.MustCompile(`^\s*[\[【]([^\]】]+)[\]】]\s*`)
+	tailTagRe = regexp.MustCompile(`\s*\[([^\[\]]*)\]\s*$`)
+
+	resRe      = regexp.MustCompile(`^(\d{3,4})[pi]$`)
+	dimsRe     = regexp.MustCompile(`^\d{3,4}x(\d{3,4})$`)
+	yearRe     = regexp.MustCompile(`^(19|20)\d{2}$`)
+	yearsRe    = regexp.MustCompile(`^((?:19|20)\d{2})-(?:19|20)\d{2}$`)
+	xEpisodeRe = regexp.MustCompile(`^(\d{1,2})x(\d{2,3})$`)
+	episodeRe  = regexp.MustCompile(`^(?:e|ep)(\d{1,4})$`)
+	seasonRe   = regexp.MustCompile(`^seasons?(\d{1,2})$`)
+	rangeRe    = regexp.MustCompile(`^(\d{1,4})(?:v\d)?(?:[-~](\d{1,4})(?:v\d)?)?$`)
+	audioChRe  = regexp.MustCompile(`^(aac|ddp|dd\+|dd|eac3|ac3|dts|truehd|flac|opus|lpcm)(\d\.\d)$`)
+	channelsRe = regexp.MustCompile(`^(\d\.\d)$`)
+	chRe       = regexp.MustCompile(`^(2|6|8)ch$`)
+)
+
+// Parse reads a release name. It never fails: a name it can't make sense of
+// comes back as a Title with nothing else set.
+func Parse(name string) Release {
+	var r Release
+	name = strings.TrimSpace(extRe.ReplaceAllString(strings.TrimSpace(name), ""))
+	name = siteRe.ReplaceAllString(name, "")
+
+	// Anime and fansub releases lead with the group: "[SubsPlease] Show - 01"
+	leadGroup := false
+	if m := leadTagRe.FindStringSubmatch(name); m != nil && len(m[0]) < len(name) {
+		r.Group, leadGroup = strings.TrimSpace(m[1]), true
+		name = name[len(m[0]):]
+	}
+	// Trailing [tags] hold attributes ("[1080p]") or the uploader ("[YTS.MX]")
+	var tails []string
+	for {
+		m := tailTagRe.FindStringSubmatchIndex(name)
+		if m == nil || m[0] == 0 {
+			break
+		}
+		tails = append(tails, name[m[2]:m[3]])
+		name = name[:m[0]]
+	}
+
+	toks := tokenize(name)
+	if !leadGroup {
+		toks = splitGroup(&r, toks)
+	}
+
+	// The title ends at the year or at the first word that can't be part of
+	// one, whichever comes first
+	end := len(toks)
+	firstStrong, markers := len(toks), make([]bool, len(toks))
+	for i := 0; i < len(toks); {
+		var probe Release
+		n, ro := match(&probe, toks, i, leadGroup)
+		if n == 0 {
+			i++
+			continue
+		}
+		if ro == strong || isCaps(toks[i].text) {
+			for j := i; j < i+n; j++ {
+				markers[j] = true
+			}
+		}
+		if ro == strong && i > 0 && firstStrong == len(toks) {
+			firstStrong = i
+		}
+		i += n
+	}
+	yearAt := findYear(toks, firstStrong)
+	if yearAt > 0 {
+		r.Year, _ = strconv.Atoi(toks[yearAt].key[:4])
+		end = yearAt
+	}
+	end = min(end, firstStrong)
+	for end > 1 && (markers[end-1] || toks[end-1].text == "-") {
+		end--
+	}
+
+	r.Title = joinTitle(toks[:end])
+	for i := end; i < len(toks); {
+		if i == yearAt {
+			i++
+			continue
+		}
+		n, _ := match(&r, toks, i, leadGroup)
+		i += max(n, 1)
+	}
+	for _, t := range tails {
+		tt := tokenize(t)
+		recognised := false
+		for i := 0; i < len(tt); {
+			n, _ := match(&r, tt, i, false)
+			if n > 0 {
+				recognised = true
+			} else if r.Year == 0 && yearRe.MatchString(tt[i].key) {
+				r.Year, _ = strconv.Atoi(tt[i].key)
+				recognised = true
+			} else if m := channelsRe.FindStringSubmatch(tt[i].key); m != nil {
+				// A tag of its own, as YTS writes "[5.1]"
+				r.Channels = m[1]
+				recognised = true
+			}
+			i += max(n, 1)
+		}
+		if !recognised && r.Group == "" {
+			r.Group = strings.TrimSpace(t)
+		}
+	}
+	if r.Title == "" {
+		r.Title = joinTitle(toks)
+	}
+	return r
+}
+
+// tokenize splits a name into words at spaces, dots, underscores and
+// brackets. A dot between two single digits is kept, so "5.1" and
+// "DDP5.1" survive as channel layouts.
+func tokenize(s string) []token {
+	b := []byte(s)
+	for i, c := range b {
+		switch c {
+		case '.':
+			if digitAt(b, i-1) && digitAt(b, i+1) && !digitAt(b, i-2) && !digitAt(b, i+2) {
+				continue
+			}
+			b[i] = ' '
+		case '_', '(', ')', '[', ']', '{', '}', ',':
+			b[i] = ' '
+		}
+	}
+	var toks []token
+	for _, f := range strings.Fields(string(b)) {
+		f = strings.Trim(f, "-")
+		if f == "" {
+			f = "-"
+		}
+		toks = append(toks, token{text: f, key: keyOf(f)})
+	}
+	return toks
+}
+
+func keyOf(s string) string {
+	if s == "-" {
+		return s
+	}
+	return strings.ReplaceAll(strings.ToLower(s), "-", "")
+}
+
+func digitAt(b []byte, i int) bool {
+	return i >= 0 && i < len(b) && b[i] >= '0' && b[i] <= '9'
+}
+
+// splitGroup takes the release group off "x264-GROUP" at the end of a scene
+// name. Hyphenated words that are themselves tags ("WEB-DL") or that
+// follow no tag at all ("Spider-Man") are left alone.
+func splitGroup(r *Release, toks []token) []token {
+	if len(toks) < 2 {
+		return toks
+	}
+	last := toks[len(toks)-1]
+	i := strings.LastIndexByte(last.text, '-')
+	if i <= 0 {
+		return toks
+	}
+	if n, _ := match(&Release{}, toks, len(toks)-1, false); n > 0 {
+		return toks
+	}
+	prefix := last.text[:i]
+	out := slices.Clone(toks)
+	n := len(out) - 1
+	out[n] = token{text: prefix, key: keyOf(prefix)}
+	// The tag may span two tokens, as "H 264" does once "H.264" is split
+	single, _ := match(&Release{}, out, n, false)
+	pair, _ := match(&Release{}, out, n-1, false)
+	if single == 0 && pair != 2 && (!yearRe.MatchString(prefix) || yearsRe.MatchString(last.text)) {
+		return toks
+	}
+	r.Group = last.text[i+1:]
+	return out
+}
+
+// findYear picks the release year out of the name: the last plausible year
+// before the first quality tag, so that "Blade Runner 2049 2017" and
+// "2001 A Space Odyssey 1968" both come out right. A series' "2002-2008"
+// counts as its first year. It returns the token index, or 0 when there is
+// none; a name never starts with its year.
+func findYear(toks []token, before int) int {
+	latest := time.Now().Year() + 1
+	found := 0
+	for i := 1; i < len(toks); i++ {
+		if !yearRe.MatchString(toks[i].key) && !yearsRe.MatchString(toks[i].text) {
+			continue
+		}
+		if y, _ := strconv.Atoi(toks[i].key[:4]); y > latest {
+			continue
+		}
+		if i > before {
+			if found == 0 {
+				found = i
+			}
+			break
+		}
+		found = i
+	}
+	return found
+}
+
+// match tries every rule at toks[i], recording what it finds in r. It
+// returns how many tokens were consumed, zero if none matched.
+func match(r *Release, toks []token, i int, anime bool) (int, role) {
+	if n := matchEpisode(r, toks, i, anime); n > 0 {
+		return n, strong
+	}
+	key := toks[i].key
+	if m := resRe.FindStringSubmatch(key); m != nil {
+		r.Resolution = m[1] + "p"
+		return 1, strong
+	}
+	if m := dimsRe.FindStringSubmatch(key); m != nil {
+		r.Resolution = m[1] + "p"
+		return 1, strong
+	}
+	if m := audioChRe.FindStringSubmatch(key); m != nil {
+		var probe Release
+		for _, ru := range rules {
+			if len(ru.words) == 1 && ru.words[0] == m[1] {
+				ru.apply(&probe)
+			}
+		}
+		r.Audio = appendNew(r.Audio, probe.Audio...)
+		r.Channels = m[2]
+		return 1, strong
+	}
+	if m := channelsRe.FindStringSubmatch(key); m != nil && i > 0 {
+		r.Channels = m[1]
+		return 1, weak
+	}
+	if m := chRe.FindStringSubmatch(key); m != nil {
+		r.Channels = map[string]string{"2": "2.0", "6": "5.1", "8": "7.1"}[m[1]]
+		return 1, strong
+	}
+next:
+	for _, ru := range rules {
+		if i+len(ru.words) > len(toks) {
+			continue
+		}
+		for j, w := range ru.words {
+			if toks[i+j].key != w {
+				continue next
+			}
+		}
+		ru.apply(r)
+		return len(ru.words), ru.role
+	}
+	return 0, weak
+}
+
+// matchEpisode reads the season and episode forms release names use:
+// S01E02, S01E02E03, S01E02-E04, S01E02-04, S01-S03, S01, S01 E02, 1x02,
+// E05, "Season 1", "Season 1-3", and for anime "- 01", "- 01-12" and
+// "- 01 ~ 12".
+func matchEpisode(r *Release, toks []token, i int, anime bool) int {
+	key := toks[i].key
+	text := strings.ToLower(toks[i].text)
+	if seasons, episodes, ok := parseSE(text); ok {
+		n := 1
+		if len(episodes) == 0 && i+1 < len(toks) {
+			if m := episodeRe.FindStringSubmatch(toks[i+1].key); m != nil {
+				e, _ := strconv.Atoi(m[1])
+				episodes, n = []int{e}, 2
+			}
+		}
+		r.Seasons = appendNew(r.Seasons, seasons...)
+		r.Episodes = appendNew(r.Episodes, episodes...)
+		return n
+	}
+	if m := xEpisodeRe.FindStringSubmatch(key); m != nil {
+		s, _ := strconv.Atoi(m[1])
+		e, _ := strconv.Atoi(m[2])
+		r.Seasons = appendNew(r.Seasons, s)
+		r.Episodes = appendNew(r.Episodes, e)
+		return 1
+	}
+	if m := episodeRe.FindStringSubmatch(key); m != nil && i > 0 {
+		e, _ := strconv.Atoi(m[1])
+		r.Episodes = appendNew(r.Episodes, e)
+		return 1
+	}
+	if m := seasonRe.FindStringSubmatch(key); m != nil {
+		s, _ := strconv.Atoi(m[1])
+		r.Seasons = appendNew(r.Seasons, s)
+		return 1
+	}
+	if (key == "season" || key == "seasons" || key == "episode") && i+1 < len(toks) {
+		if lo, hi, ok := numberRange(toks[i+1].text); ok {
+			if key == "episode" {
+				r.Episodes = appendNew(r.Episodes, span(lo, hi)...)
+			} else {
+				r.Seasons = appendNew(r.Seasons, span(lo, hi)...)
+			}
+			return 2
+		}
+	}
+	if anime && key == "-" && i > 0 && i+1 < len(toks) {
+		if lo, hi, ok := numberRange(toks[i+1].text); ok {
+			n := 2
+			if lo == hi && i+3 < len(toks) && toks[i+2].text == "~" {
+				if _, end, ok := numberRange(toks[i+3].text); ok && end > lo {
+					hi, n = end, 4
+				}
+			}
+			r.Episodes = appendNew(r.Episodes, span(lo, hi)...)
+			return n
+		}
+	}
+	return 0
+}
+
+// parseSE reads a whole token of the S01E02 family.
+func parseSE(s string) (seasons, episodes []int, ok bool) {
+	num := func() (int, bool) {
+		j := 0
+		for j < len(s) && j < 4 && s[j] >= '0' && s[j] <= '9' {
+			j++
+		}
+		if j == 0 {
+			return 0, false
+		}
+		n, _ := strconv.Atoi(s[:j])
+		s = s[j:]
+		return n, true
+	}
+	if !strings.HasPrefix(s, "s") {
+		return nil, nil, false
+	}
+	s = s[1:]
+	season, ok := num()
+	if !ok {
+		return nil, nil, false
+	}
+	seasons = []int{season}
+	for s != "" {
+		switch {
+		case s[0] == 'e':
+			s = s[1:]
+			e, ok := num()
+			if !ok {
+				return nil, nil, false
+			}
+			episodes = append(episodes, e)
+		case s[0] == '-':
+			s = s[1:]
+			ranged := &seasons
+			if len(episodes) > 0 {
+				ranged = &episodes
+				s = strings.TrimPrefix(s, "e")
+			} else {
+				s = strings.TrimPrefix(s, "s")
+			}
+			hi, ok := num()
+			if !ok {
+				return nil, nil, false
+			}
+			lo := (*ranged)[len(*ranged)-1]
+			*ranged = append(*ranged, span(lo+1, hi)...)
+		default:
+			return nil, nil, false
+		}
+	}
+	return seasons, episodes, true
+}
+
+// numberRange reads "3", "01v2", "1-5" or "1~5".
+func numberRange(s string) (lo, hi int, ok bool) {
+	m := rangeRe.FindStringSubmatch(s)
+	if m == nil {
+		return 0, 0, false
+	}
+	lo, _ = strconv.Atoi(m[1])
+	hi = lo
+	if m[2] != "" {
+		hi, _ = strconv.Atoi(m[2])
+	}
+	return lo, hi, hi >= lo
+}
+
+func span(lo, hi int) []int {
+	var out []int
+	for n := lo; n <= hi; n++ {
+		out = append(out, n)
+	}
+	return out
+}
+
+// isCaps reports whether a word is written in capitals, as scene tags are
+// ("EXTENDED", "FRENCH") and titles usually aren't.
+func isCaps(s string) bool {
+	letters := 0
+	for _, c := range s {
+		if c >= 'a' && c <= 'z' {
+			return false
+		}
+		if c >= 'A' && c <= 'Z' {
+			letters++
+		}
+	}
+	return letters > 0
+}
+
+func joinTitle(toks []token) string {
+	words := make([]string, 0, len(toks))
+	for _, t := range toks {
+		if t.text != "-" {
+			words = append(words, t.text)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+func appendNew[T comparable](s []T, vs ...T) []T {
+	for _, v := range vs {
+		if !slices.Contains(s, v) {
+			s = append(s, v)
+		}
+	}
+	return s
+}
@@ -0,0 +1,289 @@
+package release
+
+import (
+	"reflect"
+	"testing"
+)
+
+var parseTests = []struct {
+	name string
+	want Release
+}{
+	// Scene movies
+	{
+		"The.Matrix.1999.REMASTERED.1080p.BluRay.x264.DTS-HD.MA.5.1-FGT",
+		Release{Title: "The Matrix", Year: 1999, Edition: "Remastered", Resolution: "1080p", Source: "BluRay",
+			Codec: "H.264", Audio: []string{"DTS-HD MA"}, Channels: "5.1", Group: "FGT"},
+	},
+	{
+		"Inception.2010.1080p.BluRay.x264-SPARKS",
+		Release{Title: "Inception", Year: 2010, Resolution: "1080p", Source: "BluRay", Codec: "H.264", Group: "SPARKS"},
+	},
+	{
+		"The.Dark.Knight.2008.720p.BluRay.DTS.x264-ESiR",
+		Release{Title: "The Dark Knight", Year: 2008, Resolution: "720p", Source: "BluRay", Codec: "H.264",
+			Audio: []string{"DTS"}, Group: "ESiR"},
+	},
+	{
+		"Dune.Part.Two.2024.2160p.WEB-DL.DDP5.1.Atmos.DV.HDR.H.265-FLUX",
+		Release{Title: "Dune Part Two", Year: 2024, Resolution: "2160p", Source: "WEB-DL", Audio: []string{"DD+", "Atmos"},
+			Channels: "5.1", HDR: []string{"DV", "HDR"}, Codec: "H.265", Group: "FLUX"},
+	},
+	{
+		"Oppenheimer.2023.IMAX.2160p.UHD.BluRay.REMUX.HDR10.HEVC.TrueHD.Atmos.7.1-FraMeSToR",
+		Release{Title: "Oppenheimer", Year: 2023, Edition: "IMAX", Resolution: "2160p", Source: "BluRay", Remux: true,
+			HDR: []string{"HDR10"}, Codec: "H.265", Audio: []string{"TrueHD", "Atmos"}, Channels: "7.1", Group: "FraMeSToR"},
+	},
+	{
+		"Blade.Runner.1982.The.Final.Cut.1080p.BluRay.x264-AMIABLE",
+		Release{Title: "Blade Runner", Year: 1982, Edition: "Final Cut", Resolution: "1080p", Source: "BluRay",
+			Codec: "H.264", Group: "AMIABLE"},
+	},
+	{
+		"The.Lord.of.the.Rings.The.Fellowship.of.the.Ring.2001.EXTENDED.1080p.BluRay.x264-SiNNERS",
+		Release{Title: "The Lord of the Rings The Fellowship of the Ring", Year: 2001, Edition: "Extended",
+			Resolution: "1080p", Source: "BluRay", Codec: "H.264", Group: "SiNNERS"},
+	},
+	{
+		"Amelie.2001.FRENCH.720p.BluRay.x264-LOST",
+		Release{Title: "Amelie", Year: 2001, Languages: []string{"French"}, Resolution: "720p", Source: "BluRay",
+			Codec: "H.264", Group: "LOST"},
+	},
+	{
+		"Parasite.2019.KOREAN.1080p.WEBRip.x264.AAC2.0-NOGRP",
+		Release{Title: "Parasite", Year: 2019, Languages: []string{"Korean"}, Resolution: "1080p", Source: "WEBRip",
+			Codec: "H.264", Audio: []string{"AAC"}, Channels: "2.0", Group: "NOGRP"},
+	},
+	{
+		"Mad.Max.Fury.Road.2015.PROPER.1080p.BluRay.x264-GECKOS",
+		Release{Title: "Mad Max Fury Road", Year: 2015, Proper: true, Resolution: "1080p", Source: "BluRay",
+			Codec: "H.264", Group: "GECKOS"},
+	},
+	{
+		"Alien.1979.Directors.Cut.REPACK.720p.BluRay.x264-HD4U",
+		Release{Title: "Alien", Year: 1979, Edition: "Director's Cut", Repack: true, Resolution: "720p",
+			Source: "BluRay", Codec: "H.264", Group: "HD4U"},
+	},
+	{
+		"Spider-Man.No.Way.Home.2021.1080p.WEBRip.x265.10bit-RARBG",
+		Release{Title: "Spider-Man No Way Home", Year: 2021, Resolution: "1080p", Source: "WEBRip", Codec: "H.265",
+			BitDepth: 10, Group: "RARBG"},
+	},
+	{
+		"Pulp.Fiction.1994.DVDRip.XviD-SAPHiRE",
+		Release{Title: "Pulp Fiction", Year: 1994, Source: "DVDRip", Codec: "XviD", Group: "SAPHiRE"},
+	},
+	{
+		"Barbie.2023.HDCAM.x264-NoGrp",
+		Release{Title: "Barbie", Year: 2023, Source: "CAM", Codec: "H.264", Group: "NoGrp"},
+	},
+	{
+		"Gladiator.2000.UNRATED.MULTi.1080p.BluRay.AC3.x264-FHD",
+		Release{Title: "Gladiator", Year: 2000, Edition: "Unrated", Languages: []string{"Multi"}, Resolution: "1080p",
+			Source: "BluRay", Audio: []string{"DD"}, Codec: "H.264", Group: "FHD"},
+	},
+
+	// Years in titles
+	{
+		"Blade.Runner.2049.2017.1080p.BluRay.x264-SPARKS",
+		Release{Title: "Blade Runner 2049", Year: 2017, Resolution: "1080p", Source: "BluRay", Codec: "H.264", Group: "SPARKS"},
+	},
+	{
+		"2001.A.Space.Odyssey.1968.1080p.BluRay.x264-CiNEFiLE",
+		Release{Title: "2001 A Space Odyssey", Year: 1968, Resolution: "1080p", Source: "BluRay", Codec: "H.264", Group: "CiNEFiLE"},
+	},
+	{
+		"1917.2019.1080p.BluRay.x264-SPARKS",
+		Release{Title: "1917", Year: 2019, Resolution: "1080p", Source: "BluRay", Codec: "H.264", Group: "SPARKS"},
+	},
+	{
+		"2012.2009.720p.BluRay.x264-METiS",
+		Release{Title: "2012", Year: 2009, Resolution: "720p", Source: "BluRay", Codec: "H.264", Group: "METiS"},
+	},
+	{
+		"Wonder.Woman.1984.2020.2160p.HMAX.WEB-DL.DDP5.1.Atmos.HDR.HEVC-EVO",
+		Release{Title: "Wonder Woman 1984", Year: 2020, Resolution: "2160p", Source: "WEB-DL", Audio: []string{"DD+", "Atmos"},
+			Channels: "5.1", HDR: []string{"HDR"}, Codec: "H.265", Group: "EVO"},
+	},
+
+	// "4K" and other weak words inside titles
+	{
+		"4K.Restoration.Of.Metropolis.1927.1080p.BluRay.x264",
+		Release{Title: "4K Restoration Of Metropolis", Year: 1927, Resolution: "1080p", Source: "BluRay", Codec: "H.264"},
+	},
+	{
+		"The Last Waltz 4k 1978 2160p UHD BluRay x265",
+		Release{Title: "The Last Waltz 4k", Year: 1978, Resolution: "2160p", Source: "BluRay", Codec: "H.265"},
+	},
+	{
+		"Alien.1979.4K.HDR.DV.2160p.WEBRip.x265-GROUP",
+		Release{Title: "Alien", Year: 1979, Resolution: "2160p", HDR: []string{"HDR", "DV"}, Source: "WEBRip",
+			Codec: "H.265", Group: "GROUP"},
+	},
+	{
+		"The French Dispatch 2021 1080p WEB-DL",
+		Release{Title: "The French Dispatch", Year: 2021, Resolution: "1080p", Source: "WEB-DL"},
+	},
+	{
+		"Web of Lies 2019 720p HDTV x264",
+		Release{Title: "Web of Lies", Year: 2019, Resolution: "720p", Source: "HDTV", Codec: "H.264"},
+	},
+
+	// "S01"-like text inside words isn't a season
+	{
+		"Seven.Samurai.1954.CRITERION.1080p.BluRay.x264-CiNEFiLE",
+		Release{Title: "Seven Samurai", Year: 1954, Edition: "Criterion", Resolution: "1080p", Source: "BluRay",
+			Codec: "H.264", Group: "CiNEFiLE"},
+	},
+	{
+		"Chaos01.Expedition.2018.1080p.WEB-DL",
+		Release{Title: "Chaos01 Expedition", Year: 2018, Resolution: "1080p", Source: "WEB-DL"},
+	},
+	{
+		"Yes01.2015.720p.HDTV",
+		Release{Title: "Yes01", Year: 2015, Resolution: "720p", Source: "HDTV"},
+	},
+
+	// YTS and P2P names
+	{
+		"The Shawshank Redemption (1994) [1080p] [BluRay] [5.1] [YTS.MX]",
+		Release{Title: "The Shawshank Redemption", Year: 1994, Resolution: "1080p", Source: "BluRay", Channels: "5.1", Group: "YTS.MX"},
+	},
+	{
+		"Interstellar (2014) 2160p 4K BluRay 5.1-LAMA",
+		Release{Title: "Interstellar", Year: 2014, Resolution: "2160p", Source: "BluRay", Channels: "5.1", Group: "LAMA"},
+	},
+	{
+		"www.1TamilMV.com - Leo (2023) Tamil 1080p WEB-DL AVC DDP5.1.mkv",
+		Release{Title: "Leo", Year: 2023, Resolution: "1080p", Source: "WEB-DL", Codec: "H.264", Audio: []string{"DD+"}, Channels: "5.1"},
+	},
+	{
+		"Everything Everywhere All at Once (2022) (1080p BluRay x265 10bit Tigole)",
+		Release{Title: "Everything Everywhere All at Once", Year: 2022, Resolution: "1080p", Source: "BluRay",
+			Codec: "H.265", BitDepth: 10},
+	},
+	{
+		"The.Godfather.1972.1920x1080.BluRay.x264",
+		Release{Title: "The Godfather", Year: 1972, Resolution: "1080p", Source: "BluRay", Codec: "H.264"},
+	},
+
+	// Scene TV
+	{
+		"Breaking.Bad.S01E05.720p.HDTV.x264-CTU",
+		Release{Title: "Breaking Bad", Seasons: []int{1}, Episodes: []int{5}, Resolution: "720p", Source: "HDTV",
+			Codec: "H.264", Group: "CTU"},
+	},
+	{
+		"The.Office.US.S05E14E15.720p.WEB-DL.DD5.1.H.264-NTb",
+		Release{Title: "The Office US", Seasons: []int{5}, Episodes: []int{14, 15}, Resolution: "720p", Source: "WEB-DL",
+			Audio: []string{"DD"}, Channels: "5.1", Codec: "H.264", Group: "NTb"},
+	},
+	{
+		"Game.of.Thrones.S08E01-E03.1080p.AMZN.WEB-DL.DDP5.1.H.264-GoT",
+		Release{Title: "Game of Thrones", Seasons: []int{8}, Episodes: []int{1, 2, 3}, Resolution: "1080p", Source: "WEB-DL",
+			Audio: []string{"DD+"}, Channels: "5.1", Codec: "H.264", Group: "GoT"},
+	},
+	{
+		"The.Wire.S01-S05.COMPLETE.720p.BluRay.x264-DEMAND",
+		Release{Title: "The Wire", Seasons: []int{1, 2, 3, 4, 5}, Complete: true, Resolution: "720p", Source: "BluRay",
+			Codec: "H.264", Group: "DEMAND"},
+	},
+	{
+		"Doctor.Who.2005.S13E01.1080p.HDTV.H264-RiVER",
+		Release{Title: "Doctor Who", Year: 2005, Seasons: []int{13}, Episodes: []int{1}, Resolution: "1080p", Source: "HDTV",
+			Codec: "H.264", Group: "RiVER"},
+	},
+	{
+		"Friends.1x01.The.One.Where.Monica.Gets.A.Roommate.DVDRip",
+		Release{Title: "Friends", Seasons: []int{1}, Episodes: []int{1}, Source: "DVDRip"},
+	},
+	{
+		"Sherlock.S03.1080p.BluRay.x264-SHORTBREHD",
+		Release{Title: "Sherlock", Seasons: []int{3}, Resolution: "1080p", Source: "BluRay", Codec: "H.264", Group: "SHORTBREHD"},
+	},
+	{
+		"The Sopranos Season 1-6 Complete 720p BluRay",
+		Release{Title: "The Sopranos", Seasons: []int{1, 2, 3, 4, 5, 6}, Complete: true, Resolution: "720p", Source: "BluRay"},
+	},
+	{
+		"Seinfeld The Complete Series 1989-1998 DVDRip",
+		Release{Title: "Seinfeld", Year: 1989, Complete: true, Source: "DVDRip", series: true},
+	},
+	{
+		"Stranger.Things.S04E09.REPACK.2160p.NF.WEB-DL.DDP5.1.Atmos.DV.HEVC-TEPES",
+		Release{Title: "Stranger Things", Seasons: []int{4}, Episodes: []int{9}, Repack: true, Resolution: "2160p",
+			Source: "WEB-DL", Audio: []string{"DD+", "Atmos"}, Channels: "5.1", HDR: []string{"DV"}, Codec: "H.265", Group: "TEPES"},
+	},
+
+	// Anime and fansubs
+	{
+		"[SubsPlease] Jujutsu Kaisen - 24 (1080p) [8F1C2B3D].mkv",
+		Release{Title: "Jujutsu Kaisen", Episodes: []int{24}, Resolution: "1080p", Group: "SubsPlease"},
+	},
+	{
+		"[Erai-raws] Spy x Family - 01 ~ 12 [1080p][Multiple Subtitle]",
+		Release{Title: "Spy x Family", Episodes: span(1, 12), Resolution: "1080p", Group: "Erai-raws"},
+	},
+	{
+		"[Judas] Vinland Saga - S02E05 [1080p][HEVC x265 10bit][Multi-Subs]",
+		Release{Title: "Vinland Saga", Seasons: []int{2}, Episodes: []int{5}, Resolution: "1080p", Codec: "H.265",
+			BitDepth: 10, Group: "Judas"},
+	},
+	{
+		"[Commie] Steins;Gate - 01-24 [BD 720p AAC]",
+		Release{Title: "Steins;Gate", Episodes: span(1, 24), Resolution: "720p", Audio: []string{"AAC"}, Group: "Commie"},
+	},
+	{
+		"[Erai-raws] Spy x Family - 13~25 [1080p]",
+		Release{Title: "Spy x Family", Episodes: span(13, 25), Resolution: "1080p", Group: "Erai-raws"},
+	},
+	{
+		"[SubsPlease] Frieren - 01 - The Journey's End (1080p)",
+		Release{Title: "Frieren", Episodes: []int{1}, Resolution: "1080p", Group: "SubsPlease"},
+	},
+	{
+		"[HorribleSubs] One Piece - 1000 [720p].mkv",
+		Release{Title: "One Piece", Episodes: []int{1000}, Resolution: "720p", Group: "HorribleSubs"},
+	},
+}
+
+func TestParse(t *testing.T) {
+	for _, tt := range parseTests {
+		if got := Parse(tt.name); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q)\n got %+v\nwant %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEpisodeTag(t *testing.T) {
+	tests := []struct {
+		r    Release
+		want string
+	}{
+		{Release{Title: "Movie"}, ""},
+		{Release{Seasons: []int{1}, Episodes: []int{2}}, "S01E02"},
+		{Release{Seasons: []int{1}, Episodes: []int{2, 3, 4}}, "S01E02-E04"},
+		{Release{Seasons: []int{1, 2, 3}}, "S01-S03"},
+		{Release{Seasons: []int{4}}, "S04"},
+		{Release{Episodes: []int{5}}, "E05"},
+	}
+	for _, tt := range tests {
+		if got := tt.r.EpisodeTag(); got != tt.want {
+			t.Errorf("%+v.EpisodeTag() = %q, want %q", tt.r, got, tt.want)
+		}
+	}
+}
+
+func TestIsTV(t *testing.T) {
+	for name, want := range map[string]bool{
+		"Inception.2010.1080p.BluRay.x264-SPARKS":       false,
+		"Breaking.Bad.S01E05.720p.HDTV.x264-CTU":        true,
+		"Seinfeld The Complete Series 1989-1998 DVDRip": true,
+		"Sherlock.S03.1080p.BluRay.x264-SHORTBREHD":     true,
+		"Chaos01.Expedition.2018.1080p.WEB-DL":          false,
+	} {
+		if got := Parse(name).IsTV(); got != want {
+			t.Errorf("Parse(%q).IsTV() = %v, want %v", name, got, want)
+		}
+	}
+}
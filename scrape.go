@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"c-cli/core"
+	"c-cli/metainfo"
+	"c-cli/tracker"
+)
+
+// scrapeTimeout bounds a refresh across all trackers.
+const scrapeTimeout = 10 * time.Second
+
+// ScrapeTorrents asks every tracker in trackerURLs for live swarm counts and
+// returns a copy of torrents with Seeds and Peers refreshed. Trackers see
+// overlapping swarms, so the highest count reported wins. Torrents no tracker
+// knows keep their provider-reported values.
+func ScrapeTorrents(torrents []core.Torrent, trackerURLs []string) ([]core.Torrent, error) {
+	var hashes [][20]byte
+	for _, t := range torrents {
+		if h, err := metainfo.ParseHash(t.Hash); err == nil {
+			hashes = append(hashes, h)
+		}
+	}
+	if len(hashes) == 0 {
+		return nil, fmt.Errorf("no infohashes to scrape")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), scrapeTimeout)
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		best    = map[[20]byte]tracker.ScrapeResult{}
+		lastErr error
+		wg      sync.WaitGroup
+	)
+	for _, tr := range trackerURLs {
+		wg.Add(1)
+		go func(tr string) {
+			defer wg.Done()
+			results, err := tracker.Scrape(ctx, tr, hashes)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				lastErr = err
+				return
+			}
+			for h, r := range results {
+				b := best[h]
+				b.Seeders = max(b.Seeders, r.Seeders)
+				b.Leechers = max(b.Leechers, r.Leechers)
+				b.Completed = max(b.Completed, r.Completed)
+				best[h] = b
+			}
+		}(tr)
+	}
+	wg.Wait()
+
+	if len(best) == 0 {
+		if lastErr != nil {
+			return nil, fmt.Errorf("scrape failed: %w", lastErr)
+		}
+		return nil, fmt.Errorf("no tracker reported these torrents")
+	}
+
+	now := time.Now()
+	out := slices.Clone(torrents)
+	for i := range out {
+		h, err := metainfo.ParseHash(out[i].Hash)
+		if err != nil {
+			continue
+		}
+		if r, ok := best[h]; ok {
+			out[i].Seeds = r.Seeders
+			out[i].Peers = r.Leechers
+			out[i].ScrapedAt = now
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,45 @@
+package throttle
+
+import (
+	"context"
+	"sync"
+)
+
+// Pool bounds how much work runs at once across all of its callers, so
+// concurrent searches share one budget instead of each starting their own
+// goroutine per result.
+type Pool struct {
+	sem chan struct{}
+}
+
+// NewPool returns a Pool running at most size jobs at once; at least one.
+func NewPool(size int) *Pool {
+	return &Pool{sem: make(chan struct{}, max(size, 1))}
+}
+
+// Each calls fn(i) for every i in [0, n) on the pool and waits for them to
+// finish. Once ctx is done, jobs that haven't started are skipped.
+func (p *Pool) Each(ctx context.Context, n int, fn func(i int)) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(n, cap(p.sem)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				select {
+				case p.sem <- struct{}{}:
+				case <-ctx.Done():
+					continue
+				}
+				fn(i)
+				<-p.sem
+			}
+		}()
+	}
+	for i := range n {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
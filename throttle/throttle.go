@@ -0,0 +1,102 @@
+// Package throttle keeps c-cli polite towards the services it queries: it
+// bounds how many requests run at once, how fast new ones start, and retries
+// the failures worth retrying.
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limits describes how hard one service may be hit. The zero value is
+// unlimited, with no retries.
+type Limits struct {
+	Concurrency int           // Requests in flight; unlimited when zero
+	Rate        float64       // Requests started per second; unlimited when zero
+	Burst       int           // Requests that may start back to back under Rate; 1 when zero
+	Retries     int           // Extra attempts after a 5xx, 429 or timeout
+	Timeout     time.Duration // Of each attempt; none when zero
+}
+
+// Limiter enforces one set of Limits across all its callers: a semaphore for
+// Concurrency and a token bucket for Rate. It is safe for concurrent use.
+type Limiter struct {
+	limits Limits
+	sem    chan struct{} // nil when Concurrency is unlimited
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// New returns a Limiter for l.
+func New(l Limits) *Limiter {
+	if l.Burst <= 0 {
+		l.Burst = 1
+	}
+	lim := &Limiter{limits: l, tokens: float64(l.Burst), last: time.Now()}
+	if l.Concurrency > 0 {
+		lim.sem = make(chan struct{}, l.Concurrency)
+	}
+	return lim
+}
+
+// Limits returns the limits l enforces.
+func (l *Limiter) Limits() Limits { return l.limits }
+
+// Acquire waits for a free slot and then for the rate limit to allow a new
+// request. The caller must call release once the request is finished. A nil
+// *Limiter never waits.
+func (l *Limiter) Acquire(ctx context.Context) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	if l.sem != nil {
+		select {
+		case l.sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release = func() {
+		if l.sem != nil {
+			<-l.sem
+		}
+	}
+	if err := l.wait(ctx); err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+// wait takes a token from the bucket, sleeping until one is due.
+func (l *Limiter) wait(ctx context.Context) error {
+	if l.limits.Rate <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(float64(l.limits.Burst), l.tokens+now.Sub(l.last).Seconds()*l.limits.Rate)
+	l.last = now
+	l.tokens--
+	deficit := -l.tokens
+	l.mu.Unlock()
+	if deficit <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(time.Duration(deficit / l.limits.Rate * float64(time.Second)))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		// Hand the reserved token back for the next caller
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,104 @@
+package throttle
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimiterRate(t *testing.T) {
+	l := New(Limits{Rate: 20, Burst: 2}) // A token every 50ms
+	start := time.Now()
+	for range 2 {
+		release, err := l.Acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	if d := time.Since(start); d > 20*time.Millisecond {
+		t.Errorf("burst of 2 took %v, want no wait", d)
+	}
+
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if d := time.Since(start); d < 40*time.Millisecond {
+		t.Errorf("third request started after %v, want about 50ms", d)
+	}
+}
+
+func TestLimiterRateCancel(t *testing.T) {
+	l := New(Limits{Rate: 10})
+	release, _ := l.Acquire(context.Background())
+	release()
+
+	// Waiting for a token gives up with the context and hands the token back
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx); err != context.DeadlineExceeded {
+		t.Errorf("err = %v, want DeadlineExceeded", err)
+	}
+	start := time.Now()
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if d := time.Since(start); d > 150*time.Millisecond {
+		t.Errorf("next request waited %v, want at most one interval", d)
+	}
+}
+
+func TestLimiterConcurrency(t *testing.T) {
+	l := New(Limits{Concurrency: 1})
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("second Acquire: err = %v, want DeadlineExceeded", err)
+	}
+
+	release()
+	release, err = l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire after release: %v", err)
+	}
+	release()
+}
+
+func TestNilLimiter(t *testing.T) {
+	var l *Limiter
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+}
+
+func TestPoolEach(t *testing.T) {
+	p := NewPool(2)
+	var running, peak, calls atomic.Int32
+	p.Each(context.Background(), 10, func(i int) {
+		n := running.Add(1)
+		for {
+			old := peak.Load()
+			if n <= old || peak.CompareAndSwap(old, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		running.Add(-1)
+		calls.Add(1)
+	})
+	if calls.Load() != 10 || peak.Load() > 2 {
+		t.Errorf("%d calls with up to %d at once, want 10 with at most 2", calls.Load(), peak.Load())
+	}
+}
@@ -0,0 +1,139 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// backoffBase is the wait before the first retry; tests shorten it.
+var backoffBase = 500 * time.Millisecond
+
+const (
+	backoffMax = 8 * time.Second
+	// maxRetryAfter is the longest Retry-After worth waiting for; a server
+	// asking for more gets its answer passed on instead.
+	maxRetryAfter = 10 * time.Second
+)
+
+// Transport is an http.RoundTripper that sends requests through a Limiter
+// and retries idempotent ones that failed with a 5xx, a 429 or a timeout,
+// after a jittered exponential backoff. The request's slot is held until
+// its response body is closed.
+type Transport struct {
+	Base    http.RoundTripper // http.DefaultTransport when nil
+	Limiter *Limiter
+}
+
+// Client returns an http.Client whose requests go through l. Its overall
+// deadline comes from the request context.
+func Client(l *Limiter) *http.Client {
+	return &http.Client{Transport: &Transport{Limiter: l}}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	var limits Limits
+	if t.Limiter != nil {
+		limits = t.Limiter.limits
+	}
+	ctx := req.Context()
+	replayable := req.Body == nil || req.Body == http.NoBody
+	idempotent := req.Method == "" || req.Method == http.MethodGet || req.Method == http.MethodHead
+
+	for attempt := 0; ; attempt++ {
+		release, err := t.Limiter.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		actx, cancel := ctx, context.CancelFunc(func() {})
+		if limits.Timeout > 0 {
+			actx, cancel = context.WithTimeout(ctx, limits.Timeout)
+		}
+		done := func() { cancel(); release() }
+
+		resp, err := base.RoundTrip(req.WithContext(actx))
+		wait, retry := backoff(attempt), false
+		if attempt < limits.Retries && replayable && idempotent && ctx.Err() == nil {
+			if err != nil {
+				retry = isTimeout(err)
+			} else if retryableStatus(resp.StatusCode) {
+				if after := retryAfter(resp); after <= maxRetryAfter {
+					wait, retry = max(wait, after), true
+				}
+			}
+		}
+
+		if !retry {
+			if err != nil {
+				done()
+				return nil, err
+			}
+			resp.Body = &releaseBody{ReadCloser: resp.Body, done: done}
+			return resp, nil
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		done()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// backoff is the wait before retry attempt+1: exponential, with jitter so
+// that requests failing together don't retry together.
+func backoff(attempt int) time.Duration {
+	d := min(backoffBase<<attempt, backoffMax)
+	return d/2 + rand.N(d/2)
+}
+
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+// retryAfter reads a Retry-After header given in seconds; zero if absent.
+func retryAfter(resp *http.Response) time.Duration {
+	secs, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || secs < 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
+
+// releaseBody frees the request's slot when the body is closed.
+type releaseBody struct {
+	io.ReadCloser
+	done func()
+	once sync.Once
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.done)
+	return err
+}
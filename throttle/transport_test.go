@@ -0,0 +1,159 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// server answers attempt n (from 1) with reply(n, w, r) and counts attempts.
+func server(t *testing.T, reply func(n int32, w http.ResponseWriter, r *http.Request)) (string, *atomic.Int32) {
+	t.Helper()
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reply(attempts.Add(1), w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, &attempts
+}
+
+// shortBackoff makes retries start within milliseconds for the test.
+func shortBackoff(t *testing.T) {
+	old := backoffBase
+	backoffBase = time.Millisecond
+	t.Cleanup(func() { backoffBase = old })
+}
+
+func TestTransportRetries(t *testing.T) {
+	shortBackoff(t)
+	tests := []struct {
+		name   string
+		status int    // Of every attempt before the last
+		after  string // Retry-After of those attempts
+		want   int32  // Attempts made
+		final  int
+	}{
+		{"5xx", http.StatusBadGateway, "", 3, http.StatusOK},
+		{"429", http.StatusTooManyRequests, "0", 3, http.StatusOK},
+		{"404 is final", http.StatusNotFound, "", 1, http.StatusNotFound},
+		{"Retry-After over the cap", http.StatusTooManyRequests, "11", 1, http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		url, attempts := server(t, func(n int32, w http.ResponseWriter, r *http.Request) {
+			if n < 3 {
+				w.Header().Set("Retry-After", tt.after)
+				w.WriteHeader(tt.status)
+				io.WriteString(w, "try again")
+				return
+			}
+			io.WriteString(w, "ok")
+		})
+		resp, err := Client(New(Limits{Retries: 2})).Get(url)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.final || attempts.Load() != tt.want {
+			t.Errorf("%s: status %d after %d attempts, want %d after %d",
+				tt.name, resp.StatusCode, attempts.Load(), tt.final, tt.want)
+		}
+	}
+}
+
+func TestTransportRetriesExhausted(t *testing.T) {
+	shortBackoff(t)
+	url, attempts := server(t, func(_ int32, w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	resp, err := Client(New(Limits{Retries: 2})).Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || attempts.Load() != 3 {
+		t.Errorf("status %d after %d attempts, want the last 503 after 3", resp.StatusCode, attempts.Load())
+	}
+}
+
+func TestTransportRetriesTimeout(t *testing.T) {
+	shortBackoff(t)
+	url, attempts := server(t, func(n int32, w http.ResponseWriter, r *http.Request) {
+		if n == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		io.WriteString(w, "ok")
+	})
+	resp, err := Client(New(Limits{Retries: 1, Timeout: 50 * time.Millisecond})).Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if attempts.Load() != 2 {
+		t.Errorf("%d attempts, want the timed-out one retried", attempts.Load())
+	}
+}
+
+func TestTransportPostNotRetried(t *testing.T) {
+	shortBackoff(t)
+	url, attempts := server(t, func(_ int32, w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	// The body can't be replayed, and POST isn't idempotent anyway
+	resp, err := Client(New(Limits{Retries: 2})).Post(url, "text/plain", strings.NewReader("grab"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway || attempts.Load() != 1 {
+		t.Errorf("status %d after %d attempts, want 502 after 1", resp.StatusCode, attempts.Load())
+	}
+}
+
+// roundTripFunc adapts a function to http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestTransportReleasesSlot(t *testing.T) {
+	l := New(Limits{Concurrency: 1})
+	refused := errors.New("refused")
+	client := &http.Client{Transport: &Transport{
+		Base:    roundTripFunc(func(*http.Request) (*http.Response, error) { return nil, refused }),
+		Limiter: l,
+	}}
+	// A failed request gives its slot back
+	for range 2 {
+		if _, err := client.Get("http://example.invalid/"); !errors.Is(err, refused) {
+			t.Fatalf("err = %v, want refused", err)
+		}
+	}
+
+	// A response holds the slot until its body is closed
+	url, _ := server(t, func(_ int32, w http.ResponseWriter, _ *http.Request) { io.WriteString(w, "ok") })
+	resp, err := (&http.Client{Transport: &Transport{Limiter: l}}).Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Acquire with the body open: err = %v, want DeadlineExceeded", err)
+	}
+	resp.Body.Close()
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+}
@@ -0,0 +1,246 @@
+package tracker
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTrackers are public open trackers known to be maintained. Users can
+// replace them from config or extend them with a list file.
+var DefaultTrackers = []string{
+	"udp://tracker.opentrackr.org:1337/announce",
+	"udp://open.stealth.si:80/announce",
+	"udp://tracker.torrent.eu.org:451/announce",
+	"udp://exodus.desync.com:6969/announce",
+	"udp://explodie.org:6969/announce",
+	"udp://tracker.theoks.net:6969/announce",
+}
+
+const (
+	probeTimeout     = 5 * time.Second
+	probeConcurrency = 16
+
+	// A probe result moves the score halfway towards 1 (answered) or 0
+	// (failed), so a tracker recovers or drops out after a probe or two.
+	scoreWeight  = 0.5
+	initialScore = 0.5
+	// minHealthyScore excludes trackers that failed their last probe.
+	minHealthyScore = 0.3
+)
+
+// Probe checks that the tracker at trackerURL is alive and returns the round
+// trip time. UDP trackers must complete a BEP 15 connect handshake; HTTP
+// trackers must answer a request on the announce URL with any status.
+func Probe(ctx context.Context, trackerURL string) (time.Duration, error) {
+	u, err := url.Parse(trackerURL)
+	if err != nil {
+		return 0, fmt.Errorf("tracker: %w", err)
+	}
+
+	start := time.Now()
+	switch u.Scheme {
+	case "udp":
+		s, err := dialUDP(ctx, u)
+		if err != nil {
+			return 0, err
+		}
+		s.Close()
+	case "http", "https":
+		req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+		if err != nil {
+			return 0, fmt.Errorf("tracker: %w", err)
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return 0, fmt.Errorf("tracker: %w", err)
+		}
+		resp.Body.Close()
+	default:
+		return 0, fmt.Errorf("tracker: unsupported scheme %q", u.Scheme)
+	}
+	return time.Since(start), nil
+}
+
+// Health is what probing has learned about one tracker.
+type Health struct {
+	URL     string
+	Score   float64       // 0 (dead) to 1 (reliably answering)
+	RTT     time.Duration // Of the last successful probe
+	LastErr error         // Of the last probe; nil if it succeeded
+	Probed  time.Time     // Zero until the first probe
+}
+
+// List is a deduplicated set of trackers ranked by health. It is safe for
+// concurrent use; trackers are usable before the first probe.
+type List struct {
+	mu      sync.Mutex
+	entries []Health
+}
+
+// NewList returns a List of urls in order, dropping blanks and duplicates.
+func NewList(urls ...[]string) *List {
+	l := &List{}
+	seen := map[string]bool{}
+	for _, list := range urls {
+		for _, u := range list {
+			u = strings.TrimSpace(u)
+			if u == "" || seen[u] {
+				continue
+			}
+			seen[u] = true
+			l.entries = append(l.entries, Health{URL: u, Score: initialScore})
+		}
+	}
+	return l
+}
+
+// ReadListFile reads tracker URLs from a file with one URL per line, the
+// format of the public lists such as ngosang/trackerslist. Blank lines and
+// lines starting with # are skipped.
+func ReadListFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseList(f)
+}
+
+// ParseList reads tracker URLs in the ReadListFile format from r.
+func ParseList(r io.Reader) ([]string, error) {
+	var urls []string
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		s := strings.TrimSpace(sc.Text())
+		if s == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+		u, err := url.Parse(s)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("tracker list line %d: invalid URL %q", line, s)
+		}
+		switch u.Scheme {
+		case "udp", "http", "https":
+			urls = append(urls, s)
+		default:
+			// WebTorrent (wss://) and other trackers we can't talk to
+		}
+	}
+	return urls, sc.Err()
+}
+
+// Probe checks every tracker in parallel and updates its score. It returns
+// once all probes have finished or ctx is done.
+func (l *List) Probe(ctx context.Context) {
+	urls := l.URLs()
+	sem := make(chan struct{}, probeConcurrency)
+	var wg sync.WaitGroup
+	for _, u := range urls {
+		wg.Add(1)
+		go func(u string) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+
+			pctx, cancel := context.WithTimeout(ctx, probeTimeout)
+			rtt, err := Probe(pctx, u)
+			cancel()
+			if ctx.Err() != nil {
+				return // Shutting down, not the tracker's fault
+			}
+			l.record(u, rtt, err)
+		}(u)
+	}
+	wg.Wait()
+}
+
+func (l *List) record(u string, rtt time.Duration, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i := range l.entries {
+		h := &l.entries[i]
+		if h.URL != u {
+			continue
+		}
+		h.Probed = time.Now()
+		h.LastErr = err
+		if err != nil {
+			h.Score *= 1 - scoreWeight
+		} else {
+			h.Score = h.Score*(1-scoreWeight) + scoreWeight
+			h.RTT = rtt
+		}
+		return
+	}
+}
+
+// URLs returns every tracker in the list, in configured order.
+func (l *List) URLs() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	urls := make([]string, len(l.entries))
+	for i, h := range l.entries {
+		urls[i] = h.URL
+	}
+	return urls
+}
+
+// Stats returns the health of every tracker, healthiest first.
+func (l *List) Stats() []Health {
+	l.mu.Lock()
+	stats := slices.Clone(l.entries)
+	l.mu.Unlock()
+
+	slices.SortStableFunc(stats, func(a, b Health) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		// Equal scores: faster first once both have answered
+		if a.RTT != 0 && b.RTT != 0 {
+			return cmp.Compare(a.RTT, b.RTT)
+		}
+		return 0
+	})
+	return stats
+}
+
+// Healthiest returns up to n tracker URLs, best first, leaving out trackers
+// that failed recent probes. n <= 0 means no limit. If every tracker looks
+// dead, most likely the network is down, so the best n are returned anyway
+// rather than none.
+func (l *List) Healthiest(n int) []string {
+	stats := l.Stats()
+	if n <= 0 || n > len(stats) {
+		n = len(stats)
+	}
+
+	urls := make([]string, 0, n)
+	for _, h := range stats {
+		if len(urls) == n {
+			break
+		}
+		if h.Score >= minHealthyScore {
+			urls = append(urls, h.URL)
+		}
+	}
+	if len(urls) == 0 {
+		for _, h := range stats[:n] {
+			urls = append(urls, h.URL)
+		}
+	}
+	return urls
+}
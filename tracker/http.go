@@ -0,0 +1,149 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"c-cli/bencode"
+)
+
+// maxResponseSize caps tracker responses; real ones are a few KB.
+const maxResponseSize = 1 << 20
+
+var httpClient = &http.Client{Timeout: 15 * time.Second}
+
+func announceHTTP(ctx context.Context, u *url.URL, req AnnounceRequest) (*AnnounceResponse, error) {
+	q := u.Query()
+	q.Set("info_hash", string(req.InfoHash[:]))
+	q.Set("peer_id", string(req.PeerID[:]))
+	q.Set("port", strconv.Itoa(int(req.Port)))
+	q.Set("uploaded", "0")
+	q.Set("downloaded", "0")
+	// A non-zero left makes us a leecher, so trackers return seeders too
+	q.Set("left", "1")
+	q.Set("compact", "1")
+	q.Set("event", "started")
+	if req.NumWant > 0 {
+		q.Set("numwant", strconv.Itoa(req.NumWant))
+	}
+
+	announceURL := *u
+	announceURL.RawQuery = q.Encode()
+
+	dict, err := getBencoded(ctx, announceURL.String())
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &AnnounceResponse{}
+	if n, ok := dict["interval"].(int64); ok {
+		resp.Interval = time.Duration(n) * time.Second
+	}
+	if n, ok := dict["complete"].(int64); ok {
+		resp.Seeders = int(n)
+	}
+	if n, ok := dict["incomplete"].(int64); ok {
+		resp.Leechers = int(n)
+	}
+
+	switch peers := dict["peers"].(type) {
+	case string:
+		resp.Peers = parseCompactPeers([]byte(peers), false)
+	case []interface{}:
+		// Non-compact form: a list of {ip, port} dictionaries
+		for _, p := range peers {
+			pd, _ := p.(map[string]interface{})
+			ip, _ := pd["ip"].(string)
+			port, _ := pd["port"].(int64)
+			addr, err := netip.ParseAddr(ip)
+			if err != nil || port <= 0 || port > 65535 {
+				continue
+			}
+			resp.Peers = append(resp.Peers, netip.AddrPortFrom(addr.Unmap(), uint16(port)))
+		}
+	}
+	if peers6, ok := dict["peers6"].(string); ok {
+		resp.Peers = append(resp.Peers, parseCompactPeers([]byte(peers6), true)...)
+	}
+	return resp, nil
+}
+
+func scrapeHTTP(ctx context.Context, u *url.URL, hashes [][20]byte) (map[[20]byte]ScrapeResult, error) {
+	// BEP 48: the scrape URL replaces a final "announce" path segment
+	i := strings.LastIndex(u.Path, "/")
+	if i < 0 || !strings.HasPrefix(u.Path[i+1:], "announce") {
+		return nil, fmt.Errorf("tracker: %s does not support scrape", u.Host)
+	}
+	scrapeURL := *u
+	scrapeURL.Path = u.Path[:i+1] + "scrape" + u.Path[i+1+len("announce"):]
+
+	q := u.Query()
+	for _, h := range hashes {
+		q.Add("info_hash", string(h[:]))
+	}
+	scrapeURL.RawQuery = q.Encode()
+
+	dict, err := getBencoded(ctx, scrapeURL.String())
+	if err != nil {
+		return nil, err
+	}
+
+	files, _ := dict["files"].(map[string]interface{})
+	results := make(map[[20]byte]ScrapeResult, len(files))
+	for key, v := range files {
+		stats, ok := v.(map[string]interface{})
+		if !ok || len(key) != 20 {
+			continue
+		}
+		var h [20]byte
+		copy(h[:], key)
+		seeders, _ := stats["complete"].(int64)
+		leechers, _ := stats["incomplete"].(int64)
+		completed, _ := stats["downloaded"].(int64)
+		results[h] = ScrapeResult{Seeders: int(seeders), Leechers: int(leechers), Completed: int(completed)}
+	}
+	return results, nil
+}
+
+// getBencoded fetches a tracker URL and decodes its bencoded dictionary,
+// turning "failure reason" into an error.
+func getBencoded(ctx context.Context, rawURL string) (map[string]interface{}, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("tracker: %w", err)
+	}
+
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("tracker: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("tracker: status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("tracker: %w", err)
+	}
+	v, err := bencode.Decode(body)
+	if err != nil {
+		return nil, fmt.Errorf("tracker: %w", err)
+	}
+	dict, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("tracker: response is not a dictionary")
+	}
+	if reason, ok := dict["failure reason"].(string); ok {
+		return nil, fmt.Errorf("tracker: %s", reason)
+	}
+	return dict, nil
+}
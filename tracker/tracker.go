@@ -0,0 +1,97 @@
+// Package tracker talks to BitTorrent trackers over HTTP (BEP 3, BEP 23)
+// and UDP (BEP 15).
+package tracker
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"time"
+)
+
+// AnnounceRequest describes the swarm and client for an announce.
+type AnnounceRequest struct {
+	InfoHash [20]byte
+	PeerID   [20]byte
+	Port     uint16
+	NumWant  int // Peers wanted; trackers use their default when <= 0
+}
+
+// AnnounceResponse is a tracker's reply to an announce.
+type AnnounceResponse struct {
+	Interval time.Duration
+	Seeders  int
+	Leechers int
+	Peers    []netip.AddrPort
+}
+
+// Announce asks the tracker at trackerURL for peers in the swarm. The
+// request is abandoned when ctx is done.
+func Announce(ctx context.Context, trackerURL string, req AnnounceRequest) (*AnnounceResponse, error) {
+	u, err := url.Parse(trackerURL)
+	if err != nil {
+		return nil, fmt.Errorf("tracker: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return announceHTTP(ctx, u, req)
+	case "udp":
+		return announceUDP(ctx, u, req)
+	default:
+		return nil, fmt.Errorf("tracker: unsupported scheme %q", u.Scheme)
+	}
+}
+
+// ScrapeResult is a tracker's swarm statistics for one torrent.
+type ScrapeResult struct {
+	Seeders   int
+	Leechers  int
+	Completed int // Times downloaded
+}
+
+// maxScrapeHashes is the most infohashes one UDP scrape packet can carry.
+const maxScrapeHashes = 74
+
+// Scrape asks the tracker at trackerURL for swarm statistics of each
+// infohash. Hashes the tracker doesn't know are missing from the result.
+func Scrape(ctx context.Context, trackerURL string, hashes [][20]byte) (map[[20]byte]ScrapeResult, error) {
+	u, err := url.Parse(trackerURL)
+	if err != nil {
+		return nil, fmt.Errorf("tracker: %w", err)
+	}
+	if len(hashes) > maxScrapeHashes {
+		hashes = hashes[:maxScrapeHashes]
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return scrapeHTTP(ctx, u, hashes)
+	case "udp":
+		return scrapeUDP(ctx, u, hashes)
+	default:
+		return nil, fmt.Errorf("tracker: unsupported scheme %q", u.Scheme)
+	}
+}
+
+// parseCompactPeers decodes BEP 23 compact peers: 6 bytes per IPv4 peer, or
+// 18 bytes per IPv6 peer (BEP 7) when ipv6 is set.
+func parseCompactPeers(b []byte, ipv6 bool) []netip.AddrPort {
+	size := 6
+	if ipv6 {
+		size = 18
+	}
+	peers := make([]netip.AddrPort, 0, len(b)/size)
+	for i := 0; i+size <= len(b); i += size {
+		addr, ok := netip.AddrFromSlice(b[i : i+size-2])
+		if !ok {
+			continue
+		}
+		port := binary.BigEndian.Uint16(b[i+size-2:])
+		if port == 0 {
+			continue
+		}
+		peers = append(peers, netip.AddrPortFrom(addr.Unmap(), port))
+	}
+	return peers
+}
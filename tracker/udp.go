@@ -0,0 +1,197 @@
+package tracker
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/url"
+	"os"
+	"time"
+)
+
+// BEP 15 constants
+const (
+	udpProtocolID = 0x41727101980
+
+	actionConnect  = 0
+	actionAnnounce = 1
+	actionScrape   = 2
+	actionError    = 3
+
+	eventStarted = 2
+)
+
+// udpRetries is how many times a request is sent before giving up. BEP 15
+// backs off from 15s; interactive use can't wait that long, so we retry
+// sooner and let the caller's context bound the total.
+const udpRetries = 3
+
+// udpRetryTimeout is how long to wait for each reply; tests shorten it.
+var udpRetryTimeout = 3 * time.Second
+
+// udpSession is a connected BEP 15 exchange with one tracker.
+type udpSession struct {
+	conn   net.Conn
+	connID uint64
+	ipv6   bool
+}
+
+func dialUDP(ctx context.Context, u *url.URL) (*udpSession, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", hostPort(u, "80"))
+	if err != nil {
+		return nil, fmt.Errorf("tracker: %w", err)
+	}
+	s := &udpSession{conn: conn, connID: udpProtocolID}
+	if addr, ok := conn.RemoteAddr().(*net.UDPAddr); ok {
+		s.ipv6 = addr.IP.To4() == nil
+	}
+
+	resp, err := s.roundTrip(ctx, actionConnect, nil)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if len(resp) < 8 {
+		conn.Close()
+		return nil, fmt.Errorf("tracker: short connect response")
+	}
+	s.connID = binary.BigEndian.Uint64(resp)
+	return s, nil
+}
+
+func (s *udpSession) Close() error { return s.conn.Close() }
+
+// roundTrip sends action with body and returns the response payload after
+// the action and transaction ID, retransmitting on timeouts.
+func (s *udpSession) roundTrip(ctx context.Context, action uint32, body []byte) ([]byte, error) {
+	tid := rand.Uint32()
+	packet := make([]byte, 16, 16+len(body))
+	binary.BigEndian.PutUint64(packet[0:], s.connID)
+	binary.BigEndian.PutUint32(packet[8:], action)
+	binary.BigEndian.PutUint32(packet[12:], tid)
+	packet = append(packet, body...)
+
+	// Unblock reads as soon as the caller gives up
+	stop := context.AfterFunc(ctx, func() { s.conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	buf := make([]byte, 2048)
+	for attempt := 0; attempt < udpRetries; attempt++ {
+		if _, err := s.conn.Write(packet); err != nil {
+			return nil, fmt.Errorf("tracker: %w", err)
+		}
+		deadline := time.Now().Add(udpRetryTimeout)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		s.conn.SetReadDeadline(deadline)
+
+		for {
+			n, err := s.conn.Read(buf)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, fmt.Errorf("tracker: %w", ctx.Err())
+				}
+				if errors.Is(err, os.ErrDeadlineExceeded) {
+					break // retransmit
+				}
+				return nil, fmt.Errorf("tracker: %w", err)
+			}
+			if n < 8 || binary.BigEndian.Uint32(buf[4:]) != tid {
+				continue // stale or foreign packet
+			}
+			switch got := binary.BigEndian.Uint32(buf); got {
+			case action:
+				return append([]byte(nil), buf[8:n]...), nil
+			case actionError:
+				return nil, fmt.Errorf("tracker: %s", buf[8:n])
+			default:
+				return nil, fmt.Errorf("tracker: unexpected action %d", got)
+			}
+		}
+	}
+	return nil, fmt.Errorf("tracker: no response from %s", s.conn.RemoteAddr())
+}
+
+func announceUDP(ctx context.Context, u *url.URL, req AnnounceRequest) (*AnnounceResponse, error) {
+	s, err := dialUDP(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	numWant := int32(-1)
+	if req.NumWant > 0 {
+		numWant = int32(req.NumWant)
+	}
+
+	body := make([]byte, 82)
+	copy(body[0:], req.InfoHash[:])
+	copy(body[20:], req.PeerID[:])
+	binary.BigEndian.PutUint64(body[40:], 0) // downloaded
+	binary.BigEndian.PutUint64(body[48:], 1) // left: ask as a leecher
+	binary.BigEndian.PutUint64(body[56:], 0) // uploaded
+	binary.BigEndian.PutUint32(body[64:], eventStarted)
+	binary.BigEndian.PutUint32(body[68:], 0) // IP: use the sender's
+	binary.BigEndian.PutUint32(body[72:], rand.Uint32())
+	binary.BigEndian.PutUint32(body[76:], uint32(numWant))
+	binary.BigEndian.PutUint16(body[80:], req.Port)
+
+	resp, err := s.roundTrip(ctx, actionAnnounce, body)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) < 12 {
+		return nil, fmt.Errorf("tracker: short announce response")
+	}
+	return &AnnounceResponse{
+		Interval: time.Duration(binary.BigEndian.Uint32(resp[0:])) * time.Second,
+		Leechers: int(binary.BigEndian.Uint32(resp[4:])),
+		Seeders:  int(binary.BigEndian.Uint32(resp[8:])),
+		Peers:    parseCompactPeers(resp[12:], s.ipv6),
+	}, nil
+}
+
+func scrapeUDP(ctx context.Context, u *url.URL, hashes [][20]byte) (map[[20]byte]ScrapeResult, error) {
+	s, err := dialUDP(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	body := make([]byte, 0, 20*len(hashes))
+	for _, h := range hashes {
+		body = append(body, h[:]...)
+	}
+	resp, err := s.roundTrip(ctx, actionScrape, body)
+	if err != nil {
+		return nil, err
+	}
+
+	// 12 bytes per hash, in request order: seeders, completed, leechers
+	results := make(map[[20]byte]ScrapeResult, len(hashes))
+	for i, h := range hashes {
+		off := i * 12
+		if off+12 > len(resp) {
+			break
+		}
+		results[h] = ScrapeResult{
+			Seeders:   int(binary.BigEndian.Uint32(resp[off:])),
+			Completed: int(binary.BigEndian.Uint32(resp[off+4:])),
+			Leechers:  int(binary.BigEndian.Uint32(resp[off+8:])),
+		}
+	}
+	return results, nil
+}
+
+// hostPort returns u's host with a default port filled in.
+func hostPort(u *url.URL, defaultPort string) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	return net.JoinHostPort(u.Hostname(), defaultPort)
+}
@@ -0,0 +1,166 @@
+package tracker
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"c-cli/bencode"
+)
+
+// udpTracker is a stand-in BEP 15 tracker answering connect and scrape.
+type udpTracker struct {
+	conn    *net.UDPConn
+	connID  uint64
+	drop    atomic.Int32 // Packets still to be ignored
+	failMsg string       // Answer scrapes with actionError when set
+	stats   map[[20]byte]ScrapeResult
+}
+
+func startUDPTracker(t *testing.T, tr *udpTracker) string {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	tr.conn, tr.connID = conn, 0x1122334455667788
+	go tr.serve()
+	return "udp://" + conn.LocalAddr().String() + "/announce"
+}
+
+func (tr *udpTracker) serve() {
+	buf := make([]byte, 2048)
+	for {
+		n, from, err := tr.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		if tr.drop.Add(-1) >= 0 || n < 16 {
+			continue
+		}
+		connID := binary.BigEndian.Uint64(buf)
+		action := binary.BigEndian.Uint32(buf[8:])
+		tid := buf[12:16]
+
+		reply := binary.BigEndian.AppendUint32(nil, action)
+		reply = append(reply, tid...)
+		switch {
+		case action == actionConnect && connID == udpProtocolID:
+			reply = binary.BigEndian.AppendUint64(reply, tr.connID)
+		case action == actionScrape && connID == tr.connID && tr.failMsg != "":
+			binary.BigEndian.PutUint32(reply, actionError)
+			reply = append(reply, tr.failMsg...)
+		case action == actionScrape && connID == tr.connID:
+			for off := 16; off+20 <= n; off += 20 {
+				s := tr.stats[[20]byte(buf[off:off+20])]
+				reply = binary.BigEndian.AppendUint32(reply, uint32(s.Seeders))
+				reply = binary.BigEndian.AppendUint32(reply, uint32(s.Completed))
+				reply = binary.BigEndian.AppendUint32(reply, uint32(s.Leechers))
+			}
+		default:
+			continue
+		}
+		tr.conn.WriteToUDP(reply, from)
+	}
+}
+
+func testHash(b byte) [20]byte {
+	var h [20]byte
+	for i := range h {
+		h[i] = b
+	}
+	return h
+}
+
+func scrapeCtx(t *testing.T) context.Context {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestScrapeUDP(t *testing.T) {
+	defer func(d time.Duration) { udpRetryTimeout = d }(udpRetryTimeout)
+	udpRetryTimeout = 100 * time.Millisecond
+
+	a, b := testHash(1), testHash(2)
+	tr := &udpTracker{stats: map[[20]byte]ScrapeResult{
+		a: {Seeders: 120, Leechers: 7, Completed: 3400},
+		b: {Seeders: 1},
+	}}
+	// Lose the first connect request so roundTrip has to retransmit
+	tr.drop.Store(1)
+	u := startUDPTracker(t, tr)
+
+	got, err := Scrape(scrapeCtx(t), u, [][20]byte{a, b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for h, want := range tr.stats {
+		if got[h] != want {
+			t.Errorf("scrape of %x = %+v, want %+v", h[:1], got[h], want)
+		}
+	}
+}
+
+func TestScrapeUDPError(t *testing.T) {
+	u := startUDPTracker(t, &udpTracker{failMsg: "torrent not registered"})
+	_, err := Scrape(scrapeCtx(t), u, [][20]byte{testHash(1)})
+	if err == nil || !strings.Contains(err.Error(), "torrent not registered") {
+		t.Errorf("err = %v, want the tracker's error message", err)
+	}
+}
+
+func TestScrapeUDPNoResponse(t *testing.T) {
+	defer func(d time.Duration) { udpRetryTimeout = d }(udpRetryTimeout)
+	udpRetryTimeout = 20 * time.Millisecond
+
+	tr := &udpTracker{}
+	tr.drop.Store(udpRetries)
+	u := startUDPTracker(t, tr)
+	_, err := Scrape(scrapeCtx(t), u, [][20]byte{testHash(1)})
+	if err == nil || !strings.Contains(err.Error(), "no response") {
+		t.Errorf("err = %v, want no response after %d tries", err, udpRetries)
+	}
+}
+
+func TestScrapeHTTP(t *testing.T) {
+	a := testHash(1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/tr/scrape.php" || r.URL.Query().Get("passkey") != "abc" {
+			http.NotFound(w, r)
+			return
+		}
+		if got := r.URL.Query()["info_hash"]; len(got) != 1 || got[0] != string(a[:]) {
+			t.Errorf("info_hash = %q", got)
+		}
+		body, _ := bencode.Encode(map[string]interface{}{
+			"files": map[string]interface{}{
+				string(a[:]): map[string]interface{}{"complete": 42, "incomplete": 5, "downloaded": 900},
+			},
+		})
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	got, err := Scrape(scrapeCtx(t), srv.URL+"/tr/announce.php?passkey=abc", [][20]byte{a})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (ScrapeResult{Seeders: 42, Leechers: 5, Completed: 900}); got[a] != want {
+		t.Errorf("scrape = %+v, want %+v", got[a], want)
+	}
+}
+
+func TestScrapeHTTPUnsupported(t *testing.T) {
+	if _, err := Scrape(scrapeCtx(t), "http://tracker.example/tr", [][20]byte{testHash(1)}); err == nil {
+		t.Error("scrape of a tracker without an announce path succeeded")
+	}
+}
@@ -2,9 +2,14 @@ package main
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+
+	"c-cli/core"
+	"c-cli/metainfo"
 )
 
 func (m Model) View() string {
@@ -24,6 +29,10 @@ func (m Model) View() string {
 		b.WriteString(m.viewResults())
 	case viewDetails, viewTorrents:
 		b.WriteString(m.viewMovieDetails())
+	case viewDownloads:
+		b.WriteString(m.viewDownloads())
+	case viewInspect:
+		b.WriteString(m.viewInspect())
 	}
 
 	// Error/message display
@@ -34,16 +43,20 @@ func (m Model) View() string {
 		b.WriteString("\n" + successStyle.Render(m.message))
 	}
 
-	// Footer with help
-	b.WriteString("\n\n" + m.viewHelp())
+	// Footer with OMDB quota and help
+	b.WriteString("\n")
+	if status := m.viewStatus(); status != "" {
+		b.WriteString("\n" + status)
+	}
+	b.WriteString("\n" + m.viewHelp())
 
 	return b.String()
 }
 
 func (m Model) viewSearch() string {
-	sourceLabel := "YTS (Movies)"
-	if m.searchSource == SourceTorrentsCSV {
-		sourceLabel = "Torrents-CSV (All)"
+	sourceLabel := string(m.searchSource)
+	if p, ok := lib.Provider(m.searchSource); ok {
+		sourceLabel = p.Label()
 	}
 	return fmt.Sprintf(
 		"%s\n\n⏺ Source: %s\n\n> %s",
@@ -69,12 +82,16 @@ func (m Model) viewResults() string {
 			end = m.totalResults
 		}
 		pageInfo := fmt.Sprintf("Showing %d-%d of %d (Page %d/%d)", start, end, m.totalResults, m.page, m.totalPages)
-		b.WriteString(dimStyle.Render(pageInfo) + "\n")
+		b.WriteString(dimStyle.Render(pageInfo))
+		if m.enrichPending > 0 {
+			b.WriteString(dimStyle.Render(fmt.Sprintf("  %s fetching ratings (%d left)", m.spinner.View(), m.enrichPending)))
+		}
+		b.WriteString("\n")
 	}
 	b.WriteString("\n")
 
-	if len(m.movies) > 0 && m.movies[0].Source == SourceTorrentsCSV {
-		// Torrents-CSV format
+	if len(m.movies) > 0 && !hasDetails(m.movies[0].Source) {
+		// Torrent-per-row format (Torrents-CSV and similar)
 		headerRow := fmt.Sprintf("  %-38s %-6s %-10s %-6s %s",
 			dimStyle.Render("Title"),
 			dimStyle.Render("Year"),
@@ -315,15 +332,16 @@ func (m Model) viewTorrentsTable() string {
 	b.WriteString(tableTitle + "\n\n")
 
 	// Table header
-	headerRow := fmt.Sprintf("  %-6s %-10s %-12s %-8s %s",
+	headerRow := fmt.Sprintf("  %-6s %-10s %-12s %-8s %-8s %s",
 		dimStyle.Render("Idx"),
 		dimStyle.Render("Quality"),
 		dimStyle.Render("Size"),
 		dimStyle.Render("Seeds"),
 		dimStyle.Render("Peers"),
+		dimStyle.Render("Counts"),
 	)
 	b.WriteString(headerRow + "\n")
-	b.WriteString(dimStyle.Render(strings.Repeat("─", 55)) + "\n")
+	b.WriteString(dimStyle.Render(strings.Repeat("─", 65)) + "\n")
 
 	for i, torrent := range m.torrents {
 		seedsColor := "82" // green
@@ -333,12 +351,19 @@ func (m Model) viewTorrentsTable() string {
 			seedsColor = "220" // yellow
 		}
 
-		row := fmt.Sprintf("%-6d %-10s %-12s %s %-8d",
+		// Live counts come from a tracker scrape (r); the rest are the provider's
+		counts := "provider"
+		if !torrent.ScrapedAt.IsZero() {
+			counts = "live " + torrent.ScrapedAt.Format("15:04")
+		}
+
+		row := fmt.Sprintf("%-6d %-10s %-12s %s %-8d %s",
 			i,
 			torrent.Quality,
 			torrent.Size,
 			lipgloss.NewStyle().Foreground(lipgloss.Color(seedsColor)).Render(fmt.Sprintf("%-8d", torrent.Seeds)),
 			torrent.Peers,
+			counts,
 		)
 
 		if i == m.torrentIdx {
@@ -351,19 +376,188 @@ func (m Model) viewTorrentsTable() string {
 	return b.String()
 }
 
+func (m Model) viewDownloads() string {
+	var b strings.Builder
+	b.WriteString(headerStyle.Render("📥 Downloads") + "\n\n")
+
+	if _, ok := downloadClient.(core.ProgressReporter); !ok {
+		b.WriteString(dimStyle.Render(`Live progress needs download_client = "aria2" in config.toml.`))
+		return b.String()
+	}
+	if m.downloadsErr != nil {
+		b.WriteString(errorStyle.Render("❌ "+m.downloadsErr.Error()) + "\n\n")
+	}
+	if len(m.downloads) == 0 {
+		b.WriteString(dimStyle.Render("No downloads yet. Press s on a torrent to send it to aria2."))
+		return b.String()
+	}
+
+	headerRow := fmt.Sprintf("  %-32s %-28s %-12s %-9s %s",
+		dimStyle.Render("Name"),
+		dimStyle.Render("Progress"),
+		dimStyle.Render("Speed"),
+		dimStyle.Render("ETA"),
+		dimStyle.Render("Peers"),
+	)
+	b.WriteString(headerRow + "\n")
+	b.WriteString(dimStyle.Render(strings.Repeat("─", 90)) + "\n")
+
+	for _, st := range m.downloadStatus {
+		// Cut and pad by display width: titles are often not ASCII
+		name := runewidth.FillRight(runewidth.Truncate(st.Name, 30, "..."), 32)
+
+		const barWidth = 20
+		filled := int(st.Progress() * barWidth)
+		bar := strings.Repeat("█", filled) + strings.Repeat("░", barWidth-filled)
+		progress := fmt.Sprintf("%s %5.1f%%", bar, st.Progress()*100)
+
+		eta := "-"
+		if d := st.ETA(); d > 0 {
+			eta = d.String()
+		}
+		speed := core.FormatBytes(st.Speed) + "/s"
+
+		row := fmt.Sprintf("%s %-28s %-12s %-9s %d (%d seeds)",
+			name, progress, speed, eta, st.Peers, st.Seeders)
+		switch st.State {
+		case "complete":
+			b.WriteString(successStyle.Render("✔ "+row) + "\n")
+		case "error":
+			b.WriteString(errorStyle.Render("✖ "+row) + "\n")
+			if st.Error != "" {
+				b.WriteString(dimStyle.Render("    "+st.Error) + "\n")
+			}
+		case "paused", "waiting":
+			b.WriteString(dimStyle.Render("⏸ "+row) + "\n")
+		default:
+			b.WriteString(normalStyle.Render("  "+row) + "\n")
+		}
+	}
+
+	return b.String()
+}
+
+func (m Model) viewInspect() string {
+	meta := m.inspect
+	if meta == nil {
+		return "No torrent inspected"
+	}
+
+	var b strings.Builder
+	b.WriteString(headerStyle.Render("🔎 Torrent Inspector") + "\n")
+
+	var info strings.Builder
+	info.WriteString(lipgloss.NewStyle().Bold(true).Render(meta.Info.Name) + "\n\n")
+	info.WriteString("🔑 Infohash: " + meta.HashString())
+	if m.inspectVerified {
+		info.WriteString(" " + successStyle.Render("✔ verified"))
+	} else {
+		info.WriteString(" " + dimStyle.Render("(not checked: the result listed no infohash)"))
+	}
+	info.WriteString("\n")
+	info.WriteString(fmt.Sprintf("📦 Total size: %s in %d file(s)\n", core.FormatBytes(meta.TotalLength()), len(meta.Info.FileList())))
+	info.WriteString(fmt.Sprintf("🧩 Pieces: %d × %s\n", meta.Info.NumPieces(), core.FormatBytes(meta.Info.PieceLength)))
+	if meta.Info.Private {
+		info.WriteString("🔒 Private: yes (trackers only, no DHT/PEX)\n")
+	}
+	info.WriteString(fmt.Sprintf("📡 Trackers: %d", len(meta.Trackers())))
+	if meta.CreatedBy != "" {
+		info.WriteString(fmt.Sprintf("\n🛠 Created by: %s", meta.CreatedBy))
+	}
+	if !meta.CreationDate.IsZero() {
+		info.WriteString(fmt.Sprintf("\n📅 Created: %s", meta.CreationDate.Format("2006-01-02")))
+	}
+	b.WriteString(boxStyle.Render(info.String()) + "\n\n")
+
+	b.WriteString(headerStyle.Render("📂 Files") + "\n")
+	lines := m.inspectLines()
+	end := m.inspectScroll + m.inspectHeight()
+	if end > len(lines) {
+		end = len(lines)
+	}
+	for _, line := range lines[m.inspectScroll:end] {
+		b.WriteString(normalStyle.Render(line) + "\n")
+	}
+	if len(lines) > m.inspectHeight() {
+		b.WriteString(dimStyle.Render(fmt.Sprintf("Lines %d-%d of %d", m.inspectScroll+1, end, len(lines))) + "\n")
+	}
+
+	return b.String()
+}
+
+// inspectLines renders the inspected torrent's file tree, one line per
+// directory or file.
+func (m Model) inspectLines() []string {
+	if m.inspect == nil {
+		return nil
+	}
+	files := slices.Clone(m.inspect.Info.FileList())
+	slices.SortFunc(files, func(a, b metainfo.File) int { return slices.Compare(a.Path, b.Path) })
+
+	var lines []string
+	depth := 0
+	if m.inspect.Info.Files != nil {
+		// Multi-file torrents download into a directory named after the torrent
+		lines = append(lines, "📁 "+m.inspect.Info.Name+"/")
+		depth = 1
+	}
+
+	var prev []string
+	for _, f := range files {
+		dirs := f.Path[:len(f.Path)-1]
+		common := 0
+		for common < len(dirs) && common < len(prev) && dirs[common] == prev[common] {
+			common++
+		}
+		for d := common; d < len(dirs); d++ {
+			lines = append(lines, strings.Repeat("  ", depth+d)+"📁 "+dirs[d]+"/")
+		}
+		prev = dirs
+
+		lines = append(lines, fmt.Sprintf("%s📄 %s  %s",
+			strings.Repeat("  ", depth+len(dirs)), f.Path[len(f.Path)-1], core.FormatBytes(f.Length)))
+	}
+	return lines
+}
+
+// inspectHeight is how many file tree lines fit below the summary box.
+func (m Model) inspectHeight() int {
+	if h := m.height - 20; h > 5 {
+		return h
+	}
+	return 5
+}
+
+// viewStatus reports the OMDB request quota, warning once it's used up and
+// enrichment is limited to cached data.
+func (m Model) viewStatus() string {
+	if config.OMDBAPIKey == "" {
+		return ""
+	}
+	q := omdbClient.Quota.Status(config.OMDBAPIKey)
+	if q.Exhausted {
+		return errorStyle.Render(fmt.Sprintf("⚠ OMDB quota used up until %s, showing cached data only",
+			q.Resets.Local().Format("15:04")))
+	}
+	return dimStyle.Render(fmt.Sprintf("OMDB: %d/%d requests left today", q.Remaining, q.Limit))
+}
 
 func (m Model) viewHelp() string {
 	var help string
 
 	switch m.state {
 	case viewSearch:
-		help = "enter: search • tab: switch source • ctrl+c: quit"
+		help = "enter: search • tab: next source • ctrl+t: downloads • ctrl+c: quit"
 	case viewLoading:
-		help = "loading..."
+		help = "loading... • esc: cancel"
 	case viewResults:
-		help = "↑/↓: navigate • ←/→ or [/]: page • enter: select • esc: back"
+		help = "↑/↓: navigate • ←/→ or [/]: page • enter: select • ctrl+t: downloads • esc: back"
 	case viewDetails, viewTorrents:
-		help = "↑/↓/0-9: select torrent • enter/m: show magnet • t: download .torrent • a: auto-best • esc: back"
+		help = "↑/↓/0-9: select torrent • enter/m: show magnet • i: inspect • r: live seeds • t: download .torrent • s: send to client • a: auto-best • esc: back"
+	case viewInspect:
+		help = "↑/↓: scroll files • t: download .torrent • s: send to client • esc: back"
+	case viewDownloads:
+		help = "esc/ctrl+t: back • ctrl+c: quit"
 	}
 
 	return dimStyle.Render(help)